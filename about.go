@@ -17,13 +17,7 @@ import (
 )
 
 // createCleanSettingsUI 创建数据清理设置UI
-func createCleanSettingsUI(win fyne.Window) fyne.CanvasObject {
-	config, err := LoadConfig("config.json")
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("加载配置失败: %v", err), win)
-		return nil
-	}
-
+func createCleanSettingsUI(win fyne.Window, config *Config) fyne.CanvasObject {
 	// 添加编号输入文本框
 	orderNumberEntry := widget.NewEntry()
 	orderNumberEntry.SetPlaceHolder("数据清理：不输入默认清理选择日期范围的所有记录，可输入一个或多个编号（逗号分割）")
@@ -165,15 +159,8 @@ func aboutLogToUIAndSystem(message string) {
 	SysLogToFile(formattedMessage)
 }
 
-// 创建关于界面的 UI
-func createAboutUI(win fyne.Window) fyne.CanvasObject {
-	// 加载配置
-	config, err := LoadConfig("config.json")
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("加载配置失败: %v", err), win)
-		aboutLogToUIAndSystem(fmt.Sprintf("加载配置失败: %v", err))
-	}
-
+// 创建关于界面的 UI，config 为各界面共用的配置
+func createAboutUI(win fyne.Window, config *Config) fyne.CanvasObject {
 	// 解析GitHub URL
 	parsedURL, _ := url.Parse("https://github.com/potatoone")
 
@@ -189,6 +176,9 @@ func createAboutUI(win fyne.Window) fyne.CanvasObject {
 
 	// 添加开机自启动选项
 	autoStartCheck = widget.NewCheck("开机自动启动", func(checked bool) {
+		if checked == config.AutoStart && checked == utils.IsAutoStartEnabled() {
+			return // 配置变更通知同步复选框状态
+		}
 		if checked {
 			err := utils.EnableAutoStart()
 			if err != nil {
@@ -200,15 +190,12 @@ func createAboutUI(win fyne.Window) fyne.CanvasObject {
 			}
 
 			// 更新配置文件
-			if config != nil {
-				config.AutoStart = "true"
-				if err := SaveConfig("config.json", config); err != nil {
-					errMsg := fmt.Sprintf("保存配置失败: %v", err)
-					dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), win) // 修复：使用常量格式字符串
-					aboutLogToUIAndSystem(errMsg)
-				} else {
-					aboutLogToUIAndSystem("开机自启动已启用")
-				}
+			if err := updateConfig(config, func(c *Config) { c.AutoStart = true }); err != nil {
+				errMsg := configSaveError(err)
+				dialog.ShowError(fmt.Errorf("%s", errMsg), win)
+				aboutLogToUIAndSystem(errMsg)
+			} else {
+				aboutLogToUIAndSystem("开机自启动已启用")
 			}
 		} else {
 			err := utils.DisableAutoStart()
@@ -221,80 +208,111 @@ func createAboutUI(win fyne.Window) fyne.CanvasObject {
 			}
 
 			// 更新配置文件
-			if config != nil {
-				config.AutoStart = "false"
-				if err := SaveConfig("config.json", config); err != nil {
-					errMsg := fmt.Sprintf("保存配置失败: %v", err)
-					dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), win) // 修复：使用常量格式字符串
-					aboutLogToUIAndSystem(errMsg)
-				} else {
-					aboutLogToUIAndSystem("开机自启动已禁用")
-				}
+			if err := updateConfig(config, func(c *Config) { c.AutoStart = false }); err != nil {
+				errMsg := configSaveError(err)
+				dialog.ShowError(fmt.Errorf("%s", errMsg), win)
+				aboutLogToUIAndSystem(errMsg)
+			} else {
+				aboutLogToUIAndSystem("开机自启动已禁用")
 			}
 		}
 	})
 
 	// 添加"程序启动自动执行任务"复选框（与开机自启完全独立）
 	autoStartTaskCheck = widget.NewCheck("程序启动时自动执行任务", func(checked bool) {
-		if config != nil {
-			// 更新配置文件中的 autostart_auto 字段
-			config.AutoStartAutoTask = fmt.Sprintf("%v", checked)
-			if err := SaveConfig("config.json", config); err != nil {
-				errMsg := fmt.Sprintf("保存配置失败: %v", err)
-				dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), win) // 修复：使用常量格式字符串
-				aboutLogToUIAndSystem(errMsg)
-				// 如果保存失败，恢复复选框状态
-				autoStartTaskCheck.SetChecked(!checked)
-				return
-			}
+		if checked == config.AutoStartAutoTask {
+			return // 配置变更通知同步复选框状态
+		}
+		// 更新配置文件中的 autostart_auto 字段
+		if err := updateConfig(config, func(c *Config) { c.AutoStartAutoTask = checked }); err != nil {
+			errMsg := configSaveError(err)
+			dialog.ShowError(fmt.Errorf("%s", errMsg), win)
+			aboutLogToUIAndSystem(errMsg)
+			// 如果保存失败，恢复复选框状态
+			autoStartTaskCheck.SetChecked(!checked)
+			return
+		}
 
-			// 记录配置更改状态
-			if checked {
-				aboutLogToUIAndSystem("程序启动自动执行任务已启用")
-			} else {
-				aboutLogToUIAndSystem("程序启动自动执行任务已禁用")
-			}
+		// 记录配置更改状态
+		if checked {
+			aboutLogToUIAndSystem("程序启动自动执行任务已启用")
+		} else {
+			aboutLogToUIAndSystem("程序启动自动执行任务已禁用")
 		}
 	})
 
 	// 添加"开启界面锁定"复选框
 	lockUICheck = widget.NewCheck("开启界面锁定", func(checked bool) {
-		if config != nil {
-			// 更新配置文件中的 lockui 字段
-			config.LockUI = fmt.Sprintf("%v", checked)
-			if err := SaveConfig("config.json", config); err != nil {
-				errMsg := fmt.Sprintf("保存配置失败: %v", err)
-				dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), win)
-				aboutLogToUIAndSystem(errMsg)
-				// 如果保存失败，恢复复选框状态
-				lockUICheck.SetChecked(!checked)
-				return
-			}
+		if checked == config.LockUI {
+			return // 配置变更通知同步复选框状态
+		}
+		// 更新配置文件中的 lockui 字段
+		if err := updateConfig(config, func(c *Config) { c.LockUI = checked }); err != nil {
+			errMsg := configSaveError(err)
+			dialog.ShowError(fmt.Errorf("%s", errMsg), win)
+			aboutLogToUIAndSystem(errMsg)
+			// 如果保存失败，恢复复选框状态
+			lockUICheck.SetChecked(!checked)
+			return
+		}
 
-			// 记录配置更改状态
-			if checked {
-				aboutLogToUIAndSystem("界面锁定已启用")
-			} else {
-				aboutLogToUIAndSystem("界面锁定已禁用")
-			}
+		// 记录配置更改状态
+		if checked {
+			aboutLogToUIAndSystem("界面锁定已启用")
+		} else {
+			aboutLogToUIAndSystem("界面锁定已禁用")
 		}
 	})
 
 	// 设置复选框初始状态
 	autoStartCheck.SetChecked(utils.IsAutoStartEnabled())
-	if config != nil {
-		autoStartTaskCheck.SetChecked(config.AutoStartAutoTask == "true")
-		// 设置界面锁定复选框初始状态
-		lockUICheck.SetChecked(config.LockUI == "true")
+	autoStartTaskCheck.SetChecked(config.AutoStartAutoTask)
+	// 设置界面锁定复选框初始状态
+	lockUICheck.SetChecked(config.LockUI)
+
+	// 配置被其他程序修改或恢复历史版本时同步复选框，开机自启动以配置为准
+	onConfigChange(func(_, c *Config) {
+		autoStartCheck.SetChecked(c.AutoStart)
+		autoStartTaskCheck.SetChecked(c.AutoStartAutoTask)
+		lockUICheck.SetChecked(c.LockUI)
+	})
+
+	// 配置历史：查看每次保存的变更并恢复历史版本
+	historyButton := widget.NewButton("配置历史", func() {
+		showConfigHistoryDialog(win, config)
+	})
+
+	// 配置包：导出当前配置，或导入其他机器导出的配置（保留本机的 machine_code 等字段）
+	exportButton := widget.NewButton("导出配置", func() {
+		showConfigExportDialog(win)
+	})
+	importButton := widget.NewButton("导入配置", func() {
+		showConfigImportDialog(win)
+	})
+
+	// 生效配置：每个字段的值来自命令行、环境变量、配置文件还是默认值
+	effectiveButton := widget.NewButton("生效配置", func() {
+		showEffectiveConfigDialog(win)
+	})
+	overrideText := "配置来源：全部字段来自配置文件"
+	if s := overridesSummary(); s != "" {
+		overrideText = "配置来源：以下字段被环境变量或命令行覆盖（界面中修改后保存到配置文件，本进程仍使用覆盖的值）: " + s
 	}
+	overrideLabel := widget.NewLabel(overrideText)
+	overrideLabel.Wrapping = fyne.TextWrapWord
 
-	// 创建水平布局，三个复选框在同一行
+	// 创建水平布局，三个复选框和配置按钮在同一行
 	checkboxesContainer := container.NewHBox(
 		autoStartCheck,
 		widget.NewSeparator(), // 添加分隔符
 		autoStartTaskCheck,
 		widget.NewSeparator(), // 添加分隔符
 		lockUICheck,           // 添加界面锁定复选框
+		widget.NewSeparator(),
+		historyButton,
+		exportButton,
+		importButton,
+		effectiveButton,
 	)
 
 	aboutCard := widget.NewCard(
@@ -304,10 +322,11 @@ func createAboutUI(win fyne.Window) fyne.CanvasObject {
 			authorContainer, // 使用放在一行的作者信息
 			widget.NewSeparator(),
 			checkboxesContainer,
+			overrideLabel,
 		),
 	)
 
-	cleanSettingsCard := createCleanSettingsUI(win)
+	cleanSettingsCard := createCleanSettingsUI(win, config)
 
 	return container.NewVBox(
 		cleanSettingsCard,
@@ -142,7 +142,9 @@ func cleanDbRecordsByDateAndNumbers(config CleanConfig, orderNumbers []string, d
 
 	// 需要清理的表和复制文件夹字段
 	tablesAndFields := map[string]string{
-		"copy_records": "copy_dir",
+		"copy_records":  "copy_dir",
+		"jobs":          "copy_dir",
+		"object_hashes": "copy_dir",
 	}
 
 	for table, dateField := range tablesAndFields {
@@ -213,7 +215,8 @@ func cleanDbRecordsByDateAndNumbers(config CleanConfig, orderNumbers []string, d
 	return totalDeleted, nil
 }
 
-func init() {
+// initStdLog 将标准库 log 的输出重定向到系统日志文件，需在 utils.InitPaths 确定日志路径之后调用
+func initStdLog() {
 	// 确保日志目录存在
 	logDir := filepath.Dir(utils.SysLogPath) // 使用 SysLogPath 来获取目录
 	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
@@ -0,0 +1,806 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"go-uposs/utils"
+)
+
+// 命令行模式的退出状态码
+const (
+	exitOK         = 0 // 执行成功
+	exitTaskFailed = 1 // 任务执行过程中出现错误（复制、压缩、上传或推送失败）
+	exitUsage      = 2 // 命令行参数错误
+	exitInitFailed = 3 // 初始化失败（数据库、配置文件）
+)
+
+// cliUsage 命令行帮助信息
+const cliUsage = `用法: gouposs [--data-dir 目录] [--set 字段=值]... <命令> [参数]
+
+不带命令运行时启动图形界面。
+
+全局参数（写在命令之前）:
+  --data-dir 目录                     程序文件夹，配置（data/config.json）、数据库、日志和隔离文件都保存在该目录下，
+                                      未指定时使用环境变量 GOUPOSS_HOME，都未设置时使用平台默认路径。
+                                      多个实例使用不同的目录即可互不影响地同时运行
+  --set 字段=值                       覆盖配置字段（配置文件中的名称，例如 --set bucket_name=test），可重复，
+                                      只在本进程中生效，不写入配置文件
+
+命令:
+  run --once [--dry-run] [--profile 方案]
+                                      执行一次自动任务周期（今天和昨天的文件夹）后退出
+  auto [--dry-run] [--profile 方案]   按 auto_interval（或 auto_cron）循环执行自动任务，收到 Ctrl+C / SIGTERM 后退出
+  sched --from 日期 --to 日期 [--orders 编号] [--times 次数] [--dry-run] [--profile 方案]
+                                      按日期范围执行计划任务，日期格式 2025.01.01，编号逗号分割
+  sched --cron [--from 日期 --to 日期] [--orders 编号] [--dry-run] [--profile 方案]
+                                      按配置中的 sched_cron 定时执行计划任务，收到 Ctrl+C / SIGTERM 后退出
+  jobs [--state 状态] [--limit 条数]   查看文件处理状态，状态: copied processed uploaded pushed failed quarantined
+  runs [--task auto|sched] [--since 日期] [--limit 条数] [--json]
+                                      查看每个任务周期的执行记录，--since 格式 2025.01.01 或 "2025.01.01 03:00"，
+                                      --json 以 JSON 数组输出
+  autostart [on|off|status]           设置或查看开机自启动：Windows 注册表，Linux 图形会话为 XDG 自动启动项、
+                                      无图形会话为 systemd 用户服务（以 auto 模式运行），macOS 为 LaunchAgent
+  config show [--effective] [--json]  显示配置文件中的字段，--effective 显示覆盖后的生效值和每个字段的来源，密钥不显示明文
+  config check [--json]               校验配置文件的全部字段（URL、端点格式、日期格式、数值范围、文件夹是否存在、
+                                      存储桶命名规则），有问题时逐项输出并以状态码 1 退出，--json 以 JSON 数组输出
+  config export [--no-secrets] [--key-env 变量名] 文件
+                                      导出当前配置为签名的配置包，口令取自环境变量 GOUPOSS_BUNDLE_KEY（或 --key-env 指定的变量），
+                                      --no-secrets 不包含密钥字段
+  config import [--key-env 变量名] [--dry-run] [--force] 文件或地址
+                                      校验配置包的签名，输出与当前配置的差异后导入，保留本机的 machine_code、autostart、
+                                      autostart_auto、lockui；--dry-run 只输出差异，导入后的配置校验不通过时需要 --force；
+                                      参数为 http(s) 地址时从该地址下载配置包
+  secret rotate [--machine | --new-key-env 变量名]
+                                      用新的盐重新加密配置文件和配置历史中的密钥字段。
+                                      默认继续使用当前口令；--new-key-env 改用该环境变量中的口令，--machine 改用本机标识。
+                                      执行前请退出图形界面和其他命令行任务
+
+加密口令: 配置中的密钥以 AES-GCM 加密保存，口令取自环境变量 GOUPOSS_SECRET_KEY，未设置时使用本机标识
+（配置文件复制到其他机器后无法解密）。
+
+配置覆盖: 每个配置字段都可以用环境变量 GOUPOSS_<字段> 覆盖，字段名按驼峰拆分并转为大写，
+例如 GOUPOSS_BUCKET_NAME、GOUPOSS_ACCESS_KEY_ID、GOUPOSS_USE_SSL。优先级：--set > 环境变量 > 配置文件 > 默认值。
+覆盖只作用于顶层（默认方案）的字段，方案中设置了的字段不受影响。
+
+--dry-run 模拟运行：只输出将要复制、压缩、上传、推送和删除的文件，不修改本地文件、存储桶和 API2，
+也不补推推送待办。等同于配置中的 auto_dry_run / sched_dry_run，只在本次运行中生效。
+
+--profile 只执行指定的配置方案（配置中 profiles 的 name，default 为顶层的默认方案），
+未指定时全部方案同时执行，每个方案的日志写入任务日志目录下以方案名命名的子目录。
+
+退出状态码:
+  0 成功  1 任务执行出错  2 参数错误  3 初始化失败
+`
+
+// parseGlobalFlags 解析命令之前的全局参数，返回程序文件夹和剩余参数
+// 参数错误或输出帮助后 code 为进程退出状态码，否则为 -1
+func parseGlobalFlags(args []string) (dataDir string, rest []string, code int) {
+	fs := flag.NewFlagSet("gouposs", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, cliUsage) }
+	fs.StringVar(&dataDir, "data-dir", "", "程序文件夹")
+	var sets []string
+	fs.Func("set", "覆盖配置字段，字段=值", func(s string) error {
+		sets = append(sets, s)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", nil, exitOK
+		}
+		return "", nil, exitUsage
+	}
+	if err := initConfigOverrides(sets); err != nil {
+		fmt.Fprintf(os.Stderr, "配置覆盖参数错误: %v\n", err)
+		return "", nil, exitUsage
+	}
+	return dataDir, fs.Args(), -1
+}
+
+// runCLI 解析命令行参数并以无界面模式执行任务，返回进程退出状态码
+func runCLI(args []string) int {
+	headlessMode = true
+
+	switch args[0] {
+	case "run":
+		return runCLIRun(args[1:])
+	case "auto":
+		return runCLIAuto(args[1:])
+	case "sched":
+		return runCLISched(args[1:])
+	case "jobs":
+		return runCLIJobs(args[1:])
+	case "runs":
+		return runCLIRuns(args[1:])
+	case "autostart":
+		return runCLIAutoStart(args[1:])
+	case "config":
+		return runCLIConfig(args[1:])
+	case "secret":
+		return runCLISecret(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n%s", args[0], cliUsage)
+		return exitUsage
+	}
+}
+
+// initHeadless 初始化无界面模式：日志、数据库和配置
+func initHeadless() (*Config, error) {
+	headlessMode = true
+	InitAutoLogger(utils.AutoLogPath)
+	InitSchedLogger(utils.SchedLogPath)
+
+	if err := initDatabase(); err != nil {
+		return nil, fmt.Errorf("初始化数据库失败: %v", err)
+	}
+
+	config, err := appConfig.Load()
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %v", err)
+	}
+	if err := config.ValidateFields(runConfigFields...); err != nil {
+		return nil, fmt.Errorf("配置校验失败:\n%v", err)
+	}
+
+	return config, nil
+}
+
+// runCLIRun 处理 run 命令
+func runCLIRun(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	once := fs.Bool("once", false, "执行一次自动任务周期后退出")
+	dryRun := fs.Bool("dry-run", false, "模拟运行，不修改任何文件")
+	only := fs.String("profile", "", "只执行指定的配置方案，default 为默认方案")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if !*once {
+		// 不带 --once 时等同于 auto 命令
+		autoArgs := fs.Args()
+		if *only != "" {
+			autoArgs = append([]string{"--profile", *only}, autoArgs...)
+		}
+		if *dryRun {
+			autoArgs = append([]string{"--dry-run"}, autoArgs...)
+		}
+		return runCLIAuto(autoArgs)
+	}
+
+	config, err := initHeadless()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		MainLogToFile(fmt.Sprintf("命令行初始化失败: %v", err))
+		return exitInitFailed
+	}
+	defer utils.CloseDB()
+
+	profiles, err := config.SelectProfiles(*only)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	AutoLogToFile("命令行模式：执行一次自动任务")
+	err = runProfiles(profiles, func(profile string) error {
+		c, err := config.ForProfile(profile)
+		if err != nil {
+			return err
+		}
+		if err := c.ValidateFields(runConfigFields...); err != nil {
+			AutoProfileLogToFile(profile, fmt.Sprintf("配置校验失败:\n%v", err))
+			return err
+		}
+		if *dryRun {
+			c.AutoDryRun = true
+		}
+		return runAutoCycle(ctx, c, runTriggerManual)
+	})
+
+	// 退出前补推一次到期的推送待办，未成功的留到下次运行；模拟运行不推送
+	if !*dryRun && !config.AutoDryRun {
+		drainOutbox(ctx)
+	}
+
+	if err != nil {
+		AutoLogToFile(fmt.Sprintf("自动任务执行出错: %v", err))
+		return exitTaskFailed
+	}
+	return exitOK
+}
+
+// runCLIAuto 处理 auto 命令，每个配置方案循环执行自动任务直到收到退出信号
+func runCLIAuto(args []string) int {
+	fs := flag.NewFlagSet("auto", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "模拟运行，不修改任何文件")
+	only := fs.String("profile", "", "只执行指定的配置方案，default 为默认方案")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	config, err := initHeadless()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		MainLogToFile(fmt.Sprintf("命令行初始化失败: %v", err))
+		return exitInitFailed
+	}
+	defer utils.CloseDB()
+
+	profiles, err := config.SelectProfiles(*only)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// 收到 Ctrl+C / SIGTERM 时取消 ctx，正在进行的复制、压缩、上传会立即中断
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 后台补推 API2 推送待办，模拟运行不推送
+	var override func(*Config)
+	if *dryRun {
+		override = func(c *Config) { c.AutoDryRun = true }
+	} else {
+		go runOutboxWorker(ctx)
+	}
+
+	AutoLogToFile("命令行模式：开始自动任务")
+	err = runProfiles(profiles, func(profile string) error {
+		return runAutoProfile(ctx, profile, runTriggerManual, override)
+	})
+	if ctx.Err() != nil {
+		AutoLogToFile("收到退出信号，任务已停止")
+		return exitOK
+	}
+	// 没有收到退出信号时，所有方案都因配置无效而停止
+	AutoLogToFile(fmt.Sprintf("自动任务已停止: %v", err))
+	return exitInitFailed
+}
+
+// runCLISched 处理 sched 命令，按日期范围和编号执行计划任务
+// 带 --cron 时按配置中的 sched_cron 定时执行，直到收到退出信号
+func runCLISched(args []string) int {
+	fs := flag.NewFlagSet("sched", flag.ContinueOnError)
+	from := fs.String("from", "", "开始日期，格式 2025.01.01")
+	to := fs.String("to", "", "结束日期，格式 2025.01.31")
+	orders := fs.String("orders", "", "需要匹配的编号，多个编号用逗号分割")
+	times := fs.Int("times", 0, "执行次数，默认使用配置中的 sched_times")
+	cronMode := fs.Bool("cron", false, "按配置中的 sched_cron 定时执行，未指定日期时处理最近 sched_cron_days 天")
+	dryRun := fs.Bool("dry-run", false, "模拟运行，不修改任何文件")
+	only := fs.String("profile", "", "只执行指定的配置方案，default 为默认方案")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	// 定时执行时日期可以不指定，由 sched_cron_days 决定
+	hasRange := *from != "" || *to != ""
+	if hasRange || !*cronMode {
+		startDate, err := time.Parse("2006.01.02", *from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无效的开始日期: %q\n", *from)
+			return exitUsage
+		}
+		endDate, err := time.Parse("2006.01.02", *to)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无效的结束日期: %q\n", *to)
+			return exitUsage
+		}
+		if endDate.Before(startDate) {
+			fmt.Fprintln(os.Stderr, "结束日期不能早于开始日期")
+			return exitUsage
+		}
+	}
+
+	config, err := initHeadless()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		MainLogToFile(fmt.Sprintf("命令行初始化失败: %v", err))
+		return exitInitFailed
+	}
+	defer utils.CloseDB()
+
+	profiles, err := config.SelectProfiles(*only)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// 日期范围和模拟运行只在本次运行中生效，不写回配置文件
+	var override func(*Config)
+	if hasRange || *dryRun {
+		override = func(c *Config) {
+			if hasRange {
+				c.StartTime = *from
+				c.EndTime = *to
+			}
+			if *dryRun {
+				c.SchedDryRun = true
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 后台补推 API2 推送待办，模拟运行不推送
+	if !*dryRun {
+		go runOutboxWorker(ctx)
+	}
+
+	if !*cronMode {
+		SchedLogToFile(fmt.Sprintf("命令行模式：计划任务 %s - %s", *from, *to))
+		err = runProfiles(profiles, func(profile string) error {
+			return runSchedBatch(ctx, profile, *orders, *times, runTriggerManual, override)
+		})
+		if ctx.Err() != nil {
+			SchedLogToFile("收到退出信号，任务已停止")
+			return exitTaskFailed
+		}
+		return schedExitCode(err)
+	}
+
+	schedule, err := schedCronSchedule(config)
+	if err == nil && schedule == nil {
+		err = fmt.Errorf("配置中的 sched_cron 为空")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		SchedLogToFile(err.Error())
+		return exitInitFailed
+	}
+
+	SchedLogToFile(fmt.Sprintf("命令行模式：定时计划任务 %s", config.SchedCron))
+	err = runProfiles(profiles, func(profile string) error {
+		return runSchedCron(ctx, profile, schedule, config.CronCatchUp, *orders, *times, override)
+	})
+	if ctx.Err() != nil {
+		// 定时执行通过退出信号正常结束
+		SchedLogToFile("收到退出信号，任务已停止")
+		return exitOK
+	}
+	return schedExitCode(err)
+}
+
+// schedExitCode 根据计划任务的执行结果返回退出状态码
+func schedExitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errSchedConfig):
+		return exitInitFailed
+	default:
+		SchedLogToFile(fmt.Sprintf("计划任务执行出错: %v", err))
+		return exitTaskFailed
+	}
+}
+
+// runCLIJobs 处理 jobs 命令，输出各状态的文件数量和最近的文件处理记录
+func runCLIJobs(args []string) int {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	state := fs.String("state", "", "只显示指定状态的文件")
+	limit := fs.Int("limit", 50, "最多显示的记录条数，0 表示全部")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	headlessMode = true
+	if err := initDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化数据库失败: %v\n", err)
+		return exitInitFailed
+	}
+	defer utils.CloseDB()
+
+	counts, err := utils.CountJobsByState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询文件处理状态失败: %v\n", err)
+		return exitInitFailed
+	}
+	states := []utils.JobState{utils.JobCopied, utils.JobProcessed, utils.JobUploaded, utils.JobPushed, utils.JobFailed, utils.JobQuarantined}
+	for _, st := range states {
+		fmt.Printf("%s=%d  ", st, counts[st])
+	}
+	if pending, err := utils.CountOutbox(); err == nil {
+		fmt.Printf("待推送API2=%d", pending)
+	}
+	fmt.Println()
+
+	jobs, err := utils.ListJobs(utils.JobState(*state), *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询文件处理记录失败: %v\n", err)
+		return exitInitFailed
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "更新时间\t方案\t文件名\t状态\t失败次数\t编号\t对象路径\t本地路径\t最近错误")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			job.UpdatedAt.Format("2006.01.02 15:04:05"), profileLabel(job.Profile), job.FileName, job.State, job.Attempts,
+			job.OrderNumber, job.ObjectKey, job.LocalPath, job.LastError)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// runCLIRuns 处理 runs 命令，按开始时间倒序输出任务周期的执行记录
+func runCLIRuns(args []string) int {
+	fs := flag.NewFlagSet("runs", flag.ContinueOnError)
+	task := fs.String("task", "", "只显示指定任务类型的记录：auto 或 sched")
+	sinceText := fs.String("since", "", "只显示该时间之后开始的记录，格式 2025.01.01 或 \"2025.01.01 03:00\"")
+	limit := fs.Int("limit", 50, "最多显示的记录条数，0 表示全部")
+	asJSON := fs.Bool("json", false, "以 JSON 数组输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *task != "" && *task != "auto" && *task != "sched" {
+		fmt.Fprintf(os.Stderr, "无效的任务类型: %q\n", *task)
+		return exitUsage
+	}
+
+	var since time.Time
+	if *sinceText != "" {
+		var err error
+		since, err = time.ParseInLocation("2006.01.02 15:04", *sinceText, time.Local)
+		if err != nil {
+			since, err = time.ParseInLocation("2006.01.02", *sinceText, time.Local)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无效的开始时间: %q\n", *sinceText)
+			return exitUsage
+		}
+	}
+
+	headlessMode = true
+	if err := initDatabase(); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化数据库失败: %v\n", err)
+		return exitInitFailed
+	}
+	defer utils.CloseDB()
+
+	runs, err := utils.ListRuns(*task, since, *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询执行记录失败: %v\n", err)
+		return exitInitFailed
+	}
+
+	if *asJSON {
+		data, err := marshalRuns(runs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitInitFailed
+		}
+		os.Stdout.Write(data)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	// 与界面执行记录表格使用相同的列
+	for _, c := range historyColumns {
+		fmt.Fprintf(w, "%s\t", c.title)
+	}
+	fmt.Fprintln(w, "最近错误")
+	for i := range runs {
+		r := &runs[i]
+		for col := range historyColumns {
+			fmt.Fprintf(w, "%s\t", historyCell(r, col))
+		}
+		fmt.Fprintln(w, r.LastError)
+	}
+	w.Flush()
+	return exitOK
+}
+
+// runCLIAutoStart 处理 autostart 命令，设置或查看开机自启动
+// 只修改启动项，不写回配置中的 auto_start，界面启动时仍按配置同步
+func runCLIAutoStart(args []string) int {
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "on":
+		if err := utils.EnableAutoStart(); err != nil {
+			fmt.Fprintf(os.Stderr, "启用开机自启动失败: %v\n", err)
+			return exitTaskFailed
+		}
+		fmt.Println("已启用开机自启动")
+	case "off":
+		if err := utils.DisableAutoStart(); err != nil {
+			fmt.Fprintf(os.Stderr, "禁用开机自启动失败: %v\n", err)
+			return exitTaskFailed
+		}
+		fmt.Println("已禁用开机自启动")
+	case "status":
+		if utils.IsAutoStartEnabled() {
+			fmt.Println("开机自启动: 已启用")
+		} else {
+			fmt.Println("开机自启动: 未启用")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "未知的 autostart 参数: %s，可选 on、off、status\n", action)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// runCLIConfig 处理 config 命令
+func runCLIConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "缺少 config 子命令，可选 show、check、export、import\n")
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "show":
+		return runCLIConfigShow(args[1:])
+	case "check":
+		return runCLIConfigCheck(args[1:])
+	case "export":
+		return runCLIConfigExport(args[1:])
+	case "import":
+		return runCLIConfigImport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知的 config 子命令: %s，可选 show、check、export、import\n", args[0])
+		return exitUsage
+	}
+}
+
+// runCLIConfigShow 处理 config show 命令，输出每个字段的值，--effective 时输出覆盖后的生效值和来源
+func runCLIConfigShow(args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	effective := fs.Bool("effective", false, "显示环境变量和命令行覆盖后的生效值和来源")
+	asJSON := fs.Bool("json", false, "以 JSON 数组输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	fields, err := appConfig.Fields(*effective)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitInitFailed
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(fields, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "编码配置失败: %v\n", err)
+			return exitTaskFailed
+		}
+		fmt.Println(string(data))
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *effective {
+		fmt.Fprintln(w, "字段\t生效值\t来源\t环境变量")
+		for _, f := range fields {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Field, f.Value, f.Source, f.Env)
+		}
+	} else {
+		fmt.Fprintln(w, "字段\t值")
+		for _, f := range fields {
+			fmt.Fprintf(w, "%s\t%s\n", f.Field, f.Value)
+		}
+	}
+	w.Flush()
+	return exitOK
+}
+
+// runCLIConfigCheck 处理 config check 命令，校验配置文件（含环境变量和命令行的覆盖）并逐项输出问题
+func runCLIConfigCheck(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "以 JSON 数组输出校验错误")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	config, err := appConfig.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitInitFailed
+	}
+	errs := validationErrorsOf(config.Validate())
+
+	if *asJSON {
+		if errs == nil {
+			errs = ValidationErrors{}
+		}
+		data, err := json.MarshalIndent(errs, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "编码校验结果失败: %v\n", err)
+			return exitTaskFailed
+		}
+		fmt.Println(string(data))
+	} else if len(errs) == 0 {
+		fmt.Printf("配置检查通过: %s\n", filepath.Join(utils.DataPath, "config.json"))
+	} else {
+		fmt.Printf("配置检查发现 %d 处问题: %s\n", len(errs), filepath.Join(utils.DataPath, "config.json"))
+		for _, e := range errs {
+			fmt.Printf("  %s\n", e.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return exitTaskFailed
+	}
+	return exitOK
+}
+
+// bundlePassphrase 返回环境变量 name 中的配置包口令
+func bundlePassphrase(name string) (string, error) {
+	passphrase := os.Getenv(name)
+	if passphrase == "" {
+		return "", fmt.Errorf("环境变量 %s 未设置或为空，配置包口令用于签名和加密密钥", name)
+	}
+	return passphrase, nil
+}
+
+// runCLIConfigExport 处理 config export 命令，导出当前配置为签名的配置包
+func runCLIConfigExport(args []string) int {
+	fs := flag.NewFlagSet("config export", flag.ContinueOnError)
+	noSecrets := fs.Bool("no-secrets", false, "不包含密钥字段")
+	keyEnv := fs.String("key-env", configBundleKeyEnv, "读取配置包口令的环境变量")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "请指定导出的文件")
+		return exitUsage
+	}
+	passphrase, err := bundlePassphrase(*keyEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	config, err := LoadConfig("config.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return exitInitFailed
+	}
+	data, err := exportConfigBundle(config, passphrase, !*noSecrets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出配置失败: %v\n", err)
+		return exitTaskFailed
+	}
+	if err := os.WriteFile(fs.Arg(0), data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "写入配置包失败: %v\n", err)
+		return exitTaskFailed
+	}
+	SysLogToFile(fmt.Sprintf("配置已导出到 %s", fs.Arg(0)))
+	fmt.Printf("配置已导出到 %s\n", fs.Arg(0))
+	return exitOK
+}
+
+// runCLIConfigImport 处理 config import 命令，校验配置包并输出差异，确认无误后导入
+func runCLIConfigImport(args []string) int {
+	fs := flag.NewFlagSet("config import", flag.ContinueOnError)
+	keyEnv := fs.String("key-env", configBundleKeyEnv, "读取配置包口令的环境变量")
+	dryRun := fs.Bool("dry-run", false, "只输出与当前配置的差异，不导入")
+	force := fs.Bool("force", false, "导入后的配置校验不通过时仍然导入")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "请指定导入的配置包文件或 http(s) 地址")
+		return exitUsage
+	}
+	passphrase, err := bundlePassphrase(*keyEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	// 参数为 http(s) 地址时从远程下载配置包
+	source := configSourceImport
+	var data []byte
+	if isConfigURL(fs.Arg(0)) {
+		source = configSourceRemote
+		data, err = fetchConfigBundle(fs.Arg(0))
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置包失败: %v\n", err)
+		return exitInitFailed
+	}
+	imp, err := prepareConfigImport(data, passphrase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入配置失败: %v\n", err)
+		return exitTaskFailed
+	}
+	fmt.Print(imp.summary())
+
+	if *dryRun || len(imp.Changes) == 0 {
+		return exitOK
+	}
+	if len(imp.Errors) > 0 && !*force {
+		fmt.Fprintln(os.Stderr, "导入后的配置校验不通过，未导入；确认无误后使用 --force 导入")
+		return exitTaskFailed
+	}
+	if err := imp.apply(source); err != nil {
+		fmt.Fprintf(os.Stderr, "导入配置失败: %v\n", err)
+		return exitTaskFailed
+	}
+	fmt.Println("配置已导入")
+	return exitOK
+}
+
+// runCLISecret 处理 secret 命令
+func runCLISecret(args []string) int {
+	if len(args) == 0 || args[0] != "rotate" {
+		fmt.Fprintf(os.Stderr, "缺少或未知的 secret 子命令，可选 rotate\n")
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("secret rotate", flag.ContinueOnError)
+	machine := fs.Bool("machine", false, "改用本机标识作为口令")
+	keyEnv := fs.String("new-key-env", "", "改用该环境变量中的口令")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if *machine && *keyEnv != "" {
+		fmt.Fprintln(os.Stderr, "--machine 和 --new-key-env 不能同时使用")
+		return exitUsage
+	}
+
+	// 运行中的界面仍使用原口令，之后保存配置会用原口令覆盖轮换后的配置文件
+	if utils.AppRunning() {
+		fmt.Fprintf(os.Stderr, "程序正在运行（端口 %d 已被占用），请先退出界面和其他运行中的 gouposs 后再轮换密钥\n", utils.AppPort)
+		return exitInitFailed
+	}
+
+	// 先确认当前口令可以解密，失败时不修改任何文件
+	oldKey, err := currentSecretKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取当前口令失败: %v\n", err)
+		return exitInitFailed
+	}
+	newKey := oldKey
+	switch {
+	case *machine:
+		if newKey, err = machineSecretKey(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitInitFailed
+		}
+	case *keyEnv != "":
+		if newKey = envSecretKey(*keyEnv); newKey == nil {
+			fmt.Fprintf(os.Stderr, "环境变量 %s 未设置或为空\n", *keyEnv)
+			return exitUsage
+		}
+	}
+
+	skipped, err := rotateConfigSecrets("config.json", newKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "轮换密钥失败: %v\n", err)
+		return exitTaskFailed
+	}
+	SysLogToFile(fmt.Sprintf("配置密钥已轮换，口令来源: %s", newKey.source))
+
+	fmt.Printf("已用新的密钥重新加密配置文件和配置历史，口令来源: %s\n", newKey.source)
+	if skipped > 0 {
+		fmt.Printf("%d 个历史版本无法用原口令解密，保持不变\n", skipped)
+	}
+	if newKey.passphrase != oldKey.passphrase {
+		if *machine {
+			fmt.Printf("之后运行程序时请取消环境变量 %s\n", configSecretEnv)
+		} else {
+			fmt.Printf("之后运行程序时请将环境变量 %s 设置为 %s 中的口令\n", configSecretEnv, *keyEnv)
+		}
+	}
+	return exitOK
+}
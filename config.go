@@ -2,15 +2,20 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"go-uposs/utils" // 导入 utils 包
 )
 
 // Config 配置结构体
 type Config struct {
+	SchemaVersion int    `json:"schema_version"`        // 配置文件版本，由 SaveConfig 写入，旧版本文件在 LoadConfig 时自动升级
+	SecretSalt    string `json:"secret_salt,omitempty"` // 加密 accessKeyID、secretAccessKey、webhook_url 等密钥字段使用的盐，由 SaveConfig 生成
+
 	MachineCode     string `json:"machine_code"`
 	BucketName      string `json:"bucket_name"`
 	Endpoint        string `json:"endpoint"`   // 端点
@@ -20,20 +25,62 @@ type Config struct {
 
 	UseSSL bool `json:"useSSL"`
 
+	S3Region             string `json:"s3_region"`               // 区域，为空时向服务器查询
+	S3BucketLookup       string `json:"s3_bucket_lookup"`        // 存储桶地址风格：auto（默认，按端点判断）、path 或 virtual-host
+	S3SessionToken       string `json:"s3_session_token"`        // 临时凭证（STS）的会话令牌，与 accessKeyID、secretAccessKey 一起使用
+	S3CAFile             string `json:"s3_ca_file"`              // 自签名证书的 CA 文件（PEM），与系统证书一起校验服务器证书
+	S3InsecureSkipVerify bool   `json:"s3_insecure_skip_verify"` // 不校验服务器证书，只用于内网测试
+	S3Proxy              string `json:"s3_proxy"`                // 代理地址 http://、https:// 或 socks5://，为空时使用环境变量 HTTPS_PROXY、HTTP_PROXY
+	S3ConnectTimeout     int    `json:"s3_connect_timeout"`      // 建立连接和 TLS 握手的超时时间，单位秒，0 使用默认值
+	S3ReadTimeout        int    `json:"s3_read_timeout"`         // 发送请求后等待响应的超时时间，单位秒，0 使用默认值
+	S3MaxIdleConns       int    `json:"s3_max_idle_conns"`       // 每个主机保留的空闲连接数，0 使用默认值
+	S3MaxConnsPerHost    int    `json:"s3_max_conns_per_host"`   // 每个主机的最大连接数，0 表示不限制
+
+	StorageType   string `json:"storage_type"`    // 存储类型：minio（默认）或 local
+	LocalStoreDir string `json:"local_store_dir"` // storage_type 为 local 时的存储根目录，存储桶为其中的子文件夹
+
+	WebDAVURL      string `json:"webdav_url"` // storage_type 为 webdav 时共享的根地址，存储桶为其中的子文件夹
+	WebDAVUser     string `json:"webdav_user"`
+	WebDAVPassword string `json:"webdav_password"`
+
+	SFTPHost     string `json:"sftp_host"` // storage_type 为 sftp 时的服务器，host 或 host:port
+	SFTPUser     string `json:"sftp_user"`
+	SFTPPassword string `json:"sftp_password"`
+	SFTPKeyFile  string `json:"sftp_key_file"` // 私钥文件，与 sftp_password 至少设置一个
+	SFTPHostKey  string `json:"sftp_host_key"` // 服务器公钥指纹 SHA256:...，测试连接时显示
+	SFTPDir      string `json:"sftp_dir"`      // 服务器上的根目录，存储桶为其中的子文件夹，为空时使用登录后的目录
+
+	PublicBaseURL string `json:"public_base_url"` // 推送到 API2 的文件地址前缀，地址为 <public_base_url>/<对象路径>；为空时为 <public_url>/<bucket_name>/<对象路径>
+
 	LocalFolder  string `json:"local_folder"`  // 复制到本地的路径
 	RemoteFolder string `json:"remote_folder"` // 源获取路径
 
-	PicCompress string `json:"pic_compress"` // 图片压缩比率
-	PicWidth    string `json:"pic_width"`    // 图片宽度
-	PicSize     int    `json:"pic_size"`     // 图片体积过滤，单位KB
+	PicCompress int `json:"pic_compress"` // 图片压缩比率
+	PicWidth    int `json:"pic_width"`    // 图片宽度
+	PicSize     int `json:"pic_size"`     // 图片体积过滤，单位KB
+
+	PicWorkers   int `json:"pic_workers"`    // 图片压缩并发协程数，0 使用 CPU 核数
+	PicMemBudget int `json:"pic_mem_budget"` // 图片压缩内存预算，单位MB，0 使用默认值
 
 	StartTime string `json:"start_time"` // 开始时间
 	EndTime   string `json:"end_time"`   // 结束时间
 
-	IOBuffer int `json:"io_buffer"` // 缓冲区大小，以KB为单位
+	IOBuffer int `json:"io_buffer"` // 缓冲区大小，以KB为单位，复制文件时使用 IOBufferBytes
+
+	AutoInterval int `json:"auto_interval"` // 自动间隔时间，单位秒
+	SchedTimes   int `json:"sched_times"`   // 计划任务执行次数
 
-	AutoInterval string `json:"auto_interval"` // 自动间隔时间
-	SchedTimes   string `json:"sched_times"`   // 计划任务执行次数
+	AutoWatch     bool `json:"auto_watch"`     // 自动任务监听模式：文件变化时立即复制，auto_interval 作为全量扫描间隔
+	WatchDebounce int  `json:"watch_debounce"` // 监听模式防抖时间，单位秒，0 使用默认值
+
+	AutoCron      string `json:"auto_cron"`       // 自动任务 cron 表达式（支持秒），为空时按 auto_interval 执行
+	SchedCron     string `json:"sched_cron"`      // 计划任务 cron 表达式，为空时点击开始后立即执行
+	SchedCronDays int    `json:"sched_cron_days"` // cron 触发计划任务时处理最近几天（不含今天），0 使用 start_time/end_time
+	CronTimezone  string `json:"cron_timezone"`   // cron 时区，例如 Asia/Shanghai，为空使用系统时区
+	CronCatchUp   bool   `json:"cron_catch_up"`   // 错过执行时间（休眠、程序未运行）后是否立即补执行一次
+
+	AutoDryRun  bool `json:"auto_dry_run"`  // 自动任务模拟运行：只输出将要执行的操作，不复制、压缩、上传、推送和删除
+	SchedDryRun bool `json:"sched_dry_run"` // 计划任务模拟运行
 
 	API1          string `json:"api1"`           // API1 URL
 	API2          string `json:"api2"`           // API2 URL
@@ -41,17 +88,32 @@ type Config struct {
 	API1Response2 string `json:"api1_response2"` // API1 编号查询无效响应
 	WebhookURL    string `json:"webhook_url"`    // 企业微信Webhook URL
 
-	CleanStartTime string `json:"cleaStartTime"`
-	CleanEndTime   string `json:"cleanEndTime"`
+	UploadWorkers int     `json:"upload_workers"`  // 上传并发协程数，0 使用默认值
+	API1RateLimit float64 `json:"api1_rate_limit"` // API1 每秒最大请求数，0 表示不限速
+	API2RateLimit float64 `json:"api2_rate_limit"` // API2 每秒最大请求数，0 表示不限速
+
+	CleanStartTime string `json:"clean_start_time"` // 清理开始时间
+	CleanEndTime   string `json:"clean_end_time"`   // 清理结束时间
 
 	// AutoStart 是否开机自启动
-	AutoStart string `json:"autostart"`
+	AutoStart bool `json:"autostart"`
 
 	// AutoStartAutoTask 是否在开机启动后自动执行AutoTask任务
-	AutoStartAutoTask string `json:"autostart_auto"`
+	AutoStartAutoTask bool `json:"autostart_auto"`
 
 	// LockUI 是否锁定界面
-	LockUI string `json:"lockui"`
+	LockUI bool `json:"lockui"`
+
+	// Profiles 配置方案，每个方案作为一条独立的产线由自动任务和计划任务分别执行，顶层字段为默认方案
+	Profiles []Profile `json:"profiles,omitempty"`
+
+	// Profile 由 ForProfile 生成的配置所属的方案名称，默认方案为空，不写入配置文件
+	Profile string `json:"-"`
+}
+
+// IOBufferBytes 返回以字节为单位的缓冲区大小
+func (config *Config) IOBufferBytes() int {
+	return config.IOBuffer * 1024
 }
 
 // LoadConfig 从文件加载配置
@@ -59,72 +121,155 @@ func LoadConfig(filename string) (*Config, error) {
 	// 使用 utils.DataPath 构建完整的配置文件路径
 	configFilePath := filepath.Join(utils.DataPath, filename)
 
-	file, err := os.Open(configFilePath)
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 旧版本的配置文件先备份再升级到当前版本
+	data, err = upgradeConfigFile(filename, data)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
 	config := &Config{}
-	if err := json.NewDecoder(file).Decode(config); err != nil {
+	if err := json.Unmarshal(data, config); err != nil {
 		return nil, err
 	}
 
-	// 将缓冲区大小从KB转换为字节
-	config.IOBuffer *= 1024
+	// 密钥字段只在内存中解密；手动写入的明文密钥立即加密保存
+	plaintext, err := decryptConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if plaintext {
+		if err := saveConfigFile(filename, config, "加密明文密钥"); err != nil {
+			return nil, fmt.Errorf("加密明文密钥失败: %v", err)
+		}
+		if err := encryptConfigHistory(filename); err != nil {
+			SysLogToFile(fmt.Sprintf("加密配置历史失败: %v", err))
+		}
+		SysLogToFile("配置文件中的明文密钥已加密保存")
+	}
 
 	return config, nil
 }
 
 // SaveConfig 保存格式化的 JSON 配置到文件
 func SaveConfig(filename string, config *Config) error {
+	return saveConfigFile(filename, config, "")
+}
+
+// saveConfigFile 保存配置并记录历史版本，note 为历史版本的说明
+// 保存期间持有配置文件锁，多个界面或进程同时保存时依次写入；先写临时文件再重命名，写入中断不会损坏原文件
+func saveConfigFile(filename string, config *Config, note string) error {
 	// 使用 utils.DataPath 构建完整的配置文件路径
 	configFilePath := filepath.Join(utils.DataPath, filename)
 
-	// 将缓冲区大小从字节转换为KB
-	config.IOBuffer /= 1024
+	data, err := encodeConfigFile(config)
+	if err != nil {
+		return err
+	}
 
-	data, err := json.MarshalIndent(config, "", "  ") // 美化 JSON
+	unlock, err := utils.LockFile(configLockPath(filename))
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	// 读取保存前的配置，用于记录变更；旧版本或无法解析的文件不参与比较
+	// 无法用当前口令解密时说明密钥已被其他程序轮换，不能用原口令覆盖
+	previous, err := readConfigFile(filename)
+	if errors.Is(err, errConfigUndecryptable) {
+		return fmt.Errorf("%w，可能已在其他程序中轮换密钥，请使用新口令重新启动程序后再保存", err)
+	}
+	var previousSavedAt time.Time
+	if info, err := os.Stat(configFilePath); err == nil {
+		previousSavedAt = info.ModTime()
+	}
+
+	if err := utils.WriteFileAtomic(configFilePath, data, 0644); err != nil {
+		return err
+	}
 
-	// 将缓冲区大小从KB转换为字节
-	config.IOBuffer *= 1024
+	// 历史记录失败不影响保存结果
+	if err := recordConfigVersion(filename, previous, config, note, previousSavedAt); err != nil {
+		SysLogToFile(fmt.Sprintf("记录配置历史失败: %v", err))
+	}
+	return nil
+}
 
-	return os.WriteFile(configFilePath, data, 0644) // 直接写入文件
+// configLockPath 返回保存配置文件时使用的锁文件路径
+func configLockPath(filename string) string {
+	return filepath.Join(utils.DataPath, filename) + ".lock"
 }
 
-// UpdatePicConfig 更新图片相关的配置（压缩比率和宽度）
-func (config *Config) UpdatePicConfig(compress, width string, size int) {
-	config.PicCompress = compress
-	config.PicWidth = width
-	config.PicSize = size
+// encodeConfigFile 返回写入配置文件的 JSON，保存的配置总是当前版本，密钥字段使用当前口令加密
+func encodeConfigFile(config *Config) ([]byte, error) {
+	config.SchemaVersion = configSchemaVersion
+
+	stored, err := encryptedConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(stored, "", "  ") // 美化 JSON
+}
+
+// errConfigUndecryptable 当前版本的配置文件无法用本进程的口令解密
+var errConfigUndecryptable = errors.New("配置文件无法用当前口令解密")
+
+// readConfigFile 读取当前版本的配置文件并解密，不升级、不保存，可在持有配置文件锁时调用
+func readConfigFile(filename string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(utils.DataPath, filename))
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if config.SchemaVersion != configSchemaVersion {
+		return nil, fmt.Errorf("配置文件版本 %d 不是当前版本 %d", config.SchemaVersion, configSchemaVersion)
+	}
+	if _, err := decryptConfig(config); err != nil {
+		return nil, fmt.Errorf("%w: %v", errConfigUndecryptable, err)
+	}
+	return config, nil
+}
 
+// UpdatePicConfig 更新图片相关的配置（压缩比率和宽度）
+func (config *Config) UpdatePicConfig(compress, width, size int) {
 	// 更新配置文件
-	if err := SaveConfig("config.json", config); err != nil { // 使用相对路径
+	err := updateConfig(config, func(c *Config) {
+		c.PicCompress = compress
+		c.PicWidth = width
+		c.PicSize = size
+	})
+	if err != nil {
 		fmt.Printf("更新图片配置失败: %v\n", err)
 	}
 }
 
 // UpdateTimeRange 更新计划任务开始时间和结束时间
 func (config *Config) UpdateTimeRange(startTime, endTime string) {
-	config.StartTime = startTime
-	config.EndTime = endTime
-
 	// 更新配置文件
-	if err := SaveConfig("config.json", config); err != nil { // 使用相对路径
+	err := updateConfig(config, func(c *Config) {
+		c.StartTime = startTime
+		c.EndTime = endTime
+	})
+	if err != nil {
 		fmt.Printf("更新时间范围失败: %v\n", err)
 	}
 }
 
 // UpdateTimeRange 更新清理开始时间和结束时间
 func (config *Config) UpdateCleanTimeRange(cleanStartTime, cleanEndTime string) {
-	config.CleanStartTime = cleanStartTime
-	config.CleanEndTime = cleanEndTime
-
 	// 更新配置文件
-	if err := SaveConfig("config.json", config); err != nil { // 使用相对路径
+	err := updateConfig(config, func(c *Config) {
+		c.CleanStartTime = cleanStartTime
+		c.CleanEndTime = cleanEndTime
+	})
+	if err != nil {
 		fmt.Printf("更新时间范围失败: %v\n", err)
 	}
 }
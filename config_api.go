@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go-uposs/utils"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -75,12 +76,30 @@ func createAPIConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	webhookEntry := widget.NewEntry()
 	webhookEntry.SetText(config.WebhookURL)
 
+	api1RateEntry := widget.NewEntry() // API1 每秒请求数，0 不限速
+	api1RateEntry.SetText(strconv.FormatFloat(config.API1RateLimit, 'f', -1, 64))
+
+	api2RateEntry := widget.NewEntry() // API2 每秒请求数，0 不限速
+	api2RateEntry.SetText(strconv.FormatFloat(config.API2RateLimit, 'f', -1, 64))
+
+	// 配置被其他程序修改或恢复历史版本时同步变化的字段
+	onConfigChange(func(old, c *Config) {
+		syncEntry(api1Entry, old.API1, c.API1)
+		syncEntry(api2Entry, old.API2, c.API2)
+		syncEntry(api1response1, old.API1Response1, c.API1Response1)
+		syncEntry(api1response2, old.API1Response2, c.API1Response2)
+		syncEntry(webhookEntry, old.WebhookURL, c.WebhookURL)
+		syncEntry(api1RateEntry, strconv.FormatFloat(old.API1RateLimit, 'f', -1, 64), strconv.FormatFloat(c.API1RateLimit, 'f', -1, 64))
+		syncEntry(api2RateEntry, strconv.FormatFloat(old.API2RateLimit, 'f', -1, 64), strconv.FormatFloat(c.API2RateLimit, 'f', -1, 64))
+	})
+
 	// 创建标签
 	api1Label := widget.NewLabel("API 1:")
 	api2Label := widget.NewLabel("API 2:")
 	api1Response1Label := widget.NewLabel("API1 有效响应:")
 	api1Response2Label := widget.NewLabel("API1 无效响应:")
 	webhookLabel := widget.NewLabel("Webhook URL:")
+	rateLabel := widget.NewLabel("限速(次/秒):")
 
 	// 创建日志输出框
 	apiLogText := widget.NewMultiLineEntry()
@@ -90,15 +109,27 @@ func createAPIConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	saveButton := widget.NewButton("保存配置", func() {
 		dialog.ShowConfirm("确认保存", "确定要保存配置吗？", func(confirm bool) {
 			if confirm {
-				config.API1 = api1Entry.Text
-				config.API2 = api2Entry.Text
-				config.API1Response1 = api1response1.Text
-				config.API1Response2 = api1response2.Text
-				config.WebhookURL = webhookEntry.Text
+				// 验证限速输入
+				api1Rate, err1 := strconv.ParseFloat(api1RateEntry.Text, 64)
+				api2Rate, err2 := strconv.ParseFloat(api2RateEntry.Text, 64)
+				if err1 != nil || err2 != nil {
+					updateLog(apiLogText, "[API配置]", "请输入有效的限速（大于等于 0 的数字，0 表示不限速）")
+					return
+				}
+
+				err := updateConfig(config, func(c *Config) {
+					c.API1RateLimit = api1Rate
+					c.API2RateLimit = api2Rate
+					c.API1 = api1Entry.Text
+					c.API2 = api2Entry.Text
+					c.API1Response1 = api1response1.Text
+					c.API1Response2 = api1response2.Text
+					c.WebhookURL = webhookEntry.Text
+				}, apiConfigFields...)
 
 				// 假设你有一个 apiLogText 变量表示 API 配置那一栏的日志框
-				if err := SaveConfig("config.json", config); err != nil {
-					errorMsg := fmt.Sprintf("保存配置失败: %v", err)
+				if err != nil {
+					errorMsg := configSaveError(err)
 					updateLog(apiLogText, "[API配置]", errorMsg)
 					dialog.ShowInformation("保存失败", errorMsg, myWindow)
 				} else {
@@ -181,6 +212,14 @@ func createAPIConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		container.NewGridWrap(fyne.NewSize(float32(entryWidth), utils.LEBHeight), webhookEntry),
 	)
 
+	rateContainer := container.NewHBox(
+		container.NewGridWrap(fyne.NewSize(float32(labelWidth), utils.LEBHeight), rateLabel),
+		widget.NewLabel("API1"),
+		container.NewGridWrap(fyne.NewSize(120, utils.LEBHeight), api1RateEntry),
+		widget.NewLabel("API2"),
+		container.NewGridWrap(fyne.NewSize(120, utils.LEBHeight), api2RateEntry),
+	)
+
 	// 将保存和测试按钮放在垂直容器中，放在右侧
 	buttonWidth := 150
 	buttonHeight := 32
@@ -213,6 +252,7 @@ func createAPIConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		api1Response1Container,
 		api1Response2Container,
 		webhookContainer,
+		rateContainer,
 	)
 
 	// 创建顶部容器，将输入框容器和右侧按钮容器组合
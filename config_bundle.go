@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"go-uposs/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	configBundleFormat  = "gouposs-config-bundle" // 配置包文件的 format 字段
+	configBundleVersion = 1                       // 配置包格式版本
+	configBundleKeyEnv  = "GOUPOSS_BUNDLE_KEY"    // 命令行默认从该环境变量读取配置包口令
+)
+
+// configSourceImport 配置变更来源：导入配置包
+const configSourceImport = "import"
+
+// configMachineFields 导入配置包时保留本机原有值的字段，方案中的 machine_code 按方案名称保留
+var configMachineFields = []string{"machine_code", "autostart", "autostart_auto", "lockui"}
+
+// ConfigBundle 配置包：导出的配置和签名，用于把一台机器的配置批量部署到其他机器
+// 签名和密钥加密使用导出时输入的口令，导入时需要输入相同的口令
+type ConfigBundle struct {
+	Format        string          `json:"format"`
+	Version       int             `json:"version"`
+	ExportedAt    time.Time       `json:"exported_at"`
+	ExportedBy    string          `json:"exported_by"`
+	SchemaVersion int             `json:"schema_version"` // config 的配置文件版本
+	Secrets       bool            `json:"secrets"`        // 是否包含密钥，包含时密钥以口令加密
+	Salt          string          `json:"salt"`           // 派生签名和加密密钥使用的盐
+	Config        json.RawMessage `json:"config"`
+	Signature     string          `json:"signature"` // 对其余字段的 HMAC-SHA256 签名
+}
+
+// configImport 校验通过、等待确认的配置包导入
+type configImport struct {
+	Bundle  *ConfigBundle
+	Next    *Config          // 导入后的配置，已保留本机字段
+	Changes []ConfigChange   // 相对当前配置的变更，密钥不显示明文
+	Errors  ValidationErrors // 导入后配置的校验问题
+}
+
+// bundleSigningBytes 返回签名覆盖的内容：去掉 signature 后按字段名排序的紧凑 JSON，与文件的缩进无关
+func bundleSigningBytes(data []byte) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "signature")
+	return json.Marshal(fields)
+}
+
+// bundleKey 返回配置包口令对应的密钥
+func bundleKey(passphrase string) *secretKey {
+	return &secretKey{passphrase: passphrase, source: "配置包口令"}
+}
+
+// exportConfigBundle 导出 config 为签名的配置包，withSecrets 为 false 时不包含密钥字段
+func exportConfigBundle(config *Config, passphrase string, withSecrets bool) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("配置包口令不能为空")
+	}
+	salt, err := utils.NewSecretSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	// 密钥以配置包口令重新加密，或者清空
+	exported := config.clone()
+	exported.SchemaVersion = configSchemaVersion
+	exported.SecretSalt = salt
+	if withSecrets {
+		if err := encryptConfigSecrets(exported, bundleKey(passphrase)); err != nil {
+			return nil, err
+		}
+	} else {
+		exported.SecretSalt = ""
+		for _, s := range configSecrets(exported) {
+			*s.value = ""
+		}
+	}
+	configData, err := json.Marshal(exported)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &ConfigBundle{
+		Format:        configBundleFormat,
+		Version:       configBundleVersion,
+		ExportedAt:    time.Now(),
+		ExportedBy:    configSaver(),
+		SchemaVersion: configSchemaVersion,
+		Secrets:       withSecrets,
+		Salt:          salt,
+		Config:        configData,
+	}
+	unsigned, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := bundleSigningBytes(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Signature, err = utils.SignPayload(passphrase, salt, payload); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(bundle, "", "  ")
+}
+
+// readConfigBundle 校验配置包的格式和签名，返回配置包和其中解密后的配置
+func readConfigBundle(data []byte, passphrase string) (*ConfigBundle, *Config, error) {
+	bundle := &ConfigBundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, nil, fmt.Errorf("不是有效的配置包: %v", err)
+	}
+	if bundle.Format != configBundleFormat {
+		return nil, nil, fmt.Errorf("不是配置包文件（format 应为 %s）", configBundleFormat)
+	}
+	if bundle.Version > configBundleVersion {
+		return nil, nil, fmt.Errorf("配置包格式版本 %d 高于程序支持的版本 %d，请升级程序", bundle.Version, configBundleVersion)
+	}
+
+	payload, err := bundleSigningBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := utils.VerifyPayload(passphrase, bundle.Salt, payload, bundle.Signature); err != nil {
+		if errors.Is(err, utils.ErrSecretKey) {
+			return nil, nil, fmt.Errorf("签名校验失败：口令不正确或配置包已被修改")
+		}
+		return nil, nil, fmt.Errorf("签名校验失败: %v", err)
+	}
+
+	// 旧版本程序导出的配置先升级到当前版本
+	configData, _, err := migrateConfigData(bundle.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(configData, config); err != nil {
+		return nil, nil, fmt.Errorf("解析配置包中的配置失败: %v", err)
+	}
+	if bundle.Secrets {
+		config.SecretSalt = bundle.Salt
+		if _, err := decryptConfigSecrets(config, bundleKey(passphrase)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return bundle, config, nil
+}
+
+// mergeConfigBundle 返回导入后的配置：配置包中的配置保留当前配置的本机字段，配置包不含密钥时保留当前的密钥
+func mergeConfigBundle(current, imported *Config, withSecrets bool) *Config {
+	next := imported.clone()
+	next.SchemaVersion = current.SchemaVersion
+	next.SecretSalt = current.SecretSalt
+
+	cv, nv := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for _, field := range configMachineFields {
+		i := configFieldIndex[field]
+		nv.Field(i).Set(cv.Field(i))
+	}
+	for i := range next.Profiles {
+		p := &next.Profiles[i]
+		if old := current.findProfile(p.Name); old != nil {
+			p.MachineCode = old.MachineCode
+		}
+	}
+
+	if !withSecrets {
+		for field := range configSecretFields {
+			i := configFieldIndex[field]
+			nv.Field(i).Set(cv.Field(i))
+		}
+		for i := range next.Profiles {
+			p := &next.Profiles[i]
+			if old := current.findProfile(p.Name); old != nil {
+				olds := old.secrets()
+				for j, s := range p.secrets() {
+					*s.value = *olds[j].value
+				}
+			}
+		}
+	}
+	return next
+}
+
+// prepareConfigImport 校验配置包并与当前配置比较，不修改配置
+func prepareConfigImport(data []byte, passphrase string) (*configImport, error) {
+	bundle, imported, err := readConfigBundle(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	// 与配置文件比较，环境变量和命令行的覆盖不写入配置文件
+	current, err := appConfig.File()
+	if err != nil {
+		return nil, err
+	}
+	next := mergeConfigBundle(current, imported, bundle.Secrets)
+	return &configImport{
+		Bundle:  bundle,
+		Next:    next,
+		Changes: configChanges(current, next),
+		Errors:  validationErrorsOf(next.Validate()),
+	}, nil
+}
+
+// apply 保存导入后的配置并通知各界面，导入记录在配置历史中；source 为 configSourceImport 或 configSourceRemote
+func (imp *configImport) apply(source string) error {
+	note := fmt.Sprintf("导入 %s 导出的配置包", imp.Bundle.ExportedBy)
+	if source == configSourceRemote {
+		note = fmt.Sprintf("远程下发 %s 导出的配置包", imp.Bundle.ExportedBy)
+	}
+	if err := appConfig.Replace(source, imp.Next, note); err != nil {
+		return err
+	}
+	SysLogToFile(fmt.Sprintf("已%s，修改 %d 个字段", note, len(imp.Changes)))
+	return nil
+}
+
+// summary 返回导入预览：配置包信息、保留的本机字段、变更和校验问题
+func (imp *configImport) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "配置包: %s 导出于 %s", imp.Bundle.ExportedBy, imp.Bundle.ExportedAt.Format("2006.01.02 15:04:05"))
+	if !imp.Bundle.Secrets {
+		b.WriteString("，不含密钥（保留本机密钥）")
+	}
+	fmt.Fprintf(&b, "\n保留本机字段: %s\n", strings.Join(configMachineFields, "、"))
+	if len(imp.Changes) == 0 {
+		b.WriteString("\n与当前配置相同，没有需要修改的字段\n")
+	} else {
+		fmt.Fprintf(&b, "\n将修改 %d 个字段:\n", len(imp.Changes))
+		for _, c := range imp.Changes {
+			fmt.Fprintf(&b, "%s: %s → %s\n", c.Field, c.Old, c.New)
+		}
+	}
+	if len(imp.Errors) > 0 {
+		fmt.Fprintf(&b, "\n导入后的配置有 %d 处问题:\n%v\n", len(imp.Errors), imp.Errors)
+	}
+	return b.String()
+}
+
+// showConfigExportDialog 输入口令并选择是否包含密钥，导出当前配置为配置包文件
+func showConfigExportDialog(win fyne.Window) {
+	passEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+	secretsCheck := widget.NewCheck("包含密钥（accessKeyID、secretAccessKey、s3_session_token、webhook_url、webdav_password、sftp_password）", nil)
+	secretsCheck.SetChecked(true)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("口令", passEntry),
+		widget.NewFormItem("确认口令", confirmEntry),
+		widget.NewFormItem("", secretsCheck),
+	}
+	dialog.ShowForm("导出配置", "导出", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if passEntry.Text == "" || passEntry.Text != confirmEntry.Text {
+			dialog.ShowInformation("导出失败", "口令不能为空，两次输入的口令需要一致", win)
+			return
+		}
+		config, err := appConfig.File()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("加载配置失败: %v", err), win)
+			return
+		}
+		data, err := exportConfigBundle(config, passEntry.Text, secretsCheck.Checked)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("导出配置失败: %v", err), win)
+			return
+		}
+
+		save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+			if err != nil || w == nil {
+				return
+			}
+			defer w.Close()
+			if _, err := w.Write(data); err != nil {
+				dialog.ShowError(fmt.Errorf("写入配置包失败: %v", err), win)
+				return
+			}
+			aboutLogToUIAndSystem(fmt.Sprintf("配置已导出到 %s", w.URI().Path()))
+			dialog.ShowInformation("导出成功", "配置包已保存，导入时需要输入相同的口令", win)
+		}, win)
+		save.SetFileName(fmt.Sprintf("gouposs-config-%s.json", time.Now().Format("20060102")))
+		save.Show()
+	}, win)
+}
+
+// showConfigImportDialog 选择配置包并输入口令，显示与当前配置的差异，确认后导入
+func showConfigImportDialog(win fyne.Window) {
+	dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil || r == nil {
+			return
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("读取配置包失败: %v", err), win)
+			return
+		}
+
+		passEntry := widget.NewPasswordEntry()
+		dialog.ShowForm("导入配置", "校验", "取消", []*widget.FormItem{widget.NewFormItem("口令", passEntry)}, func(ok bool) {
+			if !ok {
+				return
+			}
+			imp, err := prepareConfigImport(data, passEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("导入配置失败: %v", err), win)
+				return
+			}
+			if len(imp.Changes) == 0 {
+				dialog.ShowInformation("导入配置", imp.summary(), win)
+				return
+			}
+
+			detail := widget.NewLabel(imp.summary())
+			detail.Wrapping = fyne.TextWrapWord
+			content := container.NewGridWrap(fyne.NewSize(700, 360), container.NewVScroll(detail))
+			dialog.ShowCustomConfirm("导入配置", "导入", "取消", content, func(confirm bool) {
+				if !confirm {
+					return
+				}
+				if err := imp.apply(configSourceImport); err != nil {
+					dialog.ShowError(fmt.Errorf("导入配置失败: %v", err), win)
+					return
+				}
+				dialog.ShowInformation("导入成功", "配置已导入，各配置界面已更新，任务在下一个周期使用新配置。", win)
+			}, win)
+		}, win)
+	}, win)
+}
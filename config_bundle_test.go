@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// setBundleField 修改配置包顶层字段 key 的原始 JSON，其余字段保持原样
+func setBundleField(t *testing.T, data []byte, key, value string) []byte {
+	t.Helper()
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields[key] = json.RawMessage(value)
+	out, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestReadConfigBundle(t *testing.T) {
+	config := testConfig(t, t.TempDir())
+	bundle, err := exportConfigBundle(config, testBundleKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(bundle, []byte(config.SecretAccessKey)) {
+		t.Error("配置包中的密钥应以口令加密")
+	}
+
+	_, imported, err := readConfigBundle(bundle, testBundleKey)
+	if err != nil {
+		t.Fatalf("readConfigBundle: %v", err)
+	}
+	if imported.SecretAccessKey != config.SecretAccessKey || imported.WebhookURL != config.WebhookURL || imported.PicCompress != config.PicCompress {
+		t.Errorf("读取的配置 = %+v", imported)
+	}
+
+	tamperedConfig := bytes.Replace(bundle, []byte(`"pic_compress": 80`), []byte(`"pic_compress": 81`), 1)
+	if bytes.Equal(tamperedConfig, bundle) {
+		t.Fatal("没有找到需要修改的字段")
+	}
+	for _, tc := range []struct {
+		name       string
+		data       []byte
+		passphrase string
+		want       string // 错误信息应包含的内容
+	}{
+		{"配置被修改", tamperedConfig, testBundleKey, "签名校验失败"},
+		{"导出信息被修改", setBundleField(t, bundle, "exported_by", `"someone-else"`), testBundleKey, "签名校验失败"},
+		{"改为不含密钥", setBundleField(t, bundle, "secrets", `false`), testBundleKey, "签名校验失败"},
+		{"口令不正确", bundle, "wrong-passphrase", "签名校验失败"},
+		{"没有签名", setBundleField(t, bundle, "signature", `""`), testBundleKey, "签名校验失败"},
+		{"不是配置包", setBundleField(t, bundle, "format", `"other"`), testBundleKey, "不是配置包文件"},
+		{"格式版本过高", setBundleField(t, bundle, "version", `99`), testBundleKey, "高于程序支持的版本"},
+		{"不是 JSON", []byte("config"), testBundleKey, "不是有效的配置包"},
+	} {
+		_, _, err := readConfigBundle(tc.data, tc.passphrase)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: readConfigBundle = %v，应包含 %q", tc.name, err, tc.want)
+		}
+	}
+
+	// 不含密钥的配置包
+	bundle, err = exportConfigBundle(config, testBundleKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(bundle, []byte(config.SecretAccessKey)) || bytes.Contains(bundle, []byte("enc:v1:")) {
+		t.Error("不含密钥的配置包中不应有密钥字段的值")
+	}
+	if _, imported, err := readConfigBundle(bundle, testBundleKey); err != nil {
+		t.Errorf("读取不含密钥的配置包: %v", err)
+	} else if imported.SecretAccessKey != "" {
+		t.Errorf("不含密钥的配置包中 secretAccessKey = %q，应为空", imported.SecretAccessKey)
+	}
+}
+
+func TestMergeConfigBundle(t *testing.T) {
+	current := &Config{
+		MachineCode: "machine-01", AutoStart: true, AutoStartAutoTask: true, LockUI: true,
+		AccessKeyID: "local-ak", PicCompress: 80, SchemaVersion: configSchemaVersion, SecretSalt: "local-salt",
+		Profiles: []Profile{{Name: "line2", MachineCode: "machine-02", SFTPPassword: "local-sftp"}},
+	}
+	imported := &Config{
+		MachineCode: "source-01", PicCompress: 50, SecretSalt: "bundle-salt",
+		Profiles: []Profile{
+			{Name: "line2", MachineCode: "source-02", PicCompress: 70},
+			{Name: "line3", MachineCode: "source-03"},
+		},
+	}
+
+	for _, withSecrets := range []bool{true, false} {
+		in := imported.clone()
+		if withSecrets {
+			in.AccessKeyID = "bundle-ak"
+			in.Profiles[0].SFTPPassword = "bundle-sftp"
+		}
+		next := mergeConfigBundle(current, in, withSecrets)
+
+		if next.MachineCode != "machine-01" || !next.AutoStart || !next.AutoStartAutoTask || !next.LockUI {
+			t.Errorf("withSecrets=%v: 本机字段 machine_code = %s、autostart = %v、autostart_auto = %v、lockui = %v，应保留本机的值",
+				withSecrets, next.MachineCode, next.AutoStart, next.AutoStartAutoTask, next.LockUI)
+		}
+		if next.SecretSalt != "local-salt" || next.PicCompress != 50 {
+			t.Errorf("withSecrets=%v: secret_salt = %s、pic_compress = %d，应为 local-salt、50", withSecrets, next.SecretSalt, next.PicCompress)
+		}
+		// 已有方案保留本机的 machine_code，新方案使用配置包中的值
+		if p := next.findProfile("line2"); p == nil || p.MachineCode != "machine-02" || p.PicCompress != 70 {
+			t.Errorf("withSecrets=%v: 方案 line2 = %+v，machine_code 应为 machine-02", withSecrets, p)
+		}
+		if p := next.findProfile("line3"); p == nil || p.MachineCode != "source-03" {
+			t.Errorf("withSecrets=%v: 方案 line3 = %+v，machine_code 应为 source-03", withSecrets, p)
+		}
+
+		wantAK, wantSFTP := "local-ak", "local-sftp"
+		if withSecrets {
+			wantAK, wantSFTP = "bundle-ak", "bundle-sftp"
+		}
+		if next.AccessKeyID != wantAK || next.findProfile("line2").SFTPPassword != wantSFTP {
+			t.Errorf("withSecrets=%v: accessKeyID = %s、line2.sftp_password = %s，应为 %s、%s",
+				withSecrets, next.AccessKeyID, next.findProfile("line2").SFTPPassword, wantAK, wantSFTP)
+		}
+	}
+	if imported.MachineCode != "source-01" || imported.Profiles[0].MachineCode != "source-02" {
+		t.Error("mergeConfigBundle 不应修改配置包中的配置")
+	}
+}
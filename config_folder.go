@@ -42,7 +42,14 @@ func createFolderConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObjec
 	// 初始化 remoteFolderEntry、localFolderEntry、ioBufferEntry 内容
 	remoteFolderEntry.SetText(config.RemoteFolder)
 	localFolderEntry.SetText(config.LocalFolder)
-	ioBufferEntry.SetText(strconv.Itoa(config.IOBuffer / 1024)) // 将字节转换为KB
+	ioBufferEntry.SetText(strconv.Itoa(config.IOBuffer))
+
+	// 配置被其他程序修改或恢复历史版本时同步变化的字段
+	onConfigChange(func(old, c *Config) {
+		syncEntry(remoteFolderEntry, old.RemoteFolder, c.RemoteFolder)
+		syncEntry(localFolderEntry, old.LocalFolder, c.LocalFolder)
+		syncEntry(ioBufferEntry, strconv.Itoa(old.IOBuffer), strconv.Itoa(c.IOBuffer))
+	})
 
 	// 清空日志框
 	folderLogText.SetText("")
@@ -52,28 +59,22 @@ func createFolderConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObjec
 	saveButton := widget.NewButton("保存配置", func() {
 		dialog.ShowConfirm("确认保存", "你确定要保存配置吗？", func(confirm bool) {
 			if confirm {
-				// 更新配置
-				config.RemoteFolder = remoteFolderEntry.Text
-				config.LocalFolder = localFolderEntry.Text
-
 				// 获取用户输入的缓冲区大小
 				ioBuffer, err := strconv.Atoi(ioBufferEntry.Text)
 				if err != nil {
 					updateLog(folderLogText, "[文件夹配置]", fmt.Sprintf("无效的缓冲区大小: %s", err.Error()))
 					return
 				}
-				config.IOBuffer = ioBuffer * 1024 // 将KB转换为字节
 
-				// 检查路径是否为空
-				if config.RemoteFolder == "" || config.LocalFolder == "" {
-					updateLog(folderLogText, "[文件夹配置]", "路径不能为空，请检查输入。")
-					return
-				}
-
-				// 保存配置 - 直接使用文件名，不构建路径
-				if err := SaveConfig("config.json", config); err != nil {
+				// 校验路径和缓冲区大小后保存配置
+				err = updateConfig(config, func(c *Config) {
+					c.RemoteFolder = remoteFolderEntry.Text
+					c.LocalFolder = localFolderEntry.Text
+					c.IOBuffer = ioBuffer
+				}, folderConfigFields...)
+				if err != nil {
 					// 保存失败，显示错误信息
-					updateLog(folderLogText, "[文件夹配置]", fmt.Sprintf("保存配置失败: %s", err.Error()))
+					updateLog(folderLogText, "[文件夹配置]", configSaveError(err))
 				} else {
 					// 配置保存成功，更新日志
 					updateLog(folderLogText, "[文件夹配置]", "配置保存成功！")
@@ -128,7 +129,7 @@ func createFolderConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObjec
 
 	// 记录载入界面信息到系统日志
 	SysLogToFile(fmt.Sprintf("[文件夹配置] 配置已载入，Remote=%s, Local=%s, IOBuffer=%dKB",
-		config.RemoteFolder, config.LocalFolder, config.IOBuffer/1024))
+		config.RemoteFolder, config.LocalFolder, config.IOBuffer))
 
 	// 使用 container.NewVBox 将输入框、按钮和日志输出框垂直排列
 	return container.NewVBox(inputAndButtonContainer, folderLogText)
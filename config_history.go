@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go-uposs/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// configHistoryLimit 每个配置文件保留的历史版本数
+const configHistoryLimit = 20
+
+// configHistoryDir 配置历史版本保存在 data 文件夹下的子目录
+const configHistoryDir = "config_history"
+
+// ConfigChange 一个字段的变更
+type ConfigChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ConfigVersion 配置文件的一个历史版本：保存时间、保存者、相对上一版本的变更和完整配置
+type ConfigVersion struct {
+	SavedAt time.Time      `json:"saved_at"`
+	SavedBy string         `json:"saved_by"`       // 用户@主机（界面或命令行）
+	Note    string         `json:"note,omitempty"` // 说明，例如版本升级、恢复历史版本
+	Changes []ConfigChange `json:"changes,omitempty"`
+	Config  *Config        `json:"config"`
+}
+
+// summary 返回变更的简短描述，用于界面列表
+func (v *ConfigVersion) summary() string {
+	var parts []string
+	if v.Note != "" {
+		parts = append(parts, v.Note)
+	}
+	if len(v.Changes) > 0 {
+		fields := make([]string, len(v.Changes))
+		for i, c := range v.Changes {
+			fields[i] = c.Field
+		}
+		parts = append(parts, "修改 "+strings.Join(fields, ", "))
+	}
+	if len(parts) == 0 {
+		return "无字段变更"
+	}
+	return strings.Join(parts, "；")
+}
+
+// details 返回每个字段的变更明细
+func (v *ConfigVersion) details() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %s\n", v.SavedAt.Format("2006.01.02 15:04:05"), v.SavedBy)
+	if v.Note != "" {
+		fmt.Fprintf(&b, "%s\n", v.Note)
+	}
+	for _, c := range v.Changes {
+		fmt.Fprintf(&b, "%s: %s → %s\n", c.Field, c.Old, c.New)
+	}
+	return b.String()
+}
+
+// configSaver 返回保存者描述：用户@主机（界面/命令行）
+func configSaver() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	mode := "界面"
+	if headlessMode {
+		mode = "命令行"
+	}
+	return fmt.Sprintf("%s@%s（%s）", name, host, mode)
+}
+
+// configChanges 按 Config 字段顺序比较两个配置，old 为 nil 时返回 nil
+func configChanges(old, new *Config) []ConfigChange {
+	if old == nil || new == nil {
+		return nil
+	}
+	var changes []ConfigChange
+	ov, nv := reflect.ValueOf(*old), reflect.ValueOf(*new)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if field == "" || field == "-" || field == "schema_version" || field == "secret_salt" {
+			continue
+		}
+		if field == "profiles" {
+			// 方案只记录名称，不显示其中的密钥
+			if !profilesEqual(old.Profiles, new.Profiles) {
+				changes = append(changes, ConfigChange{Field: field, Old: profilesSummary(old.Profiles), New: profilesSummary(new.Profiles) + "（已修改）"})
+			}
+			continue
+		}
+		o, n := fmt.Sprint(ov.Field(i).Interface()), fmt.Sprint(nv.Field(i).Interface())
+		if o == n {
+			continue
+		}
+		if configSecretFields[field] {
+			o, n = "******", "******（已修改）"
+		}
+		changes = append(changes, ConfigChange{Field: field, Old: o, New: n})
+	}
+	return changes
+}
+
+// configHistoryPrefix 返回配置文件历史版本的文件名前缀，例如 config.json → config_
+func configHistoryPrefix(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + "_"
+}
+
+// recordConfigVersion 记录一个历史版本并删除超出 configHistoryLimit 的旧版本
+// previous 为保存前的配置（无法读取时为 nil）；没有历史版本时先把 previous 记录为基准版本，以便恢复到第一次保存之前
+func recordConfigVersion(filename string, previous, current *Config, note string, previousSavedAt time.Time) error {
+	dir := filepath.Join(utils.DataPath, configHistoryDir)
+	if err := utils.EnsureDirExists(dir); err != nil {
+		return fmt.Errorf("创建配置历史目录失败: %v", err)
+	}
+
+	versions, err := listConfigVersions(filename)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 && previous != nil {
+		base := &ConfigVersion{SavedAt: previousSavedAt, SavedBy: "-", Note: "首次保存前的配置", Config: previous}
+		if err := writeConfigVersion(filename, base); err != nil {
+			return err
+		}
+	}
+
+	changes := configChanges(previous, current)
+	if previous != nil && len(changes) == 0 && note == "" {
+		return nil // 内容没有变化时不记录
+	}
+	v := &ConfigVersion{SavedAt: time.Now(), SavedBy: configSaver(), Note: note, Changes: changes, Config: current.clone()}
+	if err := writeConfigVersion(filename, v); err != nil {
+		return err
+	}
+
+	return pruneConfigVersions(filename)
+}
+
+// writeConfigVersion 将历史版本写入 config_history/<前缀><时间>.json，密钥字段与配置文件一样加密保存
+func writeConfigVersion(filename string, v *ConfigVersion) error {
+	path, data, err := encodeConfigVersion(filename, v)
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("写入配置历史失败: %v", err)
+	}
+	return nil
+}
+
+// encodeConfigVersion 返回历史版本的文件路径和使用当前口令加密后的内容
+func encodeConfigVersion(filename string, v *ConfigVersion) (string, []byte, error) {
+	stored, err := encryptedConfig(v.Config)
+	if err != nil {
+		return "", nil, err
+	}
+	sv := *v
+	sv.Config = stored
+	data, err := json.MarshalIndent(&sv, "", "  ")
+	if err != nil {
+		return "", nil, fmt.Errorf("编码配置历史失败: %v", err)
+	}
+	name := configHistoryPrefix(filename) + v.SavedAt.Format("20060102_150405.000000") + ".json"
+	return filepath.Join(utils.DataPath, configHistoryDir, name), data, nil
+}
+
+// configVersionFiles 按时间倒序返回配置文件的历史版本路径
+func configVersionFiles(filename string) ([]string, error) {
+	dir := filepath.Join(utils.DataPath, configHistoryDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取配置历史目录失败: %v", err)
+	}
+
+	prefix := configHistoryPrefix(filename)
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	// 文件名中的时间格式固定，按名称倒序即按时间倒序
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// listConfigVersions 按时间倒序列出配置文件的历史版本，密钥字段已解密；无法解析或解密的历史文件会被跳过
+func listConfigVersions(filename string) ([]ConfigVersion, error) {
+	files, err := configVersionFiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []ConfigVersion
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var v ConfigVersion
+		if err := json.Unmarshal(data, &v); err != nil || v.Config == nil {
+			SysLogToFile(fmt.Sprintf("跳过无法解析的配置历史 %s: %v", path, err))
+			continue
+		}
+		if _, err := decryptConfig(v.Config); err != nil {
+			SysLogToFile(fmt.Sprintf("跳过无法解密的配置历史 %s: %v", path, err))
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// encryptConfigHistory 重新写入全部历史版本，加密其中的明文密钥
+func encryptConfigHistory(filename string) error {
+	versions, err := listConfigVersions(filename)
+	if err != nil {
+		return err
+	}
+	for i := range versions {
+		if err := writeConfigVersion(filename, &versions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneConfigVersions 只保留最近 configHistoryLimit 个历史版本
+func pruneConfigVersions(filename string) error {
+	files, err := configVersionFiles(filename)
+	if err != nil {
+		return err
+	}
+	for _, path := range files[min(len(files), configHistoryLimit):] {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("删除旧的配置历史失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// previousConfigVersion 返回与 current 内容不同的最近一个历史版本，没有时返回 nil
+func previousConfigVersion(versions []ConfigVersion, current *Config) *ConfigVersion {
+	for i := range versions {
+		if len(configChanges(current, versions[i].Config)) > 0 {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+// restoreConfigVersion 通过 appConfig 将历史版本保存为当前配置并通知各界面，恢复操作本身也会记录为一个新的历史版本
+func restoreConfigVersion(v *ConfigVersion) (*Config, error) {
+	restored := *v.Config
+	note := fmt.Sprintf("恢复 %s 的版本", v.SavedAt.Format("2006.01.02 15:04:05"))
+	if err := appConfig.Replace(configSourceRestore, &restored, note); err != nil {
+		return nil, err
+	}
+	SysLogToFile(fmt.Sprintf("配置已%s", note))
+	return &restored, nil
+}
+
+// showConfigHistoryDialog 显示配置历史版本：查看每个版本的保存者和变更，恢复选中版本或上一个版本
+// config 为界面共用的配置，恢复后由 appConfig 的变更通知同步
+func showConfigHistoryDialog(win fyne.Window, config *Config) {
+	versions, err := listConfigVersions("config.json")
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取配置历史失败: %v", err), win)
+		return
+	}
+
+	detail := widget.NewLabel("选择一个版本查看变更明细")
+	detail.Wrapping = fyne.TextWrapWord
+
+	selected := -1
+	list := widget.NewList(
+		func() int { return len(versions) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			v := &versions[id]
+			o.(*widget.Label).SetText(fmt.Sprintf("%s  %s  %s", v.SavedAt.Format("2006.01.02 15:04:05"), v.SavedBy, v.summary()))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		detail.SetText(versions[id].details())
+	}
+
+	var d dialog.Dialog
+	restore := func(v *ConfigVersion) {
+		dialog.ShowConfirm("确认恢复", fmt.Sprintf("确定要将配置恢复到 %s 的版本吗？", v.SavedAt.Format("2006.01.02 15:04:05")), func(confirm bool) {
+			if !confirm {
+				return
+			}
+			restored, err := restoreConfigVersion(v)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("恢复配置失败: %v", err), win)
+				return
+			}
+			d.Hide()
+			if err := restored.Validate(); err != nil {
+				dialog.ShowError(fmt.Errorf("配置已恢复，但校验发现以下问题:\n%v", err), win)
+				return
+			}
+			dialog.ShowInformation("恢复成功", "配置已恢复，各配置界面已更新，任务在下一个周期使用恢复后的配置。", win)
+		}, win)
+	}
+
+	restoreSelected := widget.NewButton("恢复选中版本", func() {
+		if selected < 0 || selected >= len(versions) {
+			dialog.ShowInformation("提示", "请先选择一个版本", win)
+			return
+		}
+		restore(&versions[selected])
+	})
+	restorePrevious := widget.NewButton("恢复上一个版本", func() {
+		// 与当前配置比较，配置文件被外部修改过时 Current 会先重新载入
+		current, err := appConfig.File()
+		if err != nil {
+			current = config
+		}
+		v := previousConfigVersion(versions, current)
+		if v == nil {
+			dialog.ShowInformation("提示", "没有与当前配置不同的历史版本", win)
+			return
+		}
+		restore(v)
+	})
+
+	content := container.NewBorder(
+		widget.NewLabel(fmt.Sprintf("最近 %d 个配置版本（最多保留 %d 个）", len(versions), configHistoryLimit)),
+		container.NewVBox(detail, container.NewHBox(restorePrevious, restoreSelected)),
+		nil, nil,
+		container.NewGridWrap(fyne.NewSize(760, 300), list),
+	)
+	d = dialog.NewCustom("配置历史", "关闭", content, win)
+	d.Show()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-uposs/utils"
+)
+
+// configSchemaVersion 当前程序使用的配置文件版本，修改 Config 字段的类型或名称时加一并在 configMigrations 中追加迁移
+const configSchemaVersion = 2
+
+// configMigration 将配置文件从 from 版本升级到 from+1 版本
+// 迁移作用于解码后的原始 JSON，旧版本的字段类型可能与当前 Config 不一致
+type configMigration struct {
+	from    int
+	desc    string
+	migrate func(raw map[string]interface{}) error
+}
+
+// configMigrations 按版本顺序排列的迁移链，第 i 项把版本 i 升级到 i+1
+var configMigrations = []configMigration{
+	{from: 0, desc: "布尔和数字字段改为 JSON 类型，清理时间字段改名为 clean_start_time / clean_end_time", migrate: migrateConfigV0},
+	{from: 1, desc: "accessKeyID、secretAccessKey、webhook_url 改为加密保存", migrate: migrateConfigV1},
+}
+
+// migrateConfigV0 升级没有 schema_version 的配置文件
+func migrateConfigV0(raw map[string]interface{}) error {
+	renameConfigKey(raw, "cleaStartTime", "clean_start_time")
+	renameConfigKey(raw, "cleanEndTime", "clean_end_time")
+
+	for _, key := range []string{"autostart", "autostart_auto", "lockui"} {
+		if err := convertConfigBool(raw, key); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{"pic_compress", "pic_width", "auto_interval", "sched_times"} {
+		if err := convertConfigInt(raw, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateConfigV1 升级到加密保存密钥的版本
+// 明文字段在升级后写回文件时由 saveConfigFile 加密，版本号阻止旧程序把密文当作密钥使用
+func migrateConfigV1(raw map[string]interface{}) error {
+	for field := range configSecretFields {
+		if v, ok := raw[field]; ok {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("字段 %s 应为字符串", field)
+			}
+		}
+	}
+	return nil
+}
+
+// renameConfigKey 将字段 from 改名为 to，新字段已存在时保留新字段
+func renameConfigKey(raw map[string]interface{}, from, to string) {
+	v, ok := raw[from]
+	if !ok {
+		return
+	}
+	delete(raw, from)
+	if _, exists := raw[to]; !exists {
+		raw[to] = v
+	}
+}
+
+// convertConfigBool 将字符串形式的布尔值（"true"、"false"、""）转换为 JSON 布尔值
+func convertConfigBool(raw map[string]interface{}, key string) error {
+	s, ok := raw[key].(string)
+	if !ok {
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		raw[key] = false
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("字段 %s 的值 %q 不是有效的布尔值", key, s)
+	}
+	raw[key] = b
+	return nil
+}
+
+// convertConfigInt 将字符串形式的整数转换为 JSON 数字，空字符串转换为 0
+func convertConfigInt(raw map[string]interface{}, key string) error {
+	s, ok := raw[key].(string)
+	if !ok {
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		raw[key] = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("字段 %s 的值 %q 不是有效的整数", key, s)
+	}
+	raw[key] = n
+	return nil
+}
+
+// configFileVersion 返回原始配置的 schema_version，没有该字段的旧配置文件为 0
+func configFileVersion(raw map[string]interface{}) (int, error) {
+	v, ok := raw["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("无效的 schema_version: %v", v)
+	}
+	return int(f), nil
+}
+
+// migrateConfigData 将配置文件内容升级到当前版本，返回升级后的 JSON 和原版本
+// 文件已是当前版本时原样返回，版本高于程序支持的版本时返回错误
+func migrateConfigData(data []byte) ([]byte, int, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, err
+	}
+
+	version, err := configFileVersion(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > configSchemaVersion {
+		return nil, version, fmt.Errorf("配置文件版本 %d 高于程序支持的版本 %d，请升级程序", version, configSchemaVersion)
+	}
+	if version == configSchemaVersion {
+		return data, version, nil
+	}
+
+	for _, m := range configMigrations[version:] {
+		if err := m.migrate(raw); err != nil {
+			return nil, version, fmt.Errorf("配置文件从版本 %d 升级失败: %v", m.from, err)
+		}
+	}
+	raw["schema_version"] = configSchemaVersion
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, version, err
+	}
+	return migrated, version, nil
+}
+
+// backupConfigFile 升级前备份配置文件，备份名包含原版本和时间，例如 config.json.v0.20250101_080000.bak
+func backupConfigFile(path string, data []byte, version int) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%d.%s.bak", path, version, time.Now().Format("20060102_150405"))
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", fmt.Errorf("备份配置文件失败: %v", err)
+	}
+	return backupPath, nil
+}
+
+// upgradeConfigFile 升级旧版本的配置文件：先备份原文件，再按迁移链升级并写回
+// 返回写回文件的当前版本配置内容（密钥字段已加密）
+func upgradeConfigFile(filename string, data []byte) ([]byte, error) {
+	migrated, version, err := migrateConfigData(data)
+	if err != nil {
+		return nil, err
+	}
+	if version == configSchemaVersion {
+		return data, nil
+	}
+
+	configFilePath := filepath.Join(utils.DataPath, filename)
+	backupPath, err := backupConfigFile(configFilePath, data, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解码后再保存，使升级后的文件字段顺序与 Config 一致
+	config := &Config{}
+	if err := json.Unmarshal(migrated, config); err != nil {
+		return nil, fmt.Errorf("解析升级后的配置失败: %v", err)
+	}
+	if err := saveConfigFile(filename, config, fmt.Sprintf("配置文件从版本 %d 升级到 %d", version, configSchemaVersion)); err != nil {
+		return nil, fmt.Errorf("写入升级后的配置失败: %v", err)
+	}
+
+	for _, m := range configMigrations[version:] {
+		SysLogToFile(fmt.Sprintf("配置文件已从版本 %d 升级到 %d: %s", m.from, m.from+1, m.desc))
+	}
+	SysLogToFile(fmt.Sprintf("原配置文件已备份到 %s", backupPath))
+	if version < 2 {
+		SysLogToFile(fmt.Sprintf("备份文件 %s 中的密钥为明文，确认升级无误后请删除", backupPath))
+		if err := encryptConfigHistory(filename); err != nil {
+			SysLogToFile(fmt.Sprintf("加密配置历史失败: %v", err))
+		}
+	}
+
+	return os.ReadFile(configFilePath)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-uposs/utils"
+)
+
+func TestMigrateConfigData(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		data    string
+		version int  // 原版本
+		wantErr bool // 应返回错误
+		check   func(t *testing.T, config *Config, raw map[string]interface{})
+	}{
+		{
+			name:    "v0 字符串布尔值和整数",
+			data:    `{"autostart":"true","autostart_auto":" false ","lockui":"","pic_compress":"80","pic_width":" 1000 ","auto_interval":"","sched_times":"3"}`,
+			version: 0,
+			check: func(t *testing.T, config *Config, raw map[string]interface{}) {
+				if !config.AutoStart || config.AutoStartAutoTask || config.LockUI {
+					t.Errorf("autostart = %v、autostart_auto = %v、lockui = %v，应为 true、false、false", config.AutoStart, config.AutoStartAutoTask, config.LockUI)
+				}
+				if config.PicCompress != 80 || config.PicWidth != 1000 || config.AutoInterval != 0 || config.SchedTimes != 3 {
+					t.Errorf("pic_compress = %d、pic_width = %d、auto_interval = %d、sched_times = %d", config.PicCompress, config.PicWidth, config.AutoInterval, config.SchedTimes)
+				}
+			},
+		},
+		{
+			name:    "v0 已是 JSON 类型的字段保持不变",
+			data:    `{"autostart":true,"pic_compress":75}`,
+			version: 0,
+			check: func(t *testing.T, config *Config, raw map[string]interface{}) {
+				if !config.AutoStart || config.PicCompress != 75 {
+					t.Errorf("autostart = %v、pic_compress = %d", config.AutoStart, config.PicCompress)
+				}
+			},
+		},
+		{
+			name:    "v0 清理时间字段改名",
+			data:    `{"cleaStartTime":"2025.01.01","cleanEndTime":"2025.01.31"}`,
+			version: 0,
+			check: func(t *testing.T, config *Config, raw map[string]interface{}) {
+				if config.CleanStartTime != "2025.01.01" || config.CleanEndTime != "2025.01.31" {
+					t.Errorf("clean_start_time = %q、clean_end_time = %q", config.CleanStartTime, config.CleanEndTime)
+				}
+				if _, ok := raw["cleaStartTime"]; ok {
+					t.Error("升级后不应保留 cleaStartTime")
+				}
+			},
+		},
+		{
+			name:    "v0 新旧字段同时存在时保留新字段",
+			data:    `{"cleaStartTime":"2024.01.01","clean_start_time":"2025.01.01"}`,
+			version: 0,
+			check: func(t *testing.T, config *Config, raw map[string]interface{}) {
+				if config.CleanStartTime != "2025.01.01" {
+					t.Errorf("clean_start_time = %q，应为 2025.01.01", config.CleanStartTime)
+				}
+			},
+		},
+		{name: "v0 无效的布尔值", data: `{"lockui":"yes"}`, version: 0, wantErr: true},
+		{name: "v0 无效的整数", data: `{"pic_width":"1000px"}`, version: 0, wantErr: true},
+		{
+			name:    "v1 明文密钥",
+			data:    `{"schema_version":1,"accessKeyID":"ak","autostart":true}`,
+			version: 1,
+			check: func(t *testing.T, config *Config, raw map[string]interface{}) {
+				if config.AccessKeyID != "ak" || !config.AutoStart {
+					t.Errorf("accessKeyID = %q、autostart = %v", config.AccessKeyID, config.AutoStart)
+				}
+			},
+		},
+		{name: "v1 密钥字段不是字符串", data: `{"schema_version":1,"secretAccessKey":123}`, version: 1, wantErr: true},
+		{name: "高于程序支持的版本", data: `{"schema_version":99}`, version: 99, wantErr: true},
+		{name: "无效的版本号", data: `{"schema_version":"2"}`, version: 0, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			migrated, version, err := migrateConfigData([]byte(tc.data))
+			if version != tc.version {
+				t.Errorf("原版本 = %d，应为 %d", version, tc.version)
+			}
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("应返回错误，升级结果 %s", migrated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			raw := map[string]interface{}{}
+			if err := json.Unmarshal(migrated, &raw); err != nil {
+				t.Fatal(err)
+			}
+			config := &Config{}
+			if err := json.Unmarshal(migrated, config); err != nil {
+				t.Fatalf("升级后的配置无法解析为 Config: %v", err)
+			}
+			if config.SchemaVersion != configSchemaVersion {
+				t.Errorf("schema_version = %d，应为 %d", config.SchemaVersion, configSchemaVersion)
+			}
+			tc.check(t, config, raw)
+		})
+	}
+
+	// 当前版本原样返回
+	current := []byte(`{"schema_version":2,"pic_compress":80}`)
+	if migrated, version, err := migrateConfigData(current); err != nil || version != configSchemaVersion || string(migrated) != string(current) {
+		t.Errorf("当前版本 = %s, %d, %v，应原样返回", migrated, version, err)
+	}
+}
+
+func TestUpgradeConfigFile(t *testing.T) {
+	home := useTestHome(t, nil)
+	config := testConfig(t, home)
+
+	// 以 v0 格式写入：没有 schema_version，数字为字符串，密钥为明文，清理时间使用旧字段名
+	raw := map[string]interface{}{}
+	data, _ := json.Marshal(config)
+	json.Unmarshal(data, &raw)
+	delete(raw, "schema_version")
+	delete(raw, "clean_start_time")
+	raw["cleaStartTime"] = config.CleanStartTime
+	raw["pic_compress"] = "80"
+	raw["autostart"] = "true"
+	v0, _ := json.Marshal(raw)
+	configFilePath := filepath.Join(utils.DataPath, "config.json")
+	if err := os.WriteFile(configFilePath, v0, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig("config.json")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if loaded.PicCompress != 80 || !loaded.AutoStart || loaded.CleanStartTime != config.CleanStartTime || loaded.SecretAccessKey != config.SecretAccessKey {
+		t.Errorf("升级后的配置 = %+v", loaded)
+	}
+
+	// 原文件备份为 config.json.v0.<时间>.bak，内容不变
+	backups, _ := filepath.Glob(configFilePath + ".v0.*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("备份文件 = %v，应有 1 个", backups)
+	}
+	if backup, _ := os.ReadFile(backups[0]); string(backup) != string(v0) {
+		t.Error("备份文件内容应与升级前的配置文件一致")
+	}
+
+	// 写回的文件为当前版本，密钥已加密
+	saved, _ := os.ReadFile(configFilePath)
+	if strings.Contains(string(saved), config.SecretAccessKey) {
+		t.Error("升级后的配置文件不应包含明文密钥")
+	}
+	if _, version, err := migrateConfigData(saved); err != nil || version != configSchemaVersion {
+		t.Errorf("升级后的配置文件版本 = %d, %v", version, err)
+	}
+
+	// 再次载入不再备份
+	if _, err := LoadConfig("config.json"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := filepath.Glob(configFilePath + ".v*.bak"); len(again) != 1 {
+		t.Errorf("当前版本的配置文件不应再备份，备份文件 = %v", again)
+	}
+}
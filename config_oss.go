@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"go-uposs/utils"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -20,89 +22,369 @@ var ossLogText *widget.Entry
 
 // 创建一个标签和输入框并排的组件
 func labeledEntry(labelText string, entry *widget.Entry) fyne.CanvasObject {
+	return labeledWidget(labelText, entry)
+}
+
+// labeledWidget 创建一个标签和任意输入控件并排的组件，宽度与 labeledEntry 一致
+func labeledWidget(labelText string, w fyne.CanvasObject) fyne.CanvasObject {
 	label := widget.NewLabelWithStyle(labelText, fyne.TextAlignLeading, fyne.TextStyle{})
 	labelContainer := container.NewGridWrap(fyne.NewSize(labelWidth, utils.LEBHeight), label)
-	entryContainer := container.NewGridWrap(fyne.NewSize(entryWidth, utils.LEBHeight), entry)
+	entryContainer := container.NewGridWrap(fyne.NewSize(entryWidth, utils.LEBHeight), w)
 	return container.NewHBox(labelContainer, entryContainer)
 }
 
+// ossForm OSS 配置界面的输入控件
+type ossForm struct {
+	machineCode     *widget.Entry
+	bucketName      *widget.Entry
+	storageType     *widget.Select
+	endpoint        *widget.Entry
+	publicUrl       *widget.Entry
+	accessKeyID     *widget.Entry
+	secretAccessKey *widget.Entry
+	s3Region        *widget.Entry
+	s3BucketLookup  *widget.Select
+	s3SessionToken  *widget.Entry
+	s3CAFile        *widget.Entry
+	s3SkipVerify    *widget.Check
+	s3Proxy         *widget.Entry
+	s3ConnTimeout   *widget.Entry
+	s3ReadTimeout   *widget.Entry
+	s3MaxIdleConns  *widget.Entry
+	s3MaxConns      *widget.Entry
+	localStoreDir   *widget.Entry
+	webdavURL       *widget.Entry
+	webdavUser      *widget.Entry
+	webdavPassword  *widget.Entry
+	sftpHost        *widget.Entry
+	sftpUser        *widget.Entry
+	sftpPassword    *widget.Entry
+	sftpKeyFile     *widget.Entry
+	sftpHostKey     *widget.Entry
+	sftpDir         *widget.Entry
+	publicBaseURL   *widget.Entry
+	uploadWorkers   *widget.Entry
+	useSSL          *widget.Check
+}
+
+// applyStorage 把界面中的存储类型、连接参数写入 c，用于保存和测试连接；数值格式不正确时返回错误
+func (f *ossForm) applyStorage(c *Config) error {
+	for _, n := range []struct {
+		label string
+		entry *widget.Entry
+		value *int
+	}{
+		{"S3 连接超时", f.s3ConnTimeout, &c.S3ConnectTimeout},
+		{"S3 读取超时", f.s3ReadTimeout, &c.S3ReadTimeout},
+		{"S3 空闲连接数", f.s3MaxIdleConns, &c.S3MaxIdleConns},
+		{"S3 最大连接数", f.s3MaxConns, &c.S3MaxConnsPerHost},
+	} {
+		text := strings.TrimSpace(n.entry.Text)
+		if text == "" {
+			*n.value = 0
+			continue
+		}
+		v, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%s应为整数，留空使用默认值", n.label)
+		}
+		*n.value = v
+	}
+
+	c.StorageType = storageTypeName(f.storageType.Selected)
+	c.Endpoint = f.endpoint.Text
+	c.AccessKeyID = f.accessKeyID.Text
+	c.SecretAccessKey = f.secretAccessKey.Text
+	c.UseSSL = f.useSSL.Checked
+	c.S3Region = f.s3Region.Text
+	c.S3BucketLookup = f.s3BucketLookup.Selected
+	if c.S3BucketLookup == s3LookupAuto {
+		c.S3BucketLookup = ""
+	}
+	c.S3SessionToken = f.s3SessionToken.Text
+	c.S3CAFile = f.s3CAFile.Text
+	c.S3InsecureSkipVerify = f.s3SkipVerify.Checked
+	c.S3Proxy = f.s3Proxy.Text
+	c.LocalStoreDir = f.localStoreDir.Text
+	c.WebDAVURL = f.webdavURL.Text
+	c.WebDAVUser = f.webdavUser.Text
+	c.WebDAVPassword = f.webdavPassword.Text
+	c.SFTPHost = f.sftpHost.Text
+	c.SFTPUser = f.sftpUser.Text
+	c.SFTPPassword = f.sftpPassword.Text
+	c.SFTPKeyFile = f.sftpKeyFile.Text
+	c.SFTPHostKey = f.sftpHostKey.Text
+	c.SFTPDir = f.sftpDir.Text
+	c.PublicBaseURL = f.publicBaseURL.Text
+	return nil
+}
+
+// s3LookupText 返回存储桶地址风格在界面中的选项，未配置时为 auto
+func s3LookupText(style string) string {
+	if style == "" {
+		return s3LookupAuto
+	}
+	return style
+}
+
+// optionalInt 返回数值输入框的文本，0（使用默认值）显示为空
+func optionalInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// setConfig 用 config 填充全部输入控件
+func (f *ossForm) setConfig(config *Config) {
+	f.machineCode.SetText(config.MachineCode)
+	f.bucketName.SetText(config.BucketName)
+	f.storageType.SetSelected(storageTypeLabel(config.storageType()))
+	f.endpoint.SetText(config.Endpoint)
+	f.publicUrl.SetText(config.PublicUrl)
+	f.accessKeyID.SetText(config.AccessKeyID)
+	f.secretAccessKey.SetText(config.SecretAccessKey)
+	f.s3Region.SetText(config.S3Region)
+	f.s3BucketLookup.SetSelected(s3LookupText(config.S3BucketLookup))
+	f.s3SessionToken.SetText(config.S3SessionToken)
+	f.s3CAFile.SetText(config.S3CAFile)
+	f.s3SkipVerify.SetChecked(config.S3InsecureSkipVerify)
+	f.s3Proxy.SetText(config.S3Proxy)
+	f.s3ConnTimeout.SetText(optionalInt(config.S3ConnectTimeout))
+	f.s3ReadTimeout.SetText(optionalInt(config.S3ReadTimeout))
+	f.s3MaxIdleConns.SetText(optionalInt(config.S3MaxIdleConns))
+	f.s3MaxConns.SetText(optionalInt(config.S3MaxConnsPerHost))
+	f.localStoreDir.SetText(config.LocalStoreDir)
+	f.webdavURL.SetText(config.WebDAVURL)
+	f.webdavUser.SetText(config.WebDAVUser)
+	f.webdavPassword.SetText(config.WebDAVPassword)
+	f.sftpHost.SetText(config.SFTPHost)
+	f.sftpUser.SetText(config.SFTPUser)
+	f.sftpPassword.SetText(config.SFTPPassword)
+	f.sftpKeyFile.SetText(config.SFTPKeyFile)
+	f.sftpHostKey.SetText(config.SFTPHostKey)
+	f.sftpDir.SetText(config.SFTPDir)
+	f.publicBaseURL.SetText(config.PublicBaseURL)
+	f.uploadWorkers.SetText(strconv.Itoa(uploadWorkersOrDefault(config)))
+	f.useSSL.SetChecked(config.UseSSL)
+}
+
+// syncConfig 配置被其他程序修改或恢复历史版本时同步变化的字段，没有变化的字段保留尚未保存的输入
+func (f *ossForm) syncConfig(old, c *Config) {
+	syncEntry(f.machineCode, old.MachineCode, c.MachineCode)
+	syncEntry(f.bucketName, old.BucketName, c.BucketName)
+	if old.storageType() != c.storageType() {
+		f.storageType.SetSelected(storageTypeLabel(c.storageType()))
+	}
+	syncEntry(f.endpoint, old.Endpoint, c.Endpoint)
+	syncEntry(f.publicUrl, old.PublicUrl, c.PublicUrl)
+	syncEntry(f.accessKeyID, old.AccessKeyID, c.AccessKeyID)
+	syncEntry(f.secretAccessKey, old.SecretAccessKey, c.SecretAccessKey)
+	syncEntry(f.s3Region, old.S3Region, c.S3Region)
+	if old.S3BucketLookup != c.S3BucketLookup {
+		f.s3BucketLookup.SetSelected(s3LookupText(c.S3BucketLookup))
+	}
+	syncEntry(f.s3SessionToken, old.S3SessionToken, c.S3SessionToken)
+	syncEntry(f.s3CAFile, old.S3CAFile, c.S3CAFile)
+	if old.S3InsecureSkipVerify != c.S3InsecureSkipVerify {
+		f.s3SkipVerify.SetChecked(c.S3InsecureSkipVerify)
+	}
+	syncEntry(f.s3Proxy, old.S3Proxy, c.S3Proxy)
+	syncEntry(f.s3ConnTimeout, optionalInt(old.S3ConnectTimeout), optionalInt(c.S3ConnectTimeout))
+	syncEntry(f.s3ReadTimeout, optionalInt(old.S3ReadTimeout), optionalInt(c.S3ReadTimeout))
+	syncEntry(f.s3MaxIdleConns, optionalInt(old.S3MaxIdleConns), optionalInt(c.S3MaxIdleConns))
+	syncEntry(f.s3MaxConns, optionalInt(old.S3MaxConnsPerHost), optionalInt(c.S3MaxConnsPerHost))
+	syncEntry(f.localStoreDir, old.LocalStoreDir, c.LocalStoreDir)
+	syncEntry(f.webdavURL, old.WebDAVURL, c.WebDAVURL)
+	syncEntry(f.webdavUser, old.WebDAVUser, c.WebDAVUser)
+	syncEntry(f.webdavPassword, old.WebDAVPassword, c.WebDAVPassword)
+	syncEntry(f.sftpHost, old.SFTPHost, c.SFTPHost)
+	syncEntry(f.sftpUser, old.SFTPUser, c.SFTPUser)
+	syncEntry(f.sftpPassword, old.SFTPPassword, c.SFTPPassword)
+	syncEntry(f.sftpKeyFile, old.SFTPKeyFile, c.SFTPKeyFile)
+	syncEntry(f.sftpHostKey, old.SFTPHostKey, c.SFTPHostKey)
+	syncEntry(f.sftpDir, old.SFTPDir, c.SFTPDir)
+	syncEntry(f.publicBaseURL, old.PublicBaseURL, c.PublicBaseURL)
+	syncEntry(f.uploadWorkers, strconv.Itoa(uploadWorkersOrDefault(old)), strconv.Itoa(uploadWorkersOrDefault(c)))
+	if old.UseSSL != c.UseSSL {
+		f.useSSL.SetChecked(c.UseSSL)
+	}
+}
+
 // saveConfig 保存 OSS 配置
-func saveConfig(machineCodeEntry, bucketNameEntry, endpointEntry, publicUrlEntry, accessKeyIDEntry, secretAccessKeyEntry *widget.Entry, useSSLCheck *widget.Check) {
-	config, err := LoadConfig("config.json")
-	if err != nil {
-		updateLog(ossLogText, "[OSS配置]", fmt.Sprintf("加载配置失败: %s", err.Error()))
+func saveConfig(f *ossForm) {
+	// 验证上传并发数
+	uploadWorkers, err := strconv.Atoi(f.uploadWorkers.Text)
+	if err != nil || uploadWorkers < 1 {
+		updateLog(ossLogText, "[OSS配置]", "请输入有效的上传并发数（1-64）")
 		return
 	}
 
-	config.MachineCode = machineCodeEntry.Text
-	config.BucketName = bucketNameEntry.Text
-	config.Endpoint = endpointEntry.Text
-	config.PublicUrl = publicUrlEntry.Text
-	config.AccessKeyID = accessKeyIDEntry.Text
-	config.SecretAccessKey = secretAccessKeyEntry.Text
-	config.UseSSL = useSSLCheck.Checked
+	// 先检查数值格式，保存时再应用到最新的配置上
+	if err := f.applyStorage(&Config{}); err != nil {
+		updateLog(ossLogText, "[OSS配置]", err.Error())
+		return
+	}
 
-	if err := SaveConfig("config.json", config); err != nil {
-		updateLog(ossLogText, "[OSS配置]", fmt.Sprintf("保存配置失败: %s", err.Error()))
+	// 界面共用的配置由 appConfig 的变更通知同步
+	err = updateConfig(nil, func(c *Config) {
+		c.MachineCode = f.machineCode.Text
+		c.BucketName = f.bucketName.Text
+		c.PublicUrl = f.publicUrl.Text
+		_ = f.applyStorage(c)
+		c.UploadWorkers = uploadWorkers
+	}, ossConfigFields...)
+	if err != nil {
+		updateLog(ossLogText, "[OSS配置]", configSaveError(err))
 	} else {
 		updateLog(ossLogText, "[OSS配置]", "配置保存成功！")
 	}
 }
 
 // refreshConfig 刷新 OSS 配置
-func refreshConfig(machineCodeEntry, bucketNameEntry, endpointEntry, publicUrlEntry, accessKeyIDEntry, secretAccessKeyEntry *widget.Entry, useSSLCheck *widget.Check) {
-	config, err := LoadConfig("config.json")
+func refreshConfig(f *ossForm) {
+	config, err := appConfig.Current()
 	if err != nil {
 		updateLog(ossLogText, "[OSS配置]", fmt.Sprintf("加载配置失败: %s", err.Error()))
 		return
 	}
 
-	machineCodeEntry.SetText(config.MachineCode)
-	bucketNameEntry.SetText(config.BucketName)
-	endpointEntry.SetText(config.Endpoint)
-	publicUrlEntry.SetText(config.PublicUrl)
-	accessKeyIDEntry.SetText(config.AccessKeyID)
-	secretAccessKeyEntry.SetText(config.SecretAccessKey)
-	useSSLCheck.SetChecked(config.UseSSL)
+	f.setConfig(config)
 
 	updateLog(ossLogText, "[OSS配置]", "配置已刷新！")
 }
 
+// uploadWorkersOrDefault 返回配置的上传并发数，未配置时返回默认值
+func uploadWorkersOrDefault(config *Config) int {
+	if config.UploadWorkers > 0 {
+		return config.UploadWorkers
+	}
+	return defaultUploadWorkers
+}
+
 // CreateUI 创建 UI 界面
 func CreateUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	ossLogText = widget.NewMultiLineEntry()
 	ossLogText.SetMinRowsVisible(11)
 	ossLogText.SetText("")
 
-	machineCodeEntry := widget.NewEntry()
-	bucketNameEntry := widget.NewEntry()
-	endpointEntry := widget.NewEntry()
-	publicUrlEntry := widget.NewEntry()
-	accessKeyIDEntry := widget.NewPasswordEntry()
-	secretAccessKeyEntry := widget.NewPasswordEntry()
-	useSSLCheck := widget.NewCheck("使用 SSL", nil)
-
-	machineCodeEntry.SetText(config.MachineCode)
-	bucketNameEntry.SetText(config.BucketName)
-	endpointEntry.SetText(config.Endpoint)
-	publicUrlEntry.SetText(config.PublicUrl)
-	accessKeyIDEntry.SetText(config.AccessKeyID)
-	secretAccessKeyEntry.SetText(config.SecretAccessKey)
-	useSSLCheck.SetChecked(config.UseSSL)
+	storageLabels := make([]string, len(storageTypes))
+	for i, t := range storageTypes {
+		storageLabels[i] = t.label
+	}
+	f := &ossForm{
+		machineCode:     widget.NewEntry(),
+		bucketName:      widget.NewEntry(),
+		storageType:     widget.NewSelect(storageLabels, nil),
+		endpoint:        widget.NewEntry(),
+		publicUrl:       widget.NewEntry(),
+		accessKeyID:     widget.NewPasswordEntry(),
+		secretAccessKey: widget.NewPasswordEntry(),
+		s3Region:        widget.NewEntry(),
+		s3BucketLookup:  widget.NewSelect(s3BucketLookups, nil),
+		s3SessionToken:  widget.NewPasswordEntry(),
+		s3CAFile:        widget.NewEntry(),
+		s3SkipVerify:    widget.NewCheck("不校验服务器证书（仅用于内网测试）", nil),
+		s3Proxy:         widget.NewEntry(),
+		s3ConnTimeout:   widget.NewEntry(),
+		s3ReadTimeout:   widget.NewEntry(),
+		s3MaxIdleConns:  widget.NewEntry(),
+		s3MaxConns:      widget.NewEntry(),
+		localStoreDir:   widget.NewEntry(),
+		webdavURL:       widget.NewEntry(),
+		webdavUser:      widget.NewEntry(),
+		webdavPassword:  widget.NewPasswordEntry(),
+		sftpHost:        widget.NewEntry(),
+		sftpUser:        widget.NewEntry(),
+		sftpPassword:    widget.NewPasswordEntry(),
+		sftpKeyFile:     widget.NewEntry(),
+		sftpHostKey:     widget.NewEntry(),
+		sftpDir:         widget.NewEntry(),
+		publicBaseURL:   widget.NewEntry(),
+		uploadWorkers:   widget.NewEntry(),
+		useSSL:          widget.NewCheck("使用 SSL", nil),
+	}
+	f.s3Region.SetPlaceHolder("可选，例如 us-east-1，为空时向服务器查询")
+	f.s3SessionToken.SetPlaceHolder("可选，临时凭证（STS）的会话令牌")
+	f.s3CAFile.SetPlaceHolder("可选，自签名证书的 CA 文件（PEM）")
+	f.s3Proxy.SetPlaceHolder("可选，http://、https:// 或 socks5://，为空时使用 HTTPS_PROXY")
+	f.s3ConnTimeout.SetPlaceHolder(fmt.Sprintf("连接和 TLS 握手超时，单位秒，默认 %d", defaultS3ConnectTimeout))
+	f.s3ReadTimeout.SetPlaceHolder(fmt.Sprintf("等待响应超时，单位秒，默认 %d", defaultS3ReadTimeout))
+	f.s3MaxIdleConns.SetPlaceHolder(fmt.Sprintf("每个主机保留的空闲连接数，默认 %d", defaultS3MaxIdleConns))
+	f.s3MaxConns.SetPlaceHolder("每个主机的最大连接数，默认不限制")
+	f.localStoreDir.SetPlaceHolder("本地存储根目录，存储桶为其中的子文件夹")
+	f.webdavURL.SetPlaceHolder("共享的根地址，例如 https://nas.local/webdav/uposs")
+	f.sftpHost.SetPlaceHolder("host 或 host:port，默认端口 22")
+	f.sftpKeyFile.SetPlaceHolder("私钥文件，与密码至少填写一个")
+	f.sftpHostKey.SetPlaceHolder("服务器公钥指纹 SHA256:...，测试连接时显示")
+	f.sftpDir.SetPlaceHolder("服务器上的根目录，为空使用登录后的目录")
+	f.publicBaseURL.SetPlaceHolder("可选，文件地址为 <前缀>/<对象路径>，为空时为 Public URL/Bucket/对象路径")
+
+	// 每种存储类型只显示需要填写的字段
+	storageGroups := map[string]fyne.CanvasObject{
+		storageMinio: container.NewVBox(
+			labeledEntry("Endpoint:", f.endpoint),
+			labeledEntry("Access Key ID:", f.accessKeyID),
+			labeledEntry("Secret Access Key:", f.secretAccessKey),
+			// 大多数情况不需要修改的连接选项默认折叠
+			widget.NewAccordion(widget.NewAccordionItem("S3 连接选项", container.NewVBox(
+				labeledEntry("Region:", f.s3Region),
+				labeledWidget("Bucket Lookup:", f.s3BucketLookup),
+				labeledEntry("Session Token:", f.s3SessionToken),
+				labeledEntry("CA File:", f.s3CAFile),
+				labeledWidget("", f.s3SkipVerify),
+				labeledEntry("Proxy:", f.s3Proxy),
+				labeledEntry("Connect Timeout:", f.s3ConnTimeout),
+				labeledEntry("Read Timeout:", f.s3ReadTimeout),
+				labeledEntry("Max Idle Conns:", f.s3MaxIdleConns),
+				labeledEntry("Max Conns/Host:", f.s3MaxConns),
+			))),
+		),
+		storageLocal: container.NewVBox(
+			labeledEntry("Local Store Dir:", f.localStoreDir),
+		),
+		storageWebDAV: container.NewVBox(
+			labeledEntry("WebDAV URL:", f.webdavURL),
+			labeledEntry("WebDAV User:", f.webdavUser),
+			labeledEntry("WebDAV Password:", f.webdavPassword),
+		),
+		storageSFTP: container.NewVBox(
+			labeledEntry("SFTP Host:", f.sftpHost),
+			labeledEntry("SFTP User:", f.sftpUser),
+			labeledEntry("SFTP Password:", f.sftpPassword),
+			labeledEntry("SFTP Key File:", f.sftpKeyFile),
+			labeledEntry("SFTP Host Key:", f.sftpHostKey),
+			labeledEntry("SFTP Dir:", f.sftpDir),
+		),
+	}
+	storageFields := container.NewVBox()
+	f.storageType.OnChanged = func(label string) {
+		name := storageTypeName(label)
+		storageFields.Objects = []fyne.CanvasObject{storageGroups[name]}
+		storageFields.Refresh()
+		if name == storageMinio {
+			f.useSSL.Enable()
+		} else {
+			f.useSSL.Disable()
+		}
+	}
+	f.setConfig(config)
+
+	// 配置被其他程序修改或恢复历史版本时同步变化的字段
+	onConfigChange(f.syncConfig)
 
 	saveButton := widget.NewButton("保存配置", func() {
 		dialog.ShowConfirm("确认保存", "你确定要保存配置吗？", func(confirm bool) {
 			if confirm {
-				saveConfig(machineCodeEntry, bucketNameEntry, endpointEntry, publicUrlEntry, accessKeyIDEntry, secretAccessKeyEntry, useSSLCheck)
+				saveConfig(f)
 			}
 		}, myWindow)
 	})
 
-	// 创建测试连接按钮
+	// 创建测试连接按钮，使用界面中尚未保存的存储类型和连接参数
 	testButton := widget.NewButton("测试连接", func() {
 		updateLog(ossLogText, "[OSS配置]", "测试连接中...") // 使用统一的日志更新函数
-		useSSL := useSSLCheck.Checked
-		result, err := TestMinioConnection(useSSL)
+		result, err := TestStorageConnection(f.applyStorage)
 		if err != nil {
 			updateLog(ossLogText, "[OSS配置]", fmt.Sprintf("错误: %s", err.Error()))
 		} else {
@@ -111,29 +393,30 @@ func CreateUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	})
 
 	refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
-		refreshConfig(machineCodeEntry, bucketNameEntry, endpointEntry, publicUrlEntry, accessKeyIDEntry, secretAccessKeyEntry, useSSLCheck)
+		refreshConfig(f)
 	})
 
 	buttonContainer := container.NewVBox(
-		container.NewGridWrap(fyne.NewSize(140, utils.LEBHeight), useSSLCheck),
+		container.NewGridWrap(fyne.NewSize(140, utils.LEBHeight), f.useSSL),
 		container.NewGridWrap(fyne.NewSize(140, utils.LEBHeight), refreshButton),
 		container.NewGridWrap(fyne.NewSize(140, utils.LEBHeight), saveButton),
 		container.NewGridWrap(fyne.NewSize(140, utils.LEBHeight), testButton),
 	)
 
 	configContainer := container.NewVBox(
-		labeledEntry("Machine Code:", machineCodeEntry),
-		labeledEntry("Bucket Name:", bucketNameEntry),
-		labeledEntry("Endpoint:", endpointEntry),
-		labeledEntry("Public URL:", publicUrlEntry),
-		labeledEntry("Access Key ID:", accessKeyIDEntry),
-		labeledEntry("Secret Access Key:", secretAccessKeyEntry),
+		labeledEntry("Machine Code:", f.machineCode),
+		labeledEntry("Bucket Name:", f.bucketName),
+		labeledWidget("Storage Type:", f.storageType),
+		storageFields,
+		labeledEntry("Public URL:", f.publicUrl),
+		labeledEntry("Public Base URL:", f.publicBaseURL),
+		labeledEntry("Upload Workers:", f.uploadWorkers),
 	)
 
 	mainContainer := container.NewBorder(nil, nil, nil, buttonContainer, configContainer)
 
-	SysLogToFile(fmt.Sprintf("[OSS配置] 配置已载入，MachineCode=%s, BucketName=%s, Endpoint=%s, UseSSL=%t",
-		config.MachineCode, config.BucketName, config.Endpoint, config.UseSSL))
+	SysLogToFile(fmt.Sprintf("[OSS配置] 配置已载入，MachineCode=%s, BucketName=%s, StorageType=%s, Target=%s, UseSSL=%t",
+		config.MachineCode, config.BucketName, config.storageType(), storageTarget(config), config.UseSSL))
 
 	return container.NewVBox(
 		mainContainer,
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// configEnvPrefix 覆盖配置字段的环境变量前缀，例如 GOUPOSS_BUCKET_NAME 覆盖 bucket_name
+const configEnvPrefix = "GOUPOSS_"
+
+// 配置字段的值来源，优先级从高到低
+const (
+	configFromFlag    = "命令行"
+	configFromEnv     = "环境变量"
+	configFromFile    = "配置文件"
+	configFromDefault = "默认值"
+)
+
+// configOverride 以环境变量或命令行 --set 覆盖的配置字段
+type configOverride struct {
+	Field  string
+	Value  string
+	Source string // configFromFlag 或 configFromEnv
+	Env    string // 环境变量名，Source 为环境变量时有效
+}
+
+// configOverrides 本进程启动时确定的覆盖，按 环境变量、命令行 的顺序应用，命令行优先
+var configOverrides []configOverride
+
+// configOverrideFields 可以覆盖的字段，按配置文件中的顺序排列；版本号、盐和方案不能覆盖
+var configOverrideFields = func() []string {
+	var fields []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		switch name := jsonFieldName(t.Field(i)); name {
+		case "", "-", "schema_version", "secret_salt", "profiles":
+		default:
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}()
+
+// configEnvName 返回覆盖字段的环境变量名：驼峰拆分为下划线后转大写，例如 accessKeyID → GOUPOSS_ACCESS_KEY_ID
+func configEnvName(field string) string {
+	var b strings.Builder
+	runes := []rune(field)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return configEnvPrefix + b.String()
+}
+
+// setConfigField 把字符串 value 按字段类型写入 config 的字段 field
+func setConfigField(config *Config, field, value string) error {
+	i, ok := configFieldIndex[field]
+	if !ok {
+		return fmt.Errorf("未知的配置字段 %s", field)
+	}
+	v := reflect.ValueOf(config).Elem().Field(i)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s 应为 true 或 false，实际为 %q", field, value)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s 应为整数，实际为 %q", field, value)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s 应为数字，实际为 %q", field, value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("%s 不能覆盖", field)
+	}
+	return nil
+}
+
+// initConfigOverrides 读取 GOUPOSS_* 环境变量和命令行 --set 字段=值，检查字段名和取值，程序启动时调用一次
+func initConfigOverrides(sets []string) error {
+	var overrides []configOverride
+	check := &Config{}
+	for _, field := range configOverrideFields {
+		env := configEnvName(field)
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		if err := setConfigField(check, field, value); err != nil {
+			return fmt.Errorf("环境变量 %s: %v", env, err)
+		}
+		overrides = append(overrides, configOverride{Field: field, Value: value, Source: configFromEnv, Env: env})
+	}
+	for _, set := range sets {
+		field, value, ok := strings.Cut(set, "=")
+		if !ok {
+			return fmt.Errorf("--set %s 应为 字段=值 的格式", set)
+		}
+		if !isOverrideField(field) {
+			return fmt.Errorf("--set %s: 未知或不能覆盖的配置字段 %s", set, field)
+		}
+		if err := setConfigField(check, field, value); err != nil {
+			return fmt.Errorf("--set: %v", err)
+		}
+		overrides = append(overrides, configOverride{Field: field, Value: value, Source: configFromFlag})
+	}
+	configOverrides = overrides
+	return nil
+}
+
+// isOverrideField 判断 field 是否可以覆盖
+func isOverrideField(field string) bool {
+	for _, f := range configOverrideFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// applyConfigOverrides 把本进程的覆盖应用到 config 的顶层字段，方案中设置了的字段不受影响
+func applyConfigOverrides(config *Config) {
+	applyOverrides(config, configOverrides)
+}
+
+// applyOverrides 按顺序把 overrides 应用到 config，取值已在 initConfigOverrides 中检查
+func applyOverrides(config *Config, overrides []configOverride) {
+	for _, o := range overrides {
+		_ = setConfigField(config, o.Field, o.Value)
+	}
+}
+
+// overrideOf 返回 overrides 中字段 field 生效的（最后一个）覆盖，没有覆盖时返回 nil
+func overrideOf(overrides []configOverride, field string) *configOverride {
+	var found *configOverride
+	for i := range overrides {
+		if overrides[i].Field == field {
+			found = &overrides[i]
+		}
+	}
+	return found
+}
+
+// withFileValues 返回写入配置文件的配置：被覆盖且本次没有修改的字段保持文件中的值，
+// 避免环境变量和命令行中的值（例如密钥）被写入配置文件
+func withFileValues(file, old, next *Config) *Config {
+	stored := next.clone()
+	fv, ov, nv, sv := reflect.ValueOf(file).Elem(), reflect.ValueOf(old).Elem(), reflect.ValueOf(next).Elem(), reflect.ValueOf(stored).Elem()
+	for _, o := range configOverrides {
+		i := configFieldIndex[o.Field]
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			sv.Field(i).Set(fv.Field(i))
+		}
+	}
+	return stored
+}
+
+// EffectiveField 一个配置字段的生效值和来源
+type EffectiveField struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`  // 密钥字段不显示明文
+	Source string `json:"source"` // 命令行、环境变量、配置文件、默认值
+	Env    string `json:"env"`    // 覆盖该字段的环境变量名
+}
+
+// effectiveFields 返回 file 应用 overrides 后每个字段的生效值和来源，fileData 为配置文件内容，
+// 文件中没有的字段来源为默认值
+func effectiveFields(file *Config, fileData []byte, overrides []configOverride) []EffectiveField {
+	keys := map[string]json.RawMessage{}
+	_ = json.Unmarshal(fileData, &keys)
+
+	effective := file.clone()
+	applyOverrides(effective, overrides)
+	v := reflect.ValueOf(effective).Elem()
+
+	fields := make([]EffectiveField, 0, len(configOverrideFields))
+	for _, field := range configOverrideFields {
+		f := EffectiveField{Field: field, Value: fmt.Sprint(v.Field(configFieldIndex[field]).Interface()), Env: configEnvName(field)}
+		if configSecretFields[field] && f.Value != "" {
+			f.Value = "******"
+		}
+		switch o := overrideOf(overrides, field); {
+		case o != nil:
+			f.Source = o.Source
+		case keys[field] != nil:
+			f.Source = configFromFile
+		default:
+			f.Source = configFromDefault
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// overridesSummary 返回被覆盖字段的简短描述，没有覆盖时返回空字符串
+func overridesSummary() string {
+	var parts []string
+	for _, field := range configOverrideFields {
+		if o := overrideOf(configOverrides, field); o != nil {
+			parts = append(parts, fmt.Sprintf("%s（%s）", field, o.Source))
+		}
+	}
+	return strings.Join(parts, "、")
+}
+
+// showEffectiveConfigDialog 显示每个配置字段的生效值和来源，密钥不显示明文
+func showEffectiveConfigDialog(win fyne.Window) {
+	fields, err := appConfig.Fields(true)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取配置失败: %v", err), win)
+		return
+	}
+
+	header := []string{"字段", "生效值", "来源", "环境变量"}
+	table := widget.NewTable(
+		func() (int, int) { return len(fields) + 1, len(header) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(header[id.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			f := fields[id.Row-1]
+			label.SetText([]string{f.Field, f.Value, f.Source, f.Env}[id.Col])
+		},
+	)
+	for col, width := range []float32{150, 300, 90, 260} {
+		table.SetColumnWidth(col, width)
+	}
+
+	note := widget.NewLabel("优先级：命令行 --set > 环境变量 GOUPOSS_* > 配置文件 > 默认值。覆盖的值只在本进程中生效，不写入配置文件；方案中设置了的字段不受覆盖影响。")
+	note.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(note, nil, nil, nil, container.NewGridWrap(fyne.NewSize(820, 420), table))
+	dialog.NewCustom("生效配置", "关闭", content, win).Show()
+}
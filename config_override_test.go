@@ -0,0 +1,189 @@
+package main
+
+import (
+	"testing"
+)
+
+// useOverrides 设置环境变量后以 sets 初始化本进程的覆盖，测试结束后清除
+func useOverrides(t *testing.T, env map[string]string, sets ...string) {
+	t.Helper()
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	t.Cleanup(func() { configOverrides = nil })
+	if err := initConfigOverrides(sets); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fieldsByName 按字段名索引 EffectiveField
+func fieldsByName(fields []EffectiveField) map[string]EffectiveField {
+	m := map[string]EffectiveField{}
+	for _, f := range fields {
+		m[f.Field] = f
+	}
+	return m
+}
+
+func TestConfigEnvName(t *testing.T) {
+	for field, want := range map[string]string{
+		"accessKeyID":             "GOUPOSS_ACCESS_KEY_ID",
+		"secretAccessKey":         "GOUPOSS_SECRET_ACCESS_KEY",
+		"useSSL":                  "GOUPOSS_USE_SSL",
+		"bucket_name":             "GOUPOSS_BUCKET_NAME",
+		"api1":                    "GOUPOSS_API1",
+		"api1_response1":          "GOUPOSS_API1_RESPONSE1",
+		"s3_insecure_skip_verify": "GOUPOSS_S3_INSECURE_SKIP_VERIFY",
+		"webdav_url":              "GOUPOSS_WEBDAV_URL",
+	} {
+		if got := configEnvName(field); got != want {
+			t.Errorf("configEnvName(%q) = %s，应为 %s", field, got, want)
+		}
+	}
+
+	// 每个字段的环境变量名不能重复
+	seen := map[string]string{}
+	for _, field := range configOverrideFields {
+		env := configEnvName(field)
+		if other, ok := seen[env]; ok {
+			t.Errorf("%s 和 %s 的环境变量名都是 %s", other, field, env)
+		}
+		seen[env] = field
+	}
+}
+
+func TestSetConfigField(t *testing.T) {
+	for _, tc := range []struct {
+		field, value string
+		wantErr      bool
+		check        func(c *Config) bool
+	}{
+		{"lockui", "true", false, func(c *Config) bool { return c.LockUI }},
+		{"useSSL", "0", false, func(c *Config) bool { return !c.UseSSL }},
+		{"lockui", "yes", true, nil},
+		{"pic_compress", "60", false, func(c *Config) bool { return c.PicCompress == 60 }},
+		{"pic_compress", "6o", true, nil},
+		{"bucket_name", "images", false, func(c *Config) bool { return c.BucketName == "images" }},
+		{"api1_rate_limit", "2.5", false, func(c *Config) bool { return c.API1RateLimit == 2.5 }},
+		{"api1_rate_limit", "fast", true, nil},
+		{"profiles", "[]", true, nil},
+		{"no_such_field", "1", true, nil},
+	} {
+		config := &Config{UseSSL: true}
+		err := setConfigField(config, tc.field, tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("setConfigField(%s, %q) = %v，是否返回错误应为 %v", tc.field, tc.value, err, tc.wantErr)
+			continue
+		}
+		if tc.check != nil && !tc.check(config) {
+			t.Errorf("setConfigField(%s, %q) 后配置 = %+v", tc.field, tc.value, config)
+		}
+	}
+}
+
+func TestInitConfigOverridesRejects(t *testing.T) {
+	t.Cleanup(func() { configOverrides = nil })
+	for _, tc := range []struct {
+		name string
+		env  string
+		sets []string
+	}{
+		{"缺少等号", "", []string{"pic_compress"}},
+		{"未知字段", "", []string{"no_such_field=1"}},
+		{"不能覆盖的字段", "", []string{"schema_version=3"}},
+		{"取值无效", "", []string{"lockui=maybe"}},
+		{"环境变量取值无效", "abc", nil}, // 环境变量在测试结束前一直有效，放在最后
+	} {
+		if tc.env != "" {
+			t.Setenv("GOUPOSS_PIC_COMPRESS", tc.env)
+		}
+		if err := initConfigOverrides(tc.sets); err == nil {
+			t.Errorf("%s: initConfigOverrides 应返回错误", tc.name)
+		}
+	}
+}
+
+func TestConfigOverridePrecedence(t *testing.T) {
+	home := useTestHome(t, nil)
+	if err := SaveConfig("config.json", testConfig(t, home)); err != nil {
+		t.Fatal(err)
+	}
+	useOverrides(t, map[string]string{
+		"GOUPOSS_BUCKET_NAME":       "env-bucket",
+		"GOUPOSS_PIC_COMPRESS":      "70",
+		"GOUPOSS_SECRET_ACCESS_KEY": "env-secret",
+	}, "pic_compress=60")
+
+	config, err := appConfig.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 命令行 > 环境变量 > 配置文件
+	if config.PicCompress != 60 || config.BucketName != "env-bucket" || config.PicWidth != 1000 || config.SecretAccessKey != "env-secret" {
+		t.Errorf("生效配置 pic_compress = %d、bucket_name = %s、pic_width = %d、secretAccessKey = %s",
+			config.PicCompress, config.BucketName, config.PicWidth, config.SecretAccessKey)
+	}
+
+	fields, err := appConfig.Fields(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := fieldsByName(fields)
+	for field, want := range map[string]EffectiveField{
+		"pic_compress":     {Value: "60", Source: configFromFlag},
+		"bucket_name":      {Value: "env-bucket", Source: configFromEnv},
+		"pic_width":        {Value: "1000", Source: configFromFile},
+		"secretAccessKey":  {Value: "******", Source: configFromEnv},
+		"webhook_url":      {Value: "******", Source: configFromFile},
+		"s3_session_token": {Value: "", Source: configFromFile},
+	} {
+		if got := byName[field]; got.Value != want.Value || got.Source != want.Source {
+			t.Errorf("%s = %q（%s），应为 %q（%s）", field, got.Value, got.Source, want.Value, want.Source)
+		}
+	}
+	if got := byName["bucket_name"].Env; got != "GOUPOSS_BUCKET_NAME" {
+		t.Errorf("bucket_name 的环境变量 = %s", got)
+	}
+
+	// 配置文件中没有的字段来源为默认值
+	file, _ := appConfig.File()
+	byName = fieldsByName(effectiveFields(file, []byte(`{"pic_width":1000}`), nil))
+	if byName["pic_width"].Source != configFromFile || byName["pic_compress"].Source != configFromDefault {
+		t.Errorf("pic_width 来源 = %s、pic_compress 来源 = %s，应为配置文件、默认值", byName["pic_width"].Source, byName["pic_compress"].Source)
+	}
+}
+
+func TestUpdateKeepsOverriddenFileValues(t *testing.T) {
+	home := useTestHome(t, nil)
+	if err := SaveConfig("config.json", testConfig(t, home)); err != nil {
+		t.Fatal(err)
+	}
+	useOverrides(t, map[string]string{
+		"GOUPOSS_BUCKET_NAME":       "env-bucket",
+		"GOUPOSS_SECRET_ACCESS_KEY": "env-secret",
+	})
+
+	// 修改其他字段时，被覆盖的字段保持配置文件中的值
+	if err := appConfig.Update(configSourceUI, func(c *Config) { c.PicWidth = 800 }, "pic_width"); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadConfig("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PicWidth != 800 || saved.BucketName != "uposs" || saved.SecretAccessKey != "test-secret-key" {
+		t.Errorf("配置文件 pic_width = %d、bucket_name = %s、secretAccessKey = %s，覆盖的值不应写入配置文件",
+			saved.PicWidth, saved.BucketName, saved.SecretAccessKey)
+	}
+
+	// 本次修改了被覆盖的字段时写入新值，生效值仍为覆盖的值
+	if err := appConfig.Update(configSourceUI, func(c *Config) { c.BucketName = "ui-bucket" }, "bucket_name"); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := LoadConfig("config.json"); saved.BucketName != "ui-bucket" {
+		t.Errorf("配置文件 bucket_name = %s，应为 ui-bucket", saved.BucketName)
+	}
+	if current, _ := appConfig.Current(); current.BucketName != "env-bucket" {
+		t.Errorf("生效的 bucket_name = %s，应仍为环境变量的值", current.BucketName)
+	}
+}
@@ -44,8 +44,8 @@ func createLabeledEntryWithUnit(labelText string, entry *widget.Entry, unit stri
 func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	// 创建一个进度条，并初始化为配置中的压缩比率
 	progress := widget.NewProgressBar()
-	compress, err := strconv.Atoi(config.PicCompress) // 将配置中的压缩比率转换为整数
-	if err == nil && compress >= 1 && compress <= 100 {
+	compress := config.PicCompress
+	if compress >= 1 && compress <= 100 {
 		progress.SetValue(float64(compress) / 100.0) // 设置进度条初始值
 	} else {
 		progress.SetValue(0) // 如果转换失败，设置为 0
@@ -53,19 +53,38 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 
 	// 创建压缩比率输入框
 	compressInput := widget.NewEntry()
-	compressInput.SetPlaceHolder("请输入压缩比率（1-100）") // 提示用户输入压缩比率
-	compressInput.SetText(config.PicCompress)      // 设置默认值为配置文件中的压缩比率
+	compressInput.SetPlaceHolder("请输入压缩比率（1-100）")          // 提示用户输入压缩比率
+	compressInput.SetText(strconv.Itoa(config.PicCompress)) // 设置默认值为配置文件中的压缩比率
 
 	// 创建一个宽度输入框
 	widthInput := widget.NewEntry()
-	widthInput.SetPlaceHolder("请输入宽度")  // 提示用户输入宽度
-	widthInput.SetText(config.PicWidth) // 设置默认值为配置文件中的宽度
+	widthInput.SetPlaceHolder("请输入宽度")                // 提示用户输入宽度
+	widthInput.SetText(strconv.Itoa(config.PicWidth)) // 设置默认值为配置文件中的宽度
 
 	// 创建一个体积输入框
 	sizeInput := widget.NewEntry()
 	sizeInput.SetPlaceHolder("请输入体积（KB）")           // 提示用户输入体积
 	sizeInput.SetText(strconv.Itoa(config.PicSize)) // 设置默认值为配置文件中的体积
 
+	// 创建压缩并发数输入框
+	workersInput := widget.NewEntry()
+	workersInput.SetPlaceHolder("请输入压缩并发数（1-64）")
+	workersInput.SetText(strconv.Itoa(picWorkersOrDefault(config))) // 未配置时显示 CPU 核数
+
+	// 创建内存预算输入框
+	memBudgetInput := widget.NewEntry()
+	memBudgetInput.SetPlaceHolder("请输入内存预算（MB）")
+	memBudgetInput.SetText(strconv.Itoa(picMemBudgetOrDefault(config)))
+
+	// 配置被其他程序修改或恢复历史版本时同步变化的字段
+	onConfigChange(func(old, c *Config) {
+		syncEntry(compressInput, strconv.Itoa(old.PicCompress), strconv.Itoa(c.PicCompress))
+		syncEntry(widthInput, strconv.Itoa(old.PicWidth), strconv.Itoa(c.PicWidth))
+		syncEntry(sizeInput, strconv.Itoa(old.PicSize), strconv.Itoa(c.PicSize))
+		syncEntry(workersInput, strconv.Itoa(picWorkersOrDefault(old)), strconv.Itoa(picWorkersOrDefault(c)))
+		syncEntry(memBudgetInput, strconv.Itoa(picMemBudgetOrDefault(old)), strconv.Itoa(picMemBudgetOrDefault(c)))
+	})
+
 	// 创建一个日志输出框（多行文本框）
 	picLogText := widget.NewMultiLineEntry()
 	picLogText.SetMinRowsVisible(18) // 设置日志文本框可见行数
@@ -75,7 +94,7 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		// 获取用户输入的宽度
 		widthStr := widthInput.Text
 		width, err := strconv.Atoi(widthStr)
-		if err != nil || width < 1 || width > 10000 {
+		if err != nil {
 			// 如果输入无效，打印错误
 			updateLog(picLogText, "[图片配置]", "请输入有效的宽度（1-10000）！")
 			return
@@ -84,7 +103,7 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		// 获取用户输入的压缩比率
 		compressStr := compressInput.Text
 		compress, err := strconv.Atoi(compressStr)
-		if err != nil || compress < 1 || compress > 100 {
+		if err != nil {
 			// 如果压缩比率无效，打印错误
 			updateLog(picLogText, "[图片配置]", "请输入有效的压缩比率（1-100）！")
 			return
@@ -93,27 +112,43 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		// 获取用户输入的体积
 		sizeStr := sizeInput.Text
 		size, err := strconv.Atoi(sizeStr)
-		if err != nil || size < 1 {
+		if err != nil {
 			// 如果体积无效，打印错误
 			updateLog(picLogText, "[图片配置]", "请输入有效的体积（KB）！")
 			return
 		}
 
+		// 获取用户输入的压缩并发数
+		workers, err := strconv.Atoi(workersInput.Text)
+		if err != nil || workers < 1 {
+			updateLog(picLogText, "[图片配置]", "请输入有效的压缩并发数（1-64）！")
+			return
+		}
+
+		// 获取用户输入的内存预算
+		memBudget, err := strconv.Atoi(memBudgetInput.Text)
+		if err != nil {
+			updateLog(picLogText, "[图片配置]", "请输入有效的内存预算（不小于 64MB）！")
+			return
+		}
+
 		// 弹出确认对话框
 		dialog.ShowConfirm("确认保存", "你确定要保存配置吗？", func(confirmed bool) {
 			if !confirmed {
 				return
 			}
 
-			// 更新配置文件中的 pic_compress、pic_width 和 pic_size
-			config.PicWidth = widthStr
-			config.PicCompress = compressStr
-			config.PicSize = size
-
+			// 校验后更新配置文件中的 pic_compress、pic_width 和 pic_size
 			// 直接使用传入的 config 实例，不重新加载
-			if err := SaveConfig("config.json", config); err != nil {
-				errMsg := fmt.Sprintf("保存配置失败: %s", err.Error())
-				updateLog(picLogText, "[图片配置]", errMsg)
+			err := updateConfig(config, func(c *Config) {
+				c.PicWidth = width
+				c.PicCompress = compress
+				c.PicSize = size
+				c.PicWorkers = workers
+				c.PicMemBudget = memBudget
+			}, picConfigFields...)
+			if err != nil {
+				updateLog(picLogText, "[图片配置]", configSaveError(err))
 				return
 			}
 
@@ -121,8 +156,8 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 			progress.SetValue(float64(compress) / 100.0) // 设置进度条值，范围为 0.0 到 1.0
 
 			// 输出操作成功日志
-			successMsg := fmt.Sprintf("压缩比率设置为: %d%%，宽度设置为: %d，过滤图片的大小设置为: %dKB",
-				compress, width, size)
+			successMsg := fmt.Sprintf("压缩比率设置为: %d%%，宽度设置为: %d，过滤图片的大小设置为: %dKB，压缩并发数: %d，内存预算: %dMB",
+				compress, width, size, workers, memBudget)
 			updateLog(picLogText, "[图片配置]", successMsg)
 
 		}, myWindow) // myWindow 是当前窗口的引用
@@ -135,10 +170,12 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	compressBox := createLabeledEntryWithUnit("图片质量：", compressInput, "%")
 	widthBox := createLabeledEntryWithUnit("图片宽度：", widthInput, "px")
 	sizeBox := createLabeledEntryWithUnit("过滤大小：", sizeInput, "KB")
+	workersBox := createLabeledEntryWithUnit("压缩并发：", workersInput, "个")
+	memBudgetBox := createLabeledEntryWithUnit("内存预算：", memBudgetInput, "MB")
 
 	// 记录载入界面信息到系统日志
-	SysLogToFile(fmt.Sprintf("[图片配置] 配置已载入，压缩率=%s%%，宽度=%s，过滤大小=%dKB",
-		config.PicCompress, config.PicWidth, config.PicSize))
+	SysLogToFile(fmt.Sprintf("[图片配置] 配置已载入，压缩率=%d%%，宽度=%d，过滤大小=%dKB，压缩并发=%d，内存预算=%dMB",
+		config.PicCompress, config.PicWidth, config.PicSize, picWorkersOrDefault(config), picMemBudgetOrDefault(config)))
 
 	// 将控件放到垂直布局中，并将百分比条和按钮放在右边，文本框放在下面
 	return container.NewBorder(
@@ -147,9 +184,11 @@ func createPicConfigUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		nil,        // left
 		container.NewVBox(progress, buttonContainer), // right
 		container.NewVBox(
-			compressBox, // 压缩比率的标签和输入框
-			widthBox,    // 宽度的标签和输入框
-			sizeBox,     // 体积的标签和输入框
+			compressBox,  // 压缩比率的标签和输入框
+			widthBox,     // 宽度的标签和输入框
+			sizeBox,      // 体积的标签和输入框
+			workersBox,   // 压缩并发数的标签和输入框
+			memBudgetBox, // 内存预算的标签和输入框
 		),
 	)
 }
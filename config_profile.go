@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// defaultProfileName 命令行 --profile 参数中表示默认方案的名称，默认方案即配置文件顶层的字段
+const defaultProfileName = "default"
+
+// Profile 配置方案：一条产线单独的源文件夹、本地文件夹、图片参数、存储桶和接口地址
+// 字段名与 Config 相同，未设置的字段（空字符串、0、未写 useSSL）使用配置文件顶层的值
+type Profile struct {
+	Name string `json:"name"` // 方案名称，用于日志目录、复制记录和执行记录
+
+	MachineCode     string `json:"machine_code,omitempty"`
+	BucketName      string `json:"bucket_name,omitempty"`
+	Endpoint        string `json:"endpoint,omitempty"`
+	PublicUrl       string `json:"public_url,omitempty"`
+	AccessKeyID     string `json:"accessKeyID,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	UseSSL          *bool  `json:"useSSL,omitempty"`
+	S3Region        string `json:"s3_region,omitempty"`
+	S3SessionToken  string `json:"s3_session_token,omitempty"`
+
+	StorageType    string `json:"storage_type,omitempty"`
+	LocalStoreDir  string `json:"local_store_dir,omitempty"`
+	WebDAVURL      string `json:"webdav_url,omitempty"`
+	WebDAVUser     string `json:"webdav_user,omitempty"`
+	WebDAVPassword string `json:"webdav_password,omitempty"`
+	SFTPHost       string `json:"sftp_host,omitempty"`
+	SFTPUser       string `json:"sftp_user,omitempty"`
+	SFTPPassword   string `json:"sftp_password,omitempty"`
+	SFTPKeyFile    string `json:"sftp_key_file,omitempty"`
+	SFTPHostKey    string `json:"sftp_host_key,omitempty"`
+	SFTPDir        string `json:"sftp_dir,omitempty"`
+	PublicBaseURL  string `json:"public_base_url,omitempty"`
+
+	LocalFolder  string `json:"local_folder,omitempty"`
+	RemoteFolder string `json:"remote_folder,omitempty"`
+
+	PicCompress int `json:"pic_compress,omitempty"`
+	PicWidth    int `json:"pic_width,omitempty"`
+	PicSize     int `json:"pic_size,omitempty"`
+
+	API1          string `json:"api1,omitempty"`
+	API2          string `json:"api2,omitempty"`
+	API1Response1 string `json:"api1_response1,omitempty"`
+	API1Response2 string `json:"api1_response2,omitempty"`
+	WebhookURL    string `json:"webhook_url,omitempty"`
+}
+
+// profileNamePattern 方案名称规则：字母、数字、下划线和短横线，同时作为日志子目录名
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// configFieldIndex 按 json 字段名索引 Config 的字段，用于把方案的字段覆盖到配置上
+var configFieldIndex = func() map[string]int {
+	index := map[string]int{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		index[jsonFieldName(t.Field(i))] = i
+	}
+	return index
+}()
+
+// jsonFieldName 返回结构体字段在配置文件中的名称
+func jsonFieldName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+// setFields 返回方案中设置了的字段名（不含 name），按配置文件中的顺序排列
+func (p *Profile) setFields() []string {
+	var fields []string
+	v := reflect.ValueOf(*p)
+	for i := 0; i < v.NumField(); i++ {
+		name := jsonFieldName(v.Type().Field(i))
+		if name != "name" && !v.Field(i).IsZero() {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// ForProfile 返回配置方案 name 的完整配置：顶层配置的副本，再用方案中设置了的字段覆盖
+// name 为空时返回默认方案；返回的配置 Profile 为方案名称，不再包含 profiles
+func (config *Config) ForProfile(name string) (*Config, error) {
+	c := *config
+	c.Profiles = nil
+	c.Profile = name
+	if name == "" {
+		return &c, nil
+	}
+
+	p := config.findProfile(name)
+	if p == nil {
+		return nil, fmt.Errorf("配置方案 %s 不存在", name)
+	}
+	cv := reflect.ValueOf(&c).Elem()
+	pv := reflect.ValueOf(*p)
+	for i := 0; i < pv.NumField(); i++ {
+		field := jsonFieldName(pv.Type().Field(i))
+		value := pv.Field(i)
+		if field == "name" || value.IsZero() {
+			continue
+		}
+		if value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+		cv.Field(configFieldIndex[field]).Set(value)
+	}
+	return &c, nil
+}
+
+// findProfile 返回名称为 name 的方案，不存在时返回 nil
+func (config *Config) findProfile(name string) *Profile {
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			return &config.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// ProfileNames 返回全部方案名称，第一个为默认方案（空字符串）
+func (config *Config) ProfileNames() []string {
+	names := []string{""}
+	for _, p := range config.Profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// SelectProfiles 返回命令行 --profile 选择的方案，only 为空时返回全部方案，为 default 时返回默认方案
+func (config *Config) SelectProfiles(only string) ([]string, error) {
+	switch only {
+	case "":
+		return config.ProfileNames(), nil
+	case defaultProfileName:
+		return []string{""}, nil
+	}
+	if config.findProfile(only) == nil {
+		return nil, fmt.Errorf("配置方案 %s 不存在", only)
+	}
+	return []string{only}, nil
+}
+
+// clone 返回配置的副本，profiles 不与原配置共用
+func (config *Config) clone() *Config {
+	c := *config
+	c.Profiles = append([]Profile(nil), config.Profiles...)
+	return &c
+}
+
+// validateProfiles 校验方案名称和每个方案覆盖后的配置，错误字段名为 profiles.<方案>.<字段>
+// 方案从顶层继承的字段只在顶层报告；各方案的 local_folder 不能相同，否则会互相处理和删除对方的文件
+func (config *Config) validateProfiles(add func(field, format string, args ...interface{})) {
+	seen := map[string]bool{}
+	type folder struct{ profile, path string }
+	localFolders := []folder{{profileLabel(""), config.LocalFolder}}
+	for i := range config.Profiles {
+		p := &config.Profiles[i]
+		switch {
+		case p.Name == "":
+			add(fmt.Sprintf("profiles[%d].name", i), "不能为空")
+			continue
+		case !profileNamePattern.MatchString(p.Name):
+			add(fmt.Sprintf("profiles[%d].name", i), "%q 只能包含字母、数字、下划线和短横线", p.Name)
+			continue
+		case p.Name == defaultProfileName:
+			add(fmt.Sprintf("profiles[%d].name", i), "%q 表示默认方案，不能作为方案名称", p.Name)
+			continue
+		case seen[p.Name]:
+			add(fmt.Sprintf("profiles[%d].name", i), "方案名称 %s 重复", p.Name)
+			continue
+		}
+		seen[p.Name] = true
+
+		resolved, _ := config.ForProfile(p.Name)
+		fields := p.setFields()
+		// 方案修改了存储类型或连接参数时，按方案最终使用的存储类型校验全部存储字段
+		for _, f := range fields {
+			if isStorageField(f) {
+				fields = append(fields, storageConfigFields...)
+				break
+			}
+		}
+		if len(fields) > 0 {
+			for _, e := range validationErrorsOf(resolved.ValidateFields(fields...)) {
+				add(fmt.Sprintf("profiles.%s.%s", p.Name, e.Field), "%s", e.Message)
+			}
+		}
+		for _, other := range localFolders {
+			if resolved.LocalFolder != "" && sameFolder(resolved.LocalFolder, other.path) {
+				add(fmt.Sprintf("profiles.%s.local_folder", p.Name), "与方案 %s 的 local_folder 相同，每个方案需要单独的本地文件夹", other.profile)
+				break
+			}
+		}
+		localFolders = append(localFolders, folder{p.Name, resolved.LocalFolder})
+	}
+}
+
+// sameFolder 判断两个路径是否为同一个文件夹，Windows 路径不区分大小写
+func sameFolder(a, b string) bool {
+	return strings.EqualFold(strings.TrimRight(a, `/\`), strings.TrimRight(b, `/\`))
+}
+
+// profilesEqual 判断两组方案是否相同
+func profilesEqual(a, b []Profile) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// profilesSummary 返回方案列表的文字描述，用于配置变更记录
+func profilesSummary(profiles []Profile) string {
+	if len(profiles) == 0 {
+		return "无"
+	}
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return strings.Join(names, "、")
+}
+
+// profileLabel 返回方案在界面和日志中显示的名称
+func profileLabel(name string) string {
+	if name == "" {
+		return "默认"
+	}
+	return name
+}
+
+// profileCronTask 返回方案的定时任务名称，默认方案沿用 auto、sched，其他方案为 auto:<方案>
+func profileCronTask(task, profile string) string {
+	if profile == "" {
+		return task
+	}
+	return task + ":" + profile
+}
+
+// currentProfileConfig 返回 appConfig 当前配置中方案 profile 的完整配置，任务在每个周期开始时调用
+func currentProfileConfig(profile string) (*Config, error) {
+	config, err := appConfig.Current()
+	if err != nil {
+		return nil, err
+	}
+	return config.ForProfile(profile)
+}
+
+// runProfiles 为每个方案并发执行 fn，全部结束后返回各方案错误的合并结果
+func runProfiles(profiles []string, fn func(profile string) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, profile := range profiles {
+		wg.Add(1)
+		go func(profile string) {
+			defer wg.Done()
+			if err := fn(profile); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(profile)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	configRemotePath    = "/config/bundle" // 界面模式下接收远程下发配置包的地址，位于程序监听的端口
+	configRemoteMaxSize = 4 << 20          // 配置包的最大字节数
+	configRemoteTimeout = 30 * time.Second // 从远程地址下载配置包的超时时间
+)
+
+// remoteBundleMu 保护 lastRemoteBundle
+var remoteBundleMu sync.Mutex
+
+// lastRemoteBundle 本进程最近一次应用的远程配置包的导出时间，导出时间不晚于它的配置包被拒绝，防止重放旧的配置包
+var lastRemoteBundle time.Time
+
+// isConfigURL 判断 config import 的参数是否为远程地址
+func isConfigURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// fetchConfigBundle 从远程地址下载配置包，签名在导入时校验
+func fetchConfigBundle(url string) ([]byte, error) {
+	client := http.Client{Timeout: configRemoteTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("下载配置包失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载配置包失败: %s 返回状态码 %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, configRemoteMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("下载配置包失败: %v", err)
+	}
+	if len(data) > configRemoteMaxSize {
+		return nil, fmt.Errorf("配置包超过 %d 字节", configRemoteMaxSize)
+	}
+	return data, nil
+}
+
+// applyRemoteConfigBundle 校验远程下发的配置包并应用，校验不通过或配置包不比上次下发的新时拒绝
+func applyRemoteConfigBundle(data []byte, passphrase string) (*configImport, error) {
+	imp, err := prepareConfigImport(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(imp.Errors) > 0 {
+		return imp, fmt.Errorf("导入后的配置校验不通过: %v", imp.Errors)
+	}
+
+	remoteBundleMu.Lock()
+	defer remoteBundleMu.Unlock()
+	if !imp.Bundle.ExportedAt.After(lastRemoteBundle) {
+		return imp, fmt.Errorf("配置包导出于 %s，不晚于上次下发的配置包，可能是重放的旧配置包",
+			imp.Bundle.ExportedAt.Format("2006.01.02 15:04:05"))
+	}
+	if len(imp.Changes) > 0 {
+		if err := imp.apply(configSourceRemote); err != nil {
+			return imp, err
+		}
+	}
+	lastRemoteBundle = imp.Bundle.ExportedAt
+	return imp, nil
+}
+
+// registerConfigRemoteHandler 界面模式下在程序监听的端口上接收远程下发的配置包（POST 配置包文件内容）
+// 只有设置了 GOUPOSS_BUNDLE_KEY 时才启用，配置包需要用相同的口令签名
+func registerConfigRemoteHandler() {
+	passphrase := os.Getenv(configBundleKeyEnv)
+	if passphrase == "" {
+		return
+	}
+	http.HandleFunc(configRemotePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "只支持 POST", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, configRemoteMaxSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("读取配置包失败: %v", err), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		imp, err := applyRemoteConfigBundle(data, passphrase)
+		if err != nil {
+			SysLogToFile(fmt.Sprintf("拒绝 %s 下发的配置包: %v", r.RemoteAddr, err))
+			status := http.StatusUnprocessableEntity
+			if imp == nil {
+				status = http.StatusForbidden // 签名校验失败或不是配置包
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		SysLogToFile(fmt.Sprintf("已应用 %s 下发的配置包，修改 %d 个字段", r.RemoteAddr, len(imp.Changes)))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"changes": len(imp.Changes),
+			"summary": imp.summary(),
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testBundleKey = "bundle-passphrase"
+
+// exportTestBundle 导出在当前配置文件基础上修改后的配置包
+func exportTestBundle(t *testing.T, modify func(c *Config)) []byte {
+	t.Helper()
+	config, err := appConfig.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modify(config)
+	data, err := exportConfigBundle(config, testBundleKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// resetRemoteBundle 测试结束后清除上次下发的配置包记录
+func resetRemoteBundle(t *testing.T) {
+	t.Cleanup(func() {
+		remoteBundleMu.Lock()
+		lastRemoteBundle = time.Time{}
+		remoteBundleMu.Unlock()
+	})
+}
+
+func TestApplyRemoteConfigBundle(t *testing.T) {
+	home := useTestHome(t, nil)
+	if err := SaveConfig("config.json", testConfig(t, home)); err != nil {
+		t.Fatal(err)
+	}
+	resetRemoteBundle(t)
+
+	var sources []string
+	unsubscribe := appConfig.Subscribe(func(ev ConfigEvent) { sources = append(sources, ev.Source) })
+	defer unsubscribe()
+
+	bundle := exportTestBundle(t, func(c *Config) {
+		c.PicCompress = 50
+		c.MachineCode = "machine-02"
+	})
+	imp, err := applyRemoteConfigBundle(bundle, testBundleKey)
+	if err != nil {
+		t.Fatalf("applyRemoteConfigBundle: %v", err)
+	}
+	if len(imp.Changes) != 1 {
+		t.Errorf("变更 = %+v，machine_code 应保留本机的值，只修改 pic_compress", imp.Changes)
+	}
+	current, _ := appConfig.Current()
+	if current.PicCompress != 50 || current.MachineCode != "machine-01" {
+		t.Errorf("应用后 pic_compress = %d、machine_code = %s，应为 50、machine-01", current.PicCompress, current.MachineCode)
+	}
+	if len(sources) != 1 || sources[0] != configSourceRemote {
+		t.Errorf("通知来源 = %v，应为 [%s]", sources, configSourceRemote)
+	}
+
+	// 重放同一个配置包
+	if _, err := applyRemoteConfigBundle(bundle, testBundleKey); err == nil {
+		t.Error("重放的配置包应被拒绝")
+	}
+	// 口令不正确
+	if imp, err := applyRemoteConfigBundle(exportTestBundle(t, func(c *Config) {}), "wrong"); err == nil || imp != nil {
+		t.Errorf("口令不正确时 = %v, %v，应返回签名错误", imp, err)
+	}
+	// 导入后的配置校验不通过
+	invalid := exportTestBundle(t, func(c *Config) { c.PicCompress = 0 })
+	if _, err := applyRemoteConfigBundle(invalid, testBundleKey); err == nil {
+		t.Error("校验不通过的配置包应被拒绝")
+	}
+	if current, _ := appConfig.Current(); current.PicCompress != 50 {
+		t.Errorf("被拒绝的配置包不应修改配置，pic_compress = %d", current.PicCompress)
+	}
+}
+
+func TestConfigRemoteHandler(t *testing.T) {
+	home := useTestHome(t, nil)
+	if err := SaveConfig("config.json", testConfig(t, home)); err != nil {
+		t.Fatal(err)
+	}
+	resetRemoteBundle(t)
+	t.Setenv(configBundleKeyEnv, testBundleKey)
+
+	mux := http.DefaultServeMux
+	registerConfigRemoteHandler()
+	post := func(method string, body []byte) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, configRemotePath, bytes.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(http.MethodGet, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET 状态码 = %d，应为 %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	bundle := exportTestBundle(t, func(c *Config) { c.PicWidth = 800 })
+	tampered := bytes.Replace(bundle, []byte(`"pic_width": 800`), []byte(`"pic_width": 900`), 1)
+	if bytes.Equal(tampered, bundle) {
+		t.Fatal("没有找到需要修改的字段")
+	}
+	if rec := post(http.MethodPost, tampered); rec.Code != http.StatusForbidden {
+		t.Errorf("被修改的配置包状态码 = %d，应为 %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := post(http.MethodPost, bundle)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct{ Changes int }
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Changes != 1 {
+		t.Errorf("响应 = %s，应修改 1 个字段", rec.Body.String())
+	}
+	if current, _ := appConfig.Current(); current.PicWidth != 800 {
+		t.Errorf("应用后 pic_width = %d，应为 800", current.PicWidth)
+	}
+}
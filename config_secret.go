@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go-uposs/utils"
+)
+
+// configSecretEnv 设置后使用该环境变量的值作为加密配置密钥的口令，未设置时使用本机标识
+const configSecretEnv = "GOUPOSS_SECRET_KEY"
+
+// configSecretFields 加密保存的字段，变更记录中也不显示明文
+var configSecretFields = map[string]bool{
+	"accessKeyID": true, "secretAccessKey": true, "s3_session_token": true, "webhook_url": true,
+	"webdav_password": true, "sftp_password": true,
+}
+
+// configSecret 加密保存的字段，field 为加密时认证的字段名
+type configSecret struct {
+	field string
+	value *string
+}
+
+// configSecrets 返回 config 中加密保存的字段，按配置文件中的顺序排列
+// 方案中的密钥以 profiles.<字段> 认证，不含方案名称，修改方案名称后仍可解密
+func configSecrets(config *Config) []configSecret {
+	secrets := []configSecret{
+		{"accessKeyID", &config.AccessKeyID},
+		{"secretAccessKey", &config.SecretAccessKey},
+		{"s3_session_token", &config.S3SessionToken},
+		{"webhook_url", &config.WebhookURL},
+		{"webdav_password", &config.WebDAVPassword},
+		{"sftp_password", &config.SFTPPassword},
+	}
+	for i := range config.Profiles {
+		secrets = append(secrets, config.Profiles[i].secrets()...)
+	}
+	return secrets
+}
+
+// secrets 返回方案中加密保存的字段，按配置文件中的顺序排列
+func (p *Profile) secrets() []configSecret {
+	return []configSecret{
+		{"profiles.accessKeyID", &p.AccessKeyID},
+		{"profiles.secretAccessKey", &p.SecretAccessKey},
+		{"profiles.s3_session_token", &p.S3SessionToken},
+		{"profiles.webdav_password", &p.WebDAVPassword},
+		{"profiles.sftp_password", &p.SFTPPassword},
+		{"profiles.webhook_url", &p.WebhookURL},
+	}
+}
+
+// secretKey 加密配置密钥的口令和来源
+type secretKey struct {
+	passphrase string
+	source     string // 口令来源，用于提示，例如 环境变量 GOUPOSS_SECRET_KEY
+}
+
+var (
+	configKeyMu sync.Mutex
+	configKey   *secretKey
+)
+
+// machineSecretKey 返回与本机绑定的口令，配置文件复制到其他机器后无法解密
+func machineSecretKey() (*secretKey, error) {
+	id, err := utils.MachineID()
+	if err != nil {
+		return nil, fmt.Errorf("读取本机标识失败，请设置环境变量 %s: %v", configSecretEnv, err)
+	}
+	return &secretKey{passphrase: "gouposs-machine:" + id, source: "本机标识"}, nil
+}
+
+// envSecretKey 返回环境变量 name 中的口令，未设置时返回 nil
+func envSecretKey(name string) *secretKey {
+	if v := os.Getenv(name); v != "" {
+		return &secretKey{passphrase: v, source: "环境变量 " + name}
+	}
+	return nil
+}
+
+// currentSecretKey 返回本进程加密配置使用的口令：环境变量 GOUPOSS_SECRET_KEY 优先，未设置时使用本机标识
+func currentSecretKey() (*secretKey, error) {
+	configKeyMu.Lock()
+	defer configKeyMu.Unlock()
+	if configKey != nil {
+		return configKey, nil
+	}
+	key := envSecretKey(configSecretEnv)
+	if key == nil {
+		var err error
+		if key, err = machineSecretKey(); err != nil {
+			return nil, err
+		}
+	}
+	configKey = key
+	return key, nil
+}
+
+// setSecretKey 切换本进程加密配置使用的口令，轮换密钥后调用
+func setSecretKey(key *secretKey) {
+	configKeyMu.Lock()
+	defer configKeyMu.Unlock()
+	configKey = key
+}
+
+// encryptConfigSecrets 加密 config 中的明文密钥字段，已加密的字段保持不变；没有盐时生成新的盐
+// config 应为写入文件用的副本，界面和任务使用的配置始终是明文
+func encryptConfigSecrets(config *Config, key *secretKey) error {
+	if config.SecretSalt == "" {
+		salt, err := utils.NewSecretSalt()
+		if err != nil {
+			return err
+		}
+		config.SecretSalt = salt
+	}
+	for _, s := range configSecrets(config) {
+		if *s.value == "" || utils.IsEncryptedSecret(*s.value) {
+			continue
+		}
+		enc, err := utils.EncryptSecret(key.passphrase, config.SecretSalt, s.field, *s.value)
+		if err != nil {
+			return fmt.Errorf("加密 %s 失败: %v", s.field, err)
+		}
+		*s.value = enc
+	}
+	return nil
+}
+
+// decryptConfigSecrets 在内存中解密 config 的密钥字段，返回是否有尚未加密的明文字段
+func decryptConfigSecrets(config *Config, key *secretKey) (bool, error) {
+	plaintext := false
+	for _, s := range configSecrets(config) {
+		if *s.value == "" {
+			continue
+		}
+		if !utils.IsEncryptedSecret(*s.value) {
+			plaintext = true
+			continue
+		}
+		dec, err := utils.DecryptSecret(key.passphrase, config.SecretSalt, s.field, *s.value)
+		if err != nil {
+			if errors.Is(err, utils.ErrSecretKey) {
+				return false, fmt.Errorf("无法解密 %s：使用%s派生的密钥不正确，配置文件是否来自其他机器，或 %s 是否与加密时一致", s.field, key.source, configSecretEnv)
+			}
+			return false, fmt.Errorf("无法解密 %s: %v", s.field, err)
+		}
+		*s.value = dec
+	}
+	return plaintext, nil
+}
+
+// encryptedConfig 返回写入文件用的副本，密钥字段已加密；config 没有盐时同时记录新生成的盐
+func encryptedConfig(config *Config) (*Config, error) {
+	key, err := currentSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	stored := config.clone()
+	if err := encryptConfigSecrets(stored, key); err != nil {
+		return nil, err
+	}
+	config.SecretSalt = stored.SecretSalt
+	return stored, nil
+}
+
+// decryptConfig 使用本进程的口令解密从文件读取的配置，返回是否有尚未加密的明文字段
+func decryptConfig(config *Config) (bool, error) {
+	key, err := currentSecretKey()
+	if err != nil {
+		return false, err
+	}
+	return decryptConfigSecrets(config, key)
+}
+
+// renameFile 替换文件，测试中替换以模拟失败
+var renameFile = os.Rename
+
+// stagedFile 轮换密钥时已写入临时文件、尚未替换的历史版本
+type stagedFile struct {
+	tmp  string // 新口令加密的临时文件
+	path string // 历史版本文件
+	old  []byte // 原口令加密的内容
+}
+
+// rotateConfigSecrets 用 newKey 和新的盐重新加密配置文件及其历史版本，完成后本进程改用 newKey
+// 轮换期间持有配置文件锁；历史版本先写入临时文件，配置文件写入成功后再替换，写入或替换失败时恢复原文件，都保持原口令加密
+// 无法用当前口令解密的历史版本保持不变，返回其数量
+func rotateConfigSecrets(filename string, newKey *secretKey) (int, error) {
+	// 旧版本的配置文件先升级，明文密钥先加密保存
+	if _, err := LoadConfig(filename); err != nil {
+		return 0, err
+	}
+
+	unlock, err := utils.LockFile(configLockPath(filename))
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	configFilePath := filepath.Join(utils.DataPath, filename)
+	config, err := readConfigFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	var savedAt time.Time
+	if info, err := os.Stat(configFilePath); err == nil {
+		savedAt = info.ModTime()
+	}
+	files, err := configVersionFiles(filename)
+	if err != nil {
+		return 0, err
+	}
+	versions, err := listConfigVersions(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	salt, err := utils.NewSecretSalt()
+	if err != nil {
+		return 0, err
+	}
+	oldKey, err := currentSecretKey()
+	if err != nil {
+		return 0, err
+	}
+	setSecretKey(newKey)
+
+	// 替换失败时用于恢复的原文件内容
+	oldData, err := os.ReadFile(configFilePath)
+	if err != nil {
+		setSecretKey(oldKey)
+		return 0, err
+	}
+
+	// 用新口令加密的历史版本先写入临时文件
+	var staged []stagedFile
+	discard := func(files []stagedFile) {
+		for _, f := range files {
+			os.Remove(f.tmp)
+		}
+	}
+	for i := range versions {
+		versions[i].Config.SecretSalt = salt
+		path, data, err := encodeConfigVersion(filename, &versions[i])
+		if err != nil {
+			discard(staged)
+			setSecretKey(oldKey)
+			return 0, err
+		}
+		old, err := os.ReadFile(path)
+		if err != nil {
+			discard(staged)
+			setSecretKey(oldKey)
+			return 0, fmt.Errorf("读取配置历史失败: %v", err)
+		}
+		tmp, err := utils.WriteTempFile(path, data, 0644)
+		if err != nil {
+			discard(staged)
+			setSecretKey(oldKey)
+			return 0, fmt.Errorf("写入配置历史失败: %v", err)
+		}
+		staged = append(staged, stagedFile{tmp: tmp, path: path, old: old})
+	}
+
+	config.SecretSalt = salt
+	data, err := encodeConfigFile(config)
+	if err == nil {
+		err = utils.WriteFileAtomic(configFilePath, data, 0644)
+	}
+	if err != nil {
+		discard(staged)
+		setSecretKey(oldKey)
+		return 0, err
+	}
+
+	// 配置文件已使用新口令，替换历史版本；任何一个替换失败时恢复配置文件和已替换的历史版本
+	for i, f := range staged {
+		if err := renameFile(f.tmp, f.path); err != nil {
+			discard(staged[i:])
+			setSecretKey(oldKey)
+			return 0, restoreRotation(configFilePath, oldData, staged[:i], fmt.Errorf("替换配置历史失败: %v", err))
+		}
+	}
+
+	// 记录本次轮换，失败不影响结果
+	if err := recordConfigVersion(filename, config, config, "轮换加密密钥", savedAt); err != nil {
+		SysLogToFile(fmt.Sprintf("记录配置历史失败: %v", err))
+	}
+	return len(files) - len(versions), nil
+}
+
+// restoreRotation 替换历史版本失败后将配置文件和已替换的历史版本恢复为原内容，返回包含 cause 的错误
+// 恢复失败的文件在错误中列出，这些文件仍使用新口令加密
+func restoreRotation(configFilePath string, oldConfig []byte, renamed []stagedFile, cause error) error {
+	var failed []string
+	if err := utils.WriteFileAtomic(configFilePath, oldConfig, 0644); err != nil {
+		failed = append(failed, configFilePath)
+	}
+	for _, f := range renamed {
+		if err := utils.WriteFileAtomic(f.path, f.old, 0644); err != nil {
+			failed = append(failed, f.path)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%v；恢复失败，以下文件仍使用新口令加密，其余文件使用原口令: %s", cause, strings.Join(failed, ", "))
+	}
+	return fmt.Errorf("%v；配置文件和历史版本已恢复为原口令加密", cause)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-uposs/utils"
+)
+
+// saveTestHistory 保存两次配置，产生基准版本和一个修改后的历史版本
+func saveTestHistory(t *testing.T) {
+	t.Helper()
+	config := testConfig(t, utils.GoupossPath)
+	if err := SaveConfig("config.json", config); err != nil {
+		t.Fatal(err)
+	}
+	config.PicCompress = 60
+	if err := SaveConfig("config.json", config); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// snapshotConfigFiles 返回配置文件和全部历史版本文件的内容，按路径索引
+func snapshotConfigFiles(t *testing.T) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	paths := []string{filepath.Join(utils.DataPath, "config.json")}
+	entries, err := os.ReadDir(filepath.Join(utils.DataPath, configHistoryDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		paths = append(paths, filepath.Join(utils.DataPath, configHistoryDir, e.Name()))
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path] = data
+	}
+	return files
+}
+
+func TestConfigSecretsEncryptedOnDisk(t *testing.T) {
+	useTestHome(t, nil)
+	saveTestHistory(t)
+
+	data, err := os.ReadFile(filepath.Join(utils.DataPath, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range []string{"test-access-key", "test-secret-key", "qyapi.weixin.qq.com"} {
+		if bytes.Contains(data, []byte(plaintext)) {
+			t.Errorf("配置文件中不应出现明文 %q", plaintext)
+		}
+	}
+	config, err := LoadConfig("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.AccessKeyID != "test-access-key" || config.SecretAccessKey != "test-secret-key" {
+		t.Errorf("解密后 access_key_id = %q、secret_access_key = %q", config.AccessKeyID, config.SecretAccessKey)
+	}
+}
+
+func TestRotateConfigSecrets(t *testing.T) {
+	useTestHome(t, nil)
+	saveTestHistory(t)
+	oldKey, _ := currentSecretKey()
+	files, _ := configVersionFiles("config.json")
+
+	newKey := &secretKey{passphrase: "new-passphrase", source: "新口令"}
+	skipped, err := rotateConfigSecrets("config.json", newKey)
+	if err != nil || skipped != 0 {
+		t.Fatalf("rotateConfigSecrets = %d, %v", skipped, err)
+	}
+	if key, _ := currentSecretKey(); key != newKey {
+		t.Error("轮换后本进程应使用新口令")
+	}
+
+	// 用新口令重新载入
+	appConfig = newConfigStore("config.json")
+	config, err := appConfig.Current()
+	if err != nil {
+		t.Fatalf("用新口令载入失败: %v", err)
+	}
+	if config.SecretAccessKey != "test-secret-key" || config.WebhookURL == "" || config.PicCompress != 60 {
+		t.Errorf("载入的配置 = %+v", config)
+	}
+	versions, _ := listConfigVersions("config.json")
+	if len(versions) != len(files)+1 {
+		t.Errorf("新口令可读取的历史版本 = %d，应为轮换前的 %d 个加上轮换记录", len(versions), len(files))
+	}
+
+	// 原口令不能解密，也不能覆盖轮换后的配置文件
+	setSecretKey(oldKey)
+	if _, err := LoadConfig("config.json"); err == nil {
+		t.Error("原口令不应能解密轮换后的配置文件")
+	}
+	if versions, _ := listConfigVersions("config.json"); len(versions) != 0 {
+		t.Errorf("原口令可读取 %d 个历史版本，应为 0", len(versions))
+	}
+	before := snapshotConfigFiles(t)
+	config.PicCompress = 70
+	if err := SaveConfig("config.json", config); !errors.Is(err, errConfigUndecryptable) {
+		t.Errorf("用原口令保存 = %v，应拒绝覆盖", err)
+	}
+	if after := snapshotConfigFiles(t); len(after) != len(before) ||
+		!bytes.Equal(after[filepath.Join(utils.DataPath, "config.json")], before[filepath.Join(utils.DataPath, "config.json")]) {
+		t.Error("拒绝保存时不应修改配置文件和历史版本")
+	}
+}
+
+func TestRotateConfigSecretsRestoresOnRenameFailure(t *testing.T) {
+	useTestHome(t, nil)
+	saveTestHistory(t)
+	oldKey, _ := currentSecretKey()
+	before := snapshotConfigFiles(t)
+	if len(before) < 3 {
+		t.Fatalf("需要至少 2 个历史版本，实际 %d 个", len(before)-1)
+	}
+
+	// 第二个历史版本替换失败
+	calls := 0
+	renameFile = func(oldpath, newpath string) error {
+		calls++
+		if calls == 2 {
+			return errors.New("模拟替换失败")
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+
+	_, err := rotateConfigSecrets("config.json", &secretKey{passphrase: "new-passphrase", source: "新口令"})
+	if err == nil || !strings.Contains(err.Error(), "已恢复") {
+		t.Fatalf("rotateConfigSecrets = %v，应返回已恢复的错误", err)
+	}
+	if key, _ := currentSecretKey(); key != oldKey {
+		t.Error("失败后本进程应继续使用原口令")
+	}
+
+	// 配置文件和历史版本与轮换前完全一致，临时文件已删除
+	after := snapshotConfigFiles(t)
+	if len(after) != len(before) {
+		t.Errorf("轮换失败后有 %d 个文件，应为 %d 个", len(after), len(before))
+	}
+	for path, data := range before {
+		if !bytes.Equal(after[path], data) {
+			t.Errorf("%s 未恢复为原内容", filepath.Base(path))
+		}
+	}
+	if _, err := LoadConfig("config.json"); err != nil {
+		t.Errorf("原口令应能解密恢复后的配置文件: %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go-uposs/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fsnotify/fsnotify"
+)
+
+// 配置变更的来源
+const (
+	configSourceUI      = "ui"      // 界面保存
+	configSourceFile    = "file"    // 配置文件被其他程序或手动修改
+	configSourceRestore = "restore" // 恢复历史版本
+	configSourceRemote  = "remote"  // 远程下发的配置包（界面模式的 /config/bundle 或 config import 远程地址）
+)
+
+// configFileDebounce 配置文件被外部修改后等待写入完成的时间
+const configFileDebounce = 500 * time.Millisecond
+
+// ConfigEvent 配置变更通知，Old 和 New 都是副本，订阅者可以直接保存使用
+type ConfigEvent struct {
+	Source  string
+	Old     *Config
+	New     *Config
+	Changes []ConfigChange
+}
+
+// configStore 持有程序当前使用的配置
+// 界面、任务和后台协程都通过 appConfig 读取和修改配置，修改后通知订阅者；
+// 配置文件被外部修改时在 Watch 检测到或下一次 Current 时重新载入
+type configStore struct {
+	filename string
+
+	mu      sync.Mutex
+	current *Config   // 配置文件中的配置，不含环境变量和命令行的覆盖
+	data    []byte    // 最近一次读取或写入的文件内容
+	modTime time.Time // 最近一次读取或写入后的文件修改时间
+	size    int64
+
+	subsMu sync.Mutex
+	subs   map[int]func(ConfigEvent)
+	nextID int
+}
+
+// appConfig 程序共用的配置
+var appConfig = newConfigStore("config.json")
+
+func newConfigStore(filename string) *configStore {
+	return &configStore{filename: filename, subs: map[int]func(ConfigEvent){}}
+}
+
+// path 返回配置文件路径
+func (s *configStore) path() string {
+	return filepath.Join(utils.DataPath, s.filename)
+}
+
+// Load 从文件载入配置（旧版本先升级），不通知订阅者，返回配置副本
+func (s *configStore) Load() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	return s.copyLocked(), nil
+}
+
+// loadLocked 读取配置文件并记录文件状态，调用方持有 s.mu
+func (s *configStore) loadLocked() error {
+	config, err := LoadConfig(s.filename)
+	if err != nil {
+		return err
+	}
+	s.current = config
+	s.recordFileLocked()
+	return nil
+}
+
+// recordFileLocked 记录配置文件当前的内容和修改时间，用于判断之后是否被外部修改
+func (s *configStore) recordFileLocked() {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return
+	}
+	s.data = data
+	if info, err := os.Stat(s.path()); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+}
+
+// copyLocked 返回当前生效配置的副本（配置文件再应用环境变量和命令行的覆盖），调用方持有 s.mu
+func (s *configStore) copyLocked() *Config {
+	c := s.current.clone()
+	applyConfigOverrides(c)
+	return c
+}
+
+// File 返回配置文件中的配置副本，不含覆盖，用于导出、导入和与历史版本比较
+func (s *configStore) File() (*Config, error) {
+	if _, err := s.Current(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.clone(), nil
+}
+
+// Fields 返回每个可覆盖字段的值和来源，effective 为 false 时返回配置文件中的值
+func (s *configStore) Fields(effective bool) ([]EffectiveField, error) {
+	if _, err := s.Current(); err != nil {
+		return nil, err
+	}
+	var overrides []configOverride
+	if effective {
+		overrides = configOverrides
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return effectiveFields(s.current, s.data, overrides), nil
+}
+
+// Current 返回当前配置的副本，任务在每个周期开始时调用
+// 尚未载入时先载入；配置文件被外部修改过时先重新载入并通知订阅者
+func (s *configStore) Current() (*Config, error) {
+	s.mu.Lock()
+	if s.current == nil {
+		err := s.loadLocked()
+		if err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		c := s.copyLocked()
+		s.mu.Unlock()
+		return c, nil
+	}
+	s.mu.Unlock()
+
+	if err := s.Reload(); err != nil {
+		SysLogToFile(fmt.Sprintf("重新载入配置失败，继续使用当前配置: %v", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.copyLocked(), nil
+}
+
+// Reload 配置文件被外部修改时重新载入并通知订阅者，文件没有变化时不做任何事
+func (s *configStore) Reload() error {
+	s.mu.Lock()
+	if s.current == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	info, err := os.Stat(s.path())
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if bytes.Equal(data, s.data) {
+		s.modTime, s.size = info.ModTime(), info.Size()
+		s.mu.Unlock()
+		return nil
+	}
+
+	old := s.copyLocked()
+	if err := s.loadLocked(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	next := s.copyLocked()
+	s.mu.Unlock()
+
+	SysLogToFile("配置文件已被外部修改，已重新载入")
+	s.notify(configSourceFile, old, next)
+	return nil
+}
+
+// Update 在当前配置的副本上应用 apply，校验 fields 字段通过后保存并通知订阅者
+// 没有字段变化时不保存；校验失败时返回 ValidationErrors，当前配置保持不变
+// 被覆盖的字段只有在本次修改时才写入配置文件，生效值仍为覆盖的值
+func (s *configStore) Update(source string, apply func(c *Config), fields ...string) error {
+	if _, err := s.Current(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.copyLocked()
+	next := s.copyLocked()
+	apply(next)
+	if len(configChanges(old, next)) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if err := next.ValidateFields(fields...); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if err := s.saveLocked(withFileValues(s.current, old, next), ""); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	next = s.copyLocked()
+	s.mu.Unlock()
+
+	s.notify(source, old, next)
+	return nil
+}
+
+// Replace 用配置文件中的配置 config 替换当前配置并保存，note 记录在配置历史中，用于恢复历史版本和导入配置包
+func (s *configStore) Replace(source string, config *Config, note string) error {
+	if _, err := s.Current(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.copyLocked()
+	if err := s.saveLocked(config.clone(), note); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	next := s.copyLocked()
+	s.mu.Unlock()
+
+	s.notify(source, old, next)
+	return nil
+}
+
+// saveLocked 保存配置并更新当前配置，调用方持有 s.mu
+func (s *configStore) saveLocked(config *Config, note string) error {
+	if err := saveConfigFile(s.filename, config, note); err != nil {
+		return err
+	}
+	s.current = config.clone()
+	s.recordFileLocked()
+	return nil
+}
+
+// Subscribe 注册配置变更通知，返回取消订阅的函数
+// 通知在修改配置的协程中按注册顺序依次调用，订阅者不能在通知中修改配置
+func (s *configStore) Subscribe(fn func(ConfigEvent)) func() {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.subs[id] = fn
+	return func() {
+		s.subsMu.Lock()
+		defer s.subsMu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+// notify 通知全部订阅者，每个订阅者收到独立的副本
+func (s *configStore) notify(source string, old, next *Config) {
+	s.subsMu.Lock()
+	ids := make([]int, 0, len(s.subs))
+	for id := range s.subs {
+		ids = append(ids, id)
+	}
+	subs := make([]func(ConfigEvent), 0, len(ids))
+	sort.Ints(ids)
+	for _, id := range ids {
+		subs = append(subs, s.subs[id])
+	}
+	s.subsMu.Unlock()
+
+	changes := configChanges(old, next)
+	for _, fn := range subs {
+		fn(ConfigEvent{Source: source, Old: old.clone(), New: next.clone(), Changes: changes})
+	}
+}
+
+// Watch 监听配置文件，被外部修改时重新载入并通知订阅者，ctx 取消时返回
+func (s *configStore) Watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// 保存时先写临时文件再重命名，需要监听所在目录而不是文件本身
+	if err := w.Add(filepath.Dir(s.path())); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Base(ev.Name) == s.filename && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				timer.Reset(configFileDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			SysLogToFile(fmt.Sprintf("监听配置文件出错: %v", err))
+		case <-timer.C:
+			if err := s.Reload(); err != nil {
+				SysLogToFile(fmt.Sprintf("重新载入配置失败，继续使用当前配置: %v", err))
+			}
+		}
+	}
+}
+
+// onConfigChange 注册界面的配置变更通知，fn 在界面线程中执行，old 为变更前的配置
+func onConfigChange(fn func(old, c *Config)) {
+	appConfig.Subscribe(func(ev ConfigEvent) {
+		fyne.Do(func() { fn(ev.Old, ev.New) })
+	})
+}
+
+// syncEntry 配置字段变化时更新输入框，字段没有变化时保留输入框中尚未保存的内容
+func syncEntry(entry *widget.Entry, old, new string) {
+	if old != new {
+		entry.SetText(new)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-uposs/utils"
+)
+
+// useTestHome 将程序文件夹设为临时目录（等同于设置 GOUPOSS_HOME），使用固定的加密口令，
+// 保存 config 为 config.json（config 为 nil 时不保存），appConfig 从该文件载入
+func useTestHome(t *testing.T, config *Config) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(utils.HomeEnv, home)
+	if err := utils.InitPaths(""); err != nil {
+		t.Fatal(err)
+	}
+	setSecretKey(&secretKey{passphrase: "test-passphrase", source: "测试口令"})
+	appConfig = newConfigStore("config.json")
+	t.Cleanup(func() {
+		setSecretKey(nil)
+		appConfig = newConfigStore("config.json")
+	})
+
+	if config != nil {
+		if err := SaveConfig("config.json", config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+// testConfig 返回校验通过的配置，使用 dir 下的本地文件夹存储
+func testConfig(t *testing.T, dir string) *Config {
+	t.Helper()
+	for _, sub := range []string{"remote", "local", "store"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := &Config{
+		MachineCode:     "machine-01",
+		BucketName:      "uposs",
+		PublicUrl:       "https://oss.example.com",
+		Endpoint:        "oss.example.com:9000",
+		AccessKeyID:     "test-access-key",
+		SecretAccessKey: "test-secret-key",
+		StorageType:     storageLocal,
+		LocalStoreDir:   filepath.Join(dir, "store"),
+		RemoteFolder:    filepath.Join(dir, "remote"),
+		LocalFolder:     filepath.Join(dir, "local"),
+		PicCompress:     80,
+		PicWidth:        1000,
+		PicSize:         1024,
+		StartTime:       "2025.01.01",
+		EndTime:         "2025.01.31",
+		IOBuffer:        1024,
+		AutoInterval:    60,
+		SchedTimes:      1,
+		API1:            "http://127.0.0.1:3000/api1",
+		API2:            "http://127.0.0.1:3001/api2",
+		WebhookURL:      "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=test",
+		CleanStartTime:  "2025.01.01",
+		CleanEndTime:    "2025.01.31",
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("测试配置校验失败: %v", err)
+	}
+	return config
+}
+
+func TestConfigStoreUpdateNotifies(t *testing.T) {
+	home := useTestHome(t, nil)
+	config := testConfig(t, home)
+	if err := SaveConfig("config.json", config); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []ConfigEvent
+	unsubscribe := appConfig.Subscribe(func(ev ConfigEvent) { events = append(events, ev) })
+	defer unsubscribe()
+
+	if err := appConfig.Update(configSourceUI, func(c *Config) { c.PicCompress = 60 }, "pic_compress"); err != nil {
+		t.Fatal(err)
+	}
+	// 没有变化时不保存也不通知
+	if err := appConfig.Update(configSourceUI, func(c *Config) { c.PicCompress = 60 }, "pic_compress"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Source != configSourceUI || events[0].Old.PicCompress != 80 || events[0].New.PicCompress != 60 {
+		t.Fatalf("通知 = %+v，应只有一次 ui 来源的 80 → 60", events)
+	}
+
+	saved, err := LoadConfig("config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PicCompress != 60 {
+		t.Errorf("配置文件中的 pic_compress = %d，应为 60", saved.PicCompress)
+	}
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError 单个配置字段的校验错误，Field 为配置文件中的字段名
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors 配置校验发现的全部字段错误，按字段在配置文件中的顺序排列
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validationErrorsOf 返回 err 中的字段错误，err 不是校验错误时返回 nil
+func validationErrorsOf(err error) ValidationErrors {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return nil
+}
+
+// 各配置界面保存时校验的字段
+var (
+	ossConfigFields = []string{
+		"machine_code", "bucket_name", "storage_type", "endpoint", "public_url", "accessKeyID", "secretAccessKey",
+		"s3_region", "s3_bucket_lookup", "s3_session_token", "s3_ca_file", "s3_proxy", "s3_connect_timeout", "s3_read_timeout",
+		"s3_max_idle_conns", "s3_max_conns_per_host", "local_store_dir",
+		"webdav_url", "webdav_user", "webdav_password", "sftp_host", "sftp_user", "sftp_password", "sftp_key_file", "sftp_host_key",
+		"public_base_url", "upload_workers",
+	}
+	folderConfigFields = []string{"local_folder", "remote_folder", "io_buffer"}
+	picConfigFields    = []string{"pic_compress", "pic_width", "pic_size", "pic_workers", "pic_mem_budget"}
+	apiConfigFields    = []string{"api1", "api2", "webhook_url", "api1_rate_limit", "api2_rate_limit"}
+	autoConfigFields   = []string{"auto_interval", "auto_cron", "watch_debounce", "cron_timezone"}
+	schedConfigFields  = []string{"start_time", "end_time", "sched_times", "sched_cron", "sched_cron_days", "cron_timezone"}
+	cleanConfigFields  = []string{"clean_start_time", "clean_end_time"}
+)
+
+// storageConfigFields 存储类型和各存储的连接参数，配置方案修改其中任一字段时一起校验
+var storageConfigFields = []string{
+	"storage_type", "endpoint", "accessKeyID", "secretAccessKey", "s3_region", "s3_bucket_lookup", "s3_session_token",
+	"local_store_dir", "webdav_url", "webdav_user", "webdav_password",
+	"sftp_host", "sftp_user", "sftp_password", "sftp_key_file", "sftp_host_key", "sftp_dir",
+}
+
+// isStorageField 判断 field 是否为存储类型或存储连接参数
+func isStorageField(field string) bool {
+	for _, f := range storageConfigFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// runConfigFields 执行任务周期前校验的字段
+// 不包含 remote_folder 的存在性，网络共享暂时不可用时由复制阶段报错，不中止自动任务
+var runConfigFields = []string{
+	"machine_code", "bucket_name", "storage_type", "endpoint", "s3_bucket_lookup", "s3_ca_file", "s3_proxy", "local_store_dir", "webdav_url",
+	"sftp_host", "sftp_user", "sftp_password", "sftp_key_file", "sftp_host_key", "local_folder", "io_buffer",
+	"pic_compress", "pic_width", "pic_size", "pic_workers", "pic_mem_budget", "upload_workers",
+}
+
+// bucketNamePattern 存储桶名称规则：3-63 位小写字母、数字、点和短横线，以字母或数字开头和结尾
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// Validate 校验全部配置字段，没有错误时返回 nil，否则返回 ValidationErrors
+func (config *Config) Validate() error {
+	var errs ValidationErrors
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// OSS 配置
+	switch {
+	case config.MachineCode == "":
+		add("machine_code", "不能为空")
+	case strings.ContainsAny(config.MachineCode, "/\\ \t"):
+		add("machine_code", "不能包含斜杠或空白字符，%q 会作为对象路径前缀", config.MachineCode)
+	}
+	if msg := checkBucketName(config.BucketName); msg != "" {
+		add("bucket_name", "%s", msg)
+	}
+	if msg := checkURL(config.PublicUrl); msg != "" {
+		add("public_url", "%s", msg)
+	}
+
+	// 存储类型：minio 需要端点和密钥，local 需要存储根目录，webdav 需要共享地址，sftp 需要服务器、账号和指纹
+	switch config.storageType() {
+	case storageMinio:
+		if msg := checkEndpoint(config.Endpoint); msg != "" {
+			add("endpoint", "%s", msg)
+		}
+		if config.AccessKeyID == "" {
+			add("accessKeyID", "不能为空")
+		}
+		if config.SecretAccessKey == "" {
+			add("secretAccessKey", "不能为空")
+		}
+		config.validateS3Options(add)
+	case storageLocal:
+		if msg := checkFolder(config.LocalStoreDir, true); msg != "" {
+			add("local_store_dir", "%s", msg)
+		}
+	case storageWebDAV:
+		if config.WebDAVURL == "" {
+			add("webdav_url", "不能为空")
+		} else if msg := checkURL(config.WebDAVURL); msg != "" {
+			add("webdav_url", "%s", msg)
+		}
+	case storageSFTP:
+		if msg := checkEndpoint(config.SFTPHost); msg != "" {
+			add("sftp_host", "%s", msg)
+		}
+		if config.SFTPUser == "" {
+			add("sftp_user", "不能为空")
+		}
+		if config.SFTPPassword == "" && config.SFTPKeyFile == "" {
+			add("sftp_password", "与 sftp_key_file 至少设置一个")
+		}
+		if config.SFTPKeyFile != "" {
+			if _, err := os.Stat(config.SFTPKeyFile); err != nil {
+				add("sftp_key_file", "无法读取私钥文件 %s: %v", config.SFTPKeyFile, err)
+			}
+		}
+		switch {
+		case config.SFTPHostKey == "":
+			add("sftp_host_key", "不能为空，测试连接时会显示服务器的公钥指纹")
+		case !strings.HasPrefix(config.SFTPHostKey, "SHA256:"):
+			add("sftp_host_key", "%q 应为 SHA256: 开头的公钥指纹", config.SFTPHostKey)
+		}
+	default:
+		add("storage_type", "应为 %s、%s、%s 或 %s，当前为 %q", storageMinio, storageLocal, storageWebDAV, storageSFTP, config.StorageType)
+	}
+	if msg := checkURL(config.PublicBaseURL); msg != "" {
+		add("public_base_url", "%s", msg)
+	}
+
+	// 文件夹配置
+	if msg := checkFolder(config.LocalFolder, false); msg != "" {
+		add("local_folder", "%s", msg)
+	}
+	if msg := checkFolder(config.RemoteFolder, true); msg != "" {
+		add("remote_folder", "%s", msg)
+	}
+
+	// 图片配置
+	if config.PicCompress < 1 || config.PicCompress > 100 {
+		add("pic_compress", "应在 1 到 100 之间，当前为 %d", config.PicCompress)
+	}
+	if config.PicWidth < 1 || config.PicWidth > 10000 {
+		add("pic_width", "应在 1 到 10000 之间，当前为 %d", config.PicWidth)
+	}
+	if config.PicSize < 1 {
+		add("pic_size", "应大于 0，当前为 %d", config.PicSize)
+	}
+	if config.PicWorkers < 0 || config.PicWorkers > 64 {
+		add("pic_workers", "应在 0 到 64 之间（0 使用 CPU 核数），当前为 %d", config.PicWorkers)
+	}
+	if config.PicMemBudget != 0 && config.PicMemBudget < 64 {
+		add("pic_mem_budget", "应为 0（使用默认值）或不小于 64，当前为 %d", config.PicMemBudget)
+	}
+
+	// 计划任务日期范围
+	start, startOK := checkDate(config.StartTime, "start_time", add)
+	end, endOK := checkDate(config.EndTime, "end_time", add)
+	if startOK && endOK && end.Before(start) {
+		add("end_time", "不能早于 start_time %s", config.StartTime)
+	}
+
+	if config.IOBuffer <= 0 {
+		add("io_buffer", "应大于 0，当前为 %d", config.IOBuffer)
+	}
+	if config.AutoInterval <= 0 {
+		add("auto_interval", "应大于 0，当前为 %d", config.AutoInterval)
+	}
+	if config.SchedTimes <= 0 {
+		add("sched_times", "应大于 0，当前为 %d", config.SchedTimes)
+	}
+	if config.WatchDebounce < 0 {
+		add("watch_debounce", "不能小于 0，当前为 %d", config.WatchDebounce)
+	}
+
+	// 定时配置
+	if config.CronTimezone != "" {
+		if _, err := time.LoadLocation(config.CronTimezone); err != nil {
+			add("cron_timezone", "无效的时区 %s", config.CronTimezone)
+		}
+	}
+	if config.AutoCron != "" {
+		if _, err := parseCron(config.AutoCron, config.CronTimezone); err != nil {
+			add("auto_cron", "%v", err)
+		}
+	}
+	if config.SchedCron != "" {
+		if _, err := parseCron(config.SchedCron, config.CronTimezone); err != nil {
+			add("sched_cron", "%v", err)
+		}
+	}
+	if config.SchedCronDays < 0 {
+		add("sched_cron_days", "不能小于 0，当前为 %d", config.SchedCronDays)
+	}
+
+	// API 配置，未配置的接口不校验
+	for _, f := range []struct{ name, value string }{
+		{"api1", config.API1}, {"api2", config.API2}, {"webhook_url", config.WebhookURL},
+	} {
+		if msg := checkURL(f.value); msg != "" {
+			add(f.name, "%s", msg)
+		}
+	}
+	if config.UploadWorkers < 0 || config.UploadWorkers > 64 {
+		add("upload_workers", "应在 0 到 64 之间（0 使用默认值），当前为 %d", config.UploadWorkers)
+	}
+	if config.API1RateLimit < 0 {
+		add("api1_rate_limit", "不能小于 0")
+	}
+	if config.API2RateLimit < 0 {
+		add("api2_rate_limit", "不能小于 0")
+	}
+
+	// 清理日期范围
+	cleanStart, cleanStartOK := checkDate(config.CleanStartTime, "clean_start_time", add)
+	cleanEnd, cleanEndOK := checkDate(config.CleanEndTime, "clean_end_time", add)
+	if cleanStartOK && cleanEndOK && cleanEnd.Before(cleanStart) {
+		add("clean_end_time", "不能早于 clean_start_time %s", config.CleanStartTime)
+	}
+
+	// 配置方案
+	config.validateProfiles(add)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateS3Options 校验 MinIO 客户端的区域、地址风格、证书、代理、超时和连接池参数
+func (config *Config) validateS3Options(add func(field, format string, args ...interface{})) {
+	if strings.ContainsAny(config.S3Region, "/ \t") {
+		add("s3_region", "%q 不能包含斜杠或空白字符", config.S3Region)
+	}
+	switch config.S3BucketLookup {
+	case "", s3LookupAuto, s3LookupPath, s3LookupVirtualHost:
+	default:
+		add("s3_bucket_lookup", "应为 %s、%s 或 %s，当前为 %q", s3LookupAuto, s3LookupPath, s3LookupVirtualHost, config.S3BucketLookup)
+	}
+	if config.S3SessionToken != "" && (config.AccessKeyID == "" || config.SecretAccessKey == "") {
+		add("s3_session_token", "需要与临时凭证的 accessKeyID、secretAccessKey 一起使用")
+	}
+	if config.S3CAFile != "" {
+		if _, err := loadCAFile(config.S3CAFile); err != nil {
+			add("s3_ca_file", "%v", err)
+		}
+	}
+	if config.S3Proxy != "" {
+		u, err := url.Parse(config.S3Proxy)
+		switch {
+		case err != nil:
+			add("s3_proxy", "%q 不是有效的 URL", config.S3Proxy)
+		case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5":
+			add("s3_proxy", "%q 应以 http://、https:// 或 socks5:// 开头", config.S3Proxy)
+		case u.Host == "":
+			add("s3_proxy", "%q 缺少主机名", config.S3Proxy)
+		}
+	}
+	for _, f := range []struct {
+		name  string
+		value int
+	}{{"s3_connect_timeout", config.S3ConnectTimeout}, {"s3_read_timeout", config.S3ReadTimeout}} {
+		if f.value < 0 || f.value > maxS3Timeout {
+			add(f.name, "应在 0 到 %d 之间（0 使用默认值），当前为 %d", maxS3Timeout, f.value)
+		}
+	}
+	if config.S3MaxIdleConns < 0 || config.S3MaxIdleConns > maxS3Conns {
+		add("s3_max_idle_conns", "应在 0 到 %d 之间（0 使用默认值），当前为 %d", maxS3Conns, config.S3MaxIdleConns)
+	}
+	if config.S3MaxConnsPerHost < 0 || config.S3MaxConnsPerHost > maxS3Conns {
+		add("s3_max_conns_per_host", "应在 0 到 %d 之间（0 表示不限制），当前为 %d", maxS3Conns, config.S3MaxConnsPerHost)
+	}
+}
+
+// ValidateFields 只返回 fields 中字段的校验错误，用于各配置界面保存时不受其他界面字段的影响
+func (config *Config) ValidateFields(fields ...string) error {
+	all := validationErrorsOf(config.Validate())
+	var errs ValidationErrors
+	for _, e := range all {
+		for _, f := range fields {
+			if e.Field == f {
+				errs = append(errs, e)
+				break
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// updateConfig 界面修改配置：通过 appConfig 校验 fields 字段并保存，成功后同样修改界面持有的 config
+// apply 只修改本界面的字段，其他字段保持 appConfig 中的最新值；校验或保存失败时 config 保持不变
+func updateConfig(config *Config, apply func(c *Config), fields ...string) error {
+	if err := appConfig.Update(configSourceUI, apply, fields...); err != nil {
+		return err
+	}
+	if config != nil {
+		apply(config)
+	}
+	return nil
+}
+
+// configSaveError 返回 updateConfig 失败时在界面显示的错误描述
+func configSaveError(err error) string {
+	if errs := validationErrorsOf(err); errs != nil {
+		return fmt.Sprintf("配置校验失败，未保存:\n%v", errs)
+	}
+	return fmt.Sprintf("保存配置失败: %v", err)
+}
+
+// checkBucketName 按 S3 存储桶命名规则检查名称，返回错误描述
+func checkBucketName(name string) string {
+	switch {
+	case name == "":
+		return "不能为空"
+	case !bucketNamePattern.MatchString(name):
+		return fmt.Sprintf("%q 不符合存储桶命名规则：3-63 位小写字母、数字、点和短横线，以字母或数字开头和结尾", name)
+	case strings.Contains(name, ".."):
+		return fmt.Sprintf("%q 不能包含连续的点", name)
+	case net.ParseIP(name) != nil:
+		return fmt.Sprintf("%q 不能是 IP 地址格式", name)
+	}
+	return ""
+}
+
+// checkEndpoint 检查端点格式：host 或 host:port，不带协议和路径
+func checkEndpoint(endpoint string) string {
+	if endpoint == "" {
+		return "不能为空"
+	}
+	if strings.Contains(endpoint, "://") {
+		return fmt.Sprintf("%q 不能包含协议前缀，是否使用 SSL 由 useSSL 决定", endpoint)
+	}
+	if strings.ContainsAny(endpoint, "/ ") {
+		return fmt.Sprintf("%q 只能是 host 或 host:port，不能包含路径或空格", endpoint)
+	}
+	host := endpoint
+	if strings.Contains(endpoint, ":") {
+		h, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return fmt.Sprintf("%q 格式错误: %v", endpoint, err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Sprintf("%q 的端口应在 1 到 65535 之间", endpoint)
+		}
+		host = h
+	}
+	if host == "" {
+		return fmt.Sprintf("%q 缺少主机名", endpoint)
+	}
+	return ""
+}
+
+// checkURL 检查 http/https 地址格式，空值不检查
+func checkURL(value string) string {
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Sprintf("%q 不是有效的 URL", value)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("%q 应以 http:// 或 https:// 开头", value)
+	}
+	if u.Host == "" {
+		return fmt.Sprintf("%q 缺少主机名", value)
+	}
+	return ""
+}
+
+// checkFolder 检查文件夹路径：不能为空，已存在时必须是文件夹，mustExist 为 true 时必须存在
+func checkFolder(path string, mustExist bool) string {
+	if path == "" {
+		return "不能为空"
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if mustExist {
+				return fmt.Sprintf("文件夹 %s 不存在", path)
+			}
+			return ""
+		}
+		return fmt.Sprintf("无法访问 %s: %v", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%s 不是文件夹", path)
+	}
+	return ""
+}
+
+// checkDate 检查 yyyy.mm.dd 格式的日期，空值不检查；返回解析结果和是否有效
+func checkDate(value, field string, add func(field, format string, args ...interface{})) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006.01.02", value)
+	if err != nil {
+		add(field, "%q 不是有效的日期，格式应为 2006.01.02", value)
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// TestConfigExampleValid data/config.example.json 复制为 config.json 并创建源文件夹后应能通过校验
+func TestConfigExampleValid(t *testing.T) {
+	data, err := os.ReadFile("data/config.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, version, err := migrateConfigData(data)
+	if err != nil || version != configSchemaVersion {
+		t.Fatalf("示例配置版本 = %d, %v，应为当前版本 %d", version, err, configSchemaVersion)
+	}
+	config := &Config{}
+	if err := json.Unmarshal(migrated, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Profiles) == 0 {
+		t.Fatal("示例配置应包含 profiles 示例")
+	}
+
+	// 示例中的文件夹为相对路径，在临时目录中创建源文件夹
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	for _, dir := range []string{config.RemoteFolder, config.Profiles[0].RemoteFolder} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("示例配置校验失败: %v", err)
+	}
+}
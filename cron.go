@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Windows 没有系统时区数据库，内嵌时区数据供 cron_timezone 使用
+
+	"go-uposs/utils"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 定时任务名称，用于记录上次执行时间
+const (
+	cronTaskAuto  = "auto"
+	cronTaskSched = "sched"
+)
+
+const (
+	cronCheckInterval = 30 * time.Second // 等待期间检查系统时间的间隔，系统休眠唤醒后最多延迟该时长
+	cronMissGrace     = time.Minute      // 超过执行时间多久视为错过（系统休眠）
+)
+
+// cronParser 支持可选的秒字段：6 段 "秒 分 时 日 月 周" 或 5 段 "分 时 日 月 周"，以及 @daily 等描述符
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseCron 解析 cron 表达式，tz 为空时使用本地时区；表达式自带 CRON_TZ= 前缀时以前缀为准
+func parseCron(spec, tz string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("cron 表达式为空")
+	}
+	if tz != "" && !strings.HasPrefix(spec, "CRON_TZ=") && !strings.HasPrefix(spec, "TZ=") {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("无效的时区 %s: %v", tz, err)
+		}
+		spec = fmt.Sprintf("CRON_TZ=%s %s", tz, spec)
+	}
+
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 cron 表达式 %q: %v", spec, err)
+	}
+	return schedule, nil
+}
+
+// cronPreview 返回从现在开始接下来 n 次执行时间的文字描述，用于界面预览
+func cronPreview(spec, tz string, n int) string {
+	schedule, err := parseCron(spec, tz)
+	if err != nil {
+		return err.Error()
+	}
+
+	var runs []string
+	t := time.Now()
+	for i := 0; i < n; i++ {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t.Format("01.02 15:04:05"))
+	}
+	if len(runs) == 0 {
+		return "没有可执行的时间"
+	}
+	return "下次执行: " + strings.Join(runs, "，")
+}
+
+// cronWaitUntil 等待到 t，按系统时间定期检查，系统休眠唤醒后不会多等一个完整的计时周期
+func cronWaitUntil(ctx context.Context, t time.Time) error {
+	for {
+		wait := time.Until(t)
+		if wait <= 0 {
+			return nil
+		}
+		if wait > cronCheckInterval {
+			wait = cronCheckInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// waitCron 等待 schedule 的下一次执行时间
+// 系统休眠导致错过执行时间时，catchUp 为 true 立即补执行一次，否则跳过并等待下一次
+func waitCron(ctx context.Context, schedule cron.Schedule, catchUp bool, logToFile func(string) error) error {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("cron 表达式没有可执行的时间")
+		}
+		logToFile(fmt.Sprintf("下一次执行时间: %s", next.Format("2006.01.02 15:04:05 MST")))
+
+		if err := cronWaitUntil(ctx, next); err != nil {
+			return err
+		}
+
+		late := time.Since(next)
+		if late <= cronMissGrace {
+			return nil
+		}
+		if catchUp {
+			logToFile(fmt.Sprintf("错过了 %s 的执行时间（系统休眠），立即补执行", next.Format("2006.01.02 15:04:05")))
+			return nil
+		}
+		logToFile(fmt.Sprintf("错过了 %s 的执行时间（系统休眠），跳过本次执行", next.Format("2006.01.02 15:04:05")))
+	}
+}
+
+// waitCronStart 任务启动时调用：根据上次执行时间判断程序未运行期间是否错过了执行
+// 错过且 catchUp 为 true 时立即返回补执行，否则等待下一次执行时间
+func waitCronStart(ctx context.Context, task string, schedule cron.Schedule, catchUp bool, logToFile func(string) error) error {
+	lastRun, err := utils.GetLastScheduledRun(task)
+	if err != nil {
+		logToFile(fmt.Sprintf("查询上次执行时间失败: %v", err))
+	} else if !lastRun.IsZero() {
+		missed := schedule.Next(lastRun)
+		if !missed.IsZero() && missed.Before(time.Now()) {
+			if catchUp {
+				logToFile(fmt.Sprintf("上次执行于 %s，错过了 %s 的执行时间，立即补执行",
+					lastRun.Format("2006.01.02 15:04:05"), missed.Format("2006.01.02 15:04:05")))
+				return nil
+			}
+			logToFile(fmt.Sprintf("上次执行于 %s，错过了 %s 的执行时间，跳过",
+				lastRun.Format("2006.01.02 15:04:05"), missed.Format("2006.01.02 15:04:05")))
+		}
+	}
+	return waitCron(ctx, schedule, catchUp, logToFile)
+}
+
+// recordCronRun 记录任务本次执行时间
+func recordCronRun(task string, logToFile func(string) error) {
+	if err := utils.SetLastScheduledRun(task, time.Now()); err != nil {
+		logToFile(fmt.Sprintf("记录执行时间失败: %v", err))
+	}
+}
+
+// schedCronDateRange 返回按 cron 触发计划任务时处理的日期范围：触发时间之前的 days 天，不含当天
+func schedCronDateRange(trigger time.Time, days int) (string, string) {
+	return trigger.AddDate(0, 0, -days).Format("2006.01.02"), trigger.AddDate(0, 0, -1).Format("2006.01.02")
+}
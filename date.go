@@ -30,15 +30,13 @@ func GetEndTime(config *Config) string {
 // SetStartTime 更新开始时间并更新 UI 和配置
 func SetStartTime(date string, dateLabel *widget.Label, config *Config) {
 	dateLabel.SetText(date)
-	config.StartTime = date                                  // 只更新开始时间
-	config.UpdateTimeRange(config.StartTime, config.EndTime) // 更新配置文件中的时间范围
+	config.UpdateTimeRange(date, config.EndTime) // 只更新开始时间，保存成功后 config 同步更新
 }
 
 // SetEndTime 更新结束时间并更新 UI 和配置
 func SetEndTime(date string, dateLabel *widget.Label, config *Config) {
 	dateLabel.SetText(date)
-	config.EndTime = date                                    // 只更新结束时间
-	config.UpdateTimeRange(config.StartTime, config.EndTime) // 更新配置文件中的时间范围
+	config.UpdateTimeRange(config.StartTime, date) // 只更新结束时间，保存成功后 config 同步更新
 }
 
 // GetCleanStartTime 返回配置文件中的清理开始时间
@@ -60,15 +58,13 @@ func GetCleanEndTime(config *Config) string {
 // SetCleanStartTime 更新清理开始时间并更新 UI 和配置
 func SetCleanStartTime(date string, dateLabel *widget.Label, config *Config) {
 	dateLabel.SetText(date)
-	config.CleanStartTime = date                                            // 只更新清理开始时间
-	config.UpdateCleanTimeRange(config.CleanStartTime, config.CleanEndTime) // 更新配置文件中的清理时间范围
+	config.UpdateCleanTimeRange(date, config.CleanEndTime) // 只更新清理开始时间，保存成功后 config 同步更新
 }
 
 // SetCleanEndTime 更新清理结束时间并更新 UI 和配置
 func SetCleanEndTime(date string, dateLabel *widget.Label, config *Config) {
 	dateLabel.SetText(date)
-	config.CleanEndTime = date                                              // 只更新清理结束时间
-	config.UpdateCleanTimeRange(config.CleanStartTime, config.CleanEndTime) // 更新配置文件中的清理时间范围
+	config.UpdateCleanTimeRange(config.CleanStartTime, date) // 只更新清理结束时间，保存成功后 config 同步更新
 }
 
 // CreateDateUI 创建日期选择 UI
@@ -147,6 +143,12 @@ func CreateDateUI(win fyne.Window, config *Config) fyne.CanvasObject {
 		endInfoRow,
 	)
 
+	// 配置被其他界面、配置文件或历史版本修改时同步显示的日期
+	onConfigChange(func(_, c *Config) {
+		setDateButtons(GetStartTime(c), startTimeLabel, yearButton, monthButton, dayButton)
+		setDateButtons(GetEndTime(c), endTimeLabel, endYearButton, endMonthButton, endDayButton)
+	})
+
 	return rightContent
 }
 
@@ -220,6 +222,12 @@ func CreateCleanDateUI(win fyne.Window, config *Config) fyne.CanvasObject {
 		widget.NewLabel("结束时间："), endTimeLabel,
 	)
 
+	// 配置被其他界面、配置文件或历史版本修改时同步显示的日期
+	onConfigChange(func(_, c *Config) {
+		setDateButtons(GetCleanStartTime(c), startTimeLabel, yearButton, monthButton, dayButton)
+		setDateButtons(GetCleanEndTime(c), endTimeLabel, endYearButton, endMonthButton, endDayButton)
+	})
+
 	return container.NewVBox(
 		startInfoRow,
 		endInfoRow,
@@ -318,6 +326,18 @@ func updateSelectedDate(dateLabel *widget.Label, yearButton, monthButton, dayBut
 	dateLabel.SetText(formatDate(year, month, day))
 }
 
+// setDateButtons 按 YYYY.MM.DD 格式的日期设置年月日按钮和日期标签，无法解析时不修改
+func setDateButtons(date string, dateLabel *widget.Label, yearButton, monthButton, dayButton *widget.Button) {
+	t, err := time.Parse("2006.01.02", date)
+	if err != nil {
+		return
+	}
+	yearButton.SetText(strconv.Itoa(t.Year()))
+	monthButton.SetText(fmt.Sprintf("%02d", t.Month()))
+	dayButton.SetText(fmt.Sprintf("%02d", t.Day()))
+	dateLabel.SetText(date)
+}
+
 // 格式化日期为 YYYY.MM.DD
 func formatDate(year, month, day int) string {
 	return fmt.Sprintf("%d.%02d.%02d", year, month, day) // 保证月、日有前导零
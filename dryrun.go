@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go-uposs/pipeline"
+)
+
+// dryRunPlanKey 模拟运行计划在 RunContext 中的键
+const dryRunPlanKey = "dryRunPlan"
+
+// dryRunCopy 模拟运行中将要复制的文件
+type dryRunCopy struct {
+	src  string      // 远程源路径
+	dst  string      // 复制后的本地路径
+	info os.FileInfo // 源文件信息
+}
+
+// dryRunPlan 模拟运行中各阶段记录的操作，供后续阶段使用，数量统计记录在 runStats 中
+// 复制阶段不会真正复制文件，压缩和上传阶段通过 copies 把将要复制的文件当作已在本地处理
+type dryRunPlan struct {
+	mu         sync.Mutex
+	copies     []dryRunCopy
+	compressed map[string]int64 // 本地路径 → 压缩后的大小（字节）
+}
+
+// dryRunFor 返回任务类型是否开启了模拟运行
+func (c *Config) dryRunFor(task pipeline.TaskType) bool {
+	if task == pipeline.TaskSched {
+		return c.SchedDryRun
+	}
+	return c.AutoDryRun
+}
+
+// dryRunPlanOf 返回本次运行的模拟运行计划
+func dryRunPlanOf(rc *pipeline.RunContext) *dryRunPlan {
+	return rc.LoadOrStore(dryRunPlanKey, &dryRunPlan{compressed: make(map[string]int64)}).(*dryRunPlan)
+}
+
+// dryRunLog 输出带 [模拟] 前缀的日志
+func dryRunLog(rc *pipeline.RunContext, format string, args ...interface{}) {
+	rc.Log("[模拟] " + fmt.Sprintf(format, args...))
+}
+
+func (p *dryRunPlan) addCopy(src, dst string, info os.FileInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.copies = append(p.copies, dryRunCopy{src: src, dst: dst, info: info})
+}
+
+func (p *dryRunPlan) plannedCopies() []dryRunCopy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]dryRunCopy(nil), p.copies...)
+}
+
+// sourceOf 返回本地路径对应的将要复制的远程源路径，不是将要复制的文件时返回本地路径本身
+func (p *dryRunPlan) sourceOf(localPath string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.copies {
+		if c.dst == localPath {
+			return c.src
+		}
+	}
+	return localPath
+}
+
+func (p *dryRunPlan) setCompressed(localPath string, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.compressed[localPath] = size
+}
+
+// compressedSize 返回压缩阶段估算的大小，没有压缩记录时返回 ok=false
+func (p *dryRunPlan) compressedSize(localPath string) (int64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	size, ok := p.compressed[localPath]
+	return size, ok
+}
+
+// isImageFile 判断文件名是否为流水线处理的图片格式（JPEG、PNG、GIF）
+func isImageFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".jpeg" || ext == ".jpg" || ext == ".png" || ext == ".gif"
+}
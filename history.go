@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"go-uposs/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// historyLimit 执行记录界面最多显示的条数
+const historyLimit = 500
+
+// historyColumns 执行记录表格的列标题和宽度
+var historyColumns = []struct {
+	title string
+	width float32
+}{
+	{"开始时间", 150}, {"任务", 50}, {"方案", 70}, {"触发", 50}, {"状态", 60}, {"耗时", 70},
+	{"扫描", 50}, {"复制", 50}, {"压缩", 50}, {"上传", 50}, {"跳过", 50}, {"推送", 50}, {"删除", 50},
+	{"复制MB", 70}, {"上传MB", 70}, {"错误", 50},
+}
+
+// historyRanges 执行记录界面的时间范围选项
+var historyRanges = map[string]time.Duration{
+	"最近 24 小时": 24 * time.Hour,
+	"最近 7 天":   7 * 24 * time.Hour,
+	"最近 30 天":  30 * 24 * time.Hour,
+	"全部":       0,
+}
+
+// historyTasks 执行记录界面的任务类型选项
+var historyTasks = map[string]string{
+	"全部任务": "",
+	"自动任务": "auto",
+	"计划任务": "sched",
+}
+
+// historyCell 返回执行记录第 col 列的显示内容
+func historyCell(r *utils.RunRecord, col int) string {
+	mb := func(n int64) string { return fmt.Sprintf("%.2f", float64(n)/(1024*1024)) }
+
+	switch col {
+	case 0:
+		return r.StartedAt.Format("2006.01.02 15:04:05")
+	case 1:
+		if r.Task == "sched" {
+			return "计划"
+		}
+		return "自动"
+	case 2:
+		return profileLabel(r.Profile)
+	case 3:
+		return runTriggerName(r.Trigger)
+	case 4:
+		if r.DryRun {
+			return "模拟" + runStatusName(r.Status)
+		}
+		return runStatusName(r.Status)
+	case 5:
+		if r.FinishedAt.IsZero() {
+			return "-"
+		}
+		return r.Duration().Round(time.Second).String()
+	case 6:
+		return fmt.Sprint(r.FilesScanned)
+	case 7:
+		return fmt.Sprint(r.FilesCopied)
+	case 8:
+		return fmt.Sprint(r.FilesCompressed)
+	case 9:
+		return fmt.Sprint(r.FilesUploaded)
+	case 10:
+		return fmt.Sprint(r.FilesSkipped)
+	case 11:
+		return fmt.Sprint(r.FilesPushed)
+	case 12:
+		return fmt.Sprint(r.FilesDeleted)
+	case 13:
+		return mb(r.BytesCopied)
+	case 14:
+		return mb(r.BytesUploaded)
+	case 15:
+		return fmt.Sprint(r.Errors)
+	}
+	return ""
+}
+
+// refreshHistory 刷新执行记录界面，切换到执行记录标签页时调用
+var refreshHistory func()
+
+// createHistoryUI 创建执行记录界面：按任务类型和时间范围查看每个周期的统计，并导出为 JSON
+func createHistoryUI(win fyne.Window) fyne.CanvasObject {
+	var runs []utils.RunRecord
+
+	// 选中记录的错误详情
+	detailLabel := widget.NewLabel("选择一条记录查看错误详情")
+	detailLabel.Wrapping = fyne.TextWrapWord
+
+	table := widget.NewTable(
+		func() (int, int) { return len(runs) + 1, len(historyColumns) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(historyColumns[id.Col].title)
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			label.SetText(historyCell(&runs[id.Row-1], id.Col))
+		},
+	)
+	for i, c := range historyColumns {
+		table.SetColumnWidth(i, c.width)
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		if id.Row == 0 || id.Row > len(runs) {
+			return
+		}
+		r := runs[id.Row-1]
+		if r.LastError == "" {
+			detailLabel.SetText(fmt.Sprintf("%s 开始的周期没有错误", r.StartedAt.Format("2006.01.02 15:04:05")))
+			return
+		}
+		detailLabel.SetText(fmt.Sprintf("%s 开始的周期错误: %s", r.StartedAt.Format("2006.01.02 15:04:05"), r.LastError))
+	}
+
+	taskSelect := widget.NewSelect([]string{"全部任务", "自动任务", "计划任务"}, nil)
+	taskSelect.SetSelected("全部任务")
+	rangeSelect := widget.NewSelect([]string{"最近 24 小时", "最近 7 天", "最近 30 天", "全部"}, nil)
+	rangeSelect.SetSelected("最近 7 天")
+
+	// query 按当前筛选条件查询执行记录
+	query := func() ([]utils.RunRecord, error) {
+		var since time.Time
+		if d := historyRanges[rangeSelect.Selected]; d > 0 {
+			since = time.Now().Add(-d)
+		}
+		return utils.ListRuns(historyTasks[taskSelect.Selected], since, historyLimit)
+	}
+
+	refresh := func() {
+		result, err := query()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("查询执行记录失败: %v", err), win)
+			return
+		}
+		runs = result
+		table.UnselectAll()
+		table.Refresh()
+		detailLabel.SetText(fmt.Sprintf("共 %d 条记录，选择一条记录查看错误详情", len(runs)))
+	}
+	taskSelect.OnChanged = func(string) { refresh() }
+	rangeSelect.OnChanged = func(string) { refresh() }
+
+	refreshButton := widget.NewButton("刷新", refresh)
+
+	exportButton := widget.NewButton("导出 JSON", func() {
+		result, err := query()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("查询执行记录失败: %v", err), win)
+			return
+		}
+		path := filepath.Join(utils.GoupossPath, fmt.Sprintf("runs_%s.json", time.Now().Format("20060102_150405")))
+		if err := exportRunsJSON(path, result); err != nil {
+			dialog.ShowError(fmt.Errorf("导出执行记录失败: %v", err), win)
+			return
+		}
+		dialog.ShowInformation("导出成功", fmt.Sprintf("已导出 %d 条执行记录到:\n%s", len(result), path), win)
+	})
+
+	toolbar := container.NewHBox(
+		container.NewGridWrap(fyne.NewSize(120, utils.LEBHeight), taskSelect),
+		container.NewGridWrap(fyne.NewSize(140, utils.LEBHeight), rangeSelect),
+		container.NewGridWrap(fyne.NewSize(100, utils.LEBHeight), refreshButton),
+		container.NewGridWrap(fyne.NewSize(120, utils.LEBHeight), exportButton),
+	)
+
+	refresh()
+	refreshHistory = refresh
+
+	return container.NewBorder(toolbar, detailLabel, nil, nil,
+		container.NewGridWrap(fyne.NewSize(1000, 520), table))
+}
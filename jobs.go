@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-uposs/pipeline"
+	"go-uposs/utils"
+)
+
+// recordJobFailure 记录文件处理失败，失败次数达到 utils.MaxJobAttempts 时将文件移入隔离目录
+func recordJobFailure(rc *pipeline.RunContext, path string, cause error) {
+	fileName := filepath.Base(path)
+	copyDir := filepath.Base(filepath.Dir(path))
+	runStatsOf(rc).addError()
+
+	state, err := utils.MarkJobFailed(rc.Profile, fileName, copyDir, path, cause)
+	if err != nil {
+		rc.Log(fmt.Sprintf("记录文件处理失败状态失败: %v", err))
+		return
+	}
+	if state != utils.JobQuarantined {
+		return
+	}
+
+	// 按配置方案和日期文件夹存放隔离文件，避免同名冲突
+	dst := filepath.Join(utils.QuarantinePath, rc.Profile, copyDir, fileName)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		rc.Log(fmt.Sprintf("创建隔离目录失败❌😅: %v", err))
+		return
+	}
+	if err := os.Rename(path, dst); err != nil {
+		rc.Log(fmt.Sprintf("移动文件到隔离目录失败❌😅: %s, 错误: %v", path, err))
+		return
+	}
+	if err := utils.UpdateJobLocalPath(rc.Profile, fileName, dst); err != nil {
+		rc.Log(fmt.Sprintf("更新隔离文件路径失败: %v", err))
+	}
+	rc.Log(fmt.Sprintf("文件 %s 已失败 %d 次，移入隔离目录: %s", fileName, utils.MaxJobAttempts, dst))
+}
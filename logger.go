@@ -20,6 +20,13 @@ var (
 
 	// 日志写入互斥锁
 	logMutex sync.Mutex
+
+	// headlessMode 为 true 时不更新 Fyne 控件，日志改为输出到标准输出（命令行模式）
+	headlessMode bool
+
+	// 配置方案的任务日志记录器，按 任务日志目录/方案名 缓存
+	profileLoggers   = map[string]*Logger{}
+	profileLoggersMu sync.Mutex
 )
 
 // Logger 用于处理日志的记录
@@ -115,6 +122,10 @@ func logToUIAndFile(logger *Logger, logWidget *widget.Entry, message string, tas
 		return fmt.Errorf("日志记录器未初始化")
 	}
 
+	// 多个上传协程会同时写日志，串行化文件写入
+	logMutex.Lock()
+	defer logMutex.Unlock()
+
 	// 获取当前日期，并检查是否需要更换日志文件
 	currentDate := time.Now().Format("2006.01.02")
 	if currentDate != logger.Date {
@@ -139,6 +150,12 @@ func logToUIAndFile(logger *Logger, logWidget *widget.Entry, message string, tas
 		return fmt.Errorf("写入日志失败: %v", err)
 	}
 
+	// 命令行模式下没有界面，直接输出到标准输出
+	if headlessMode || logWidget == nil {
+		fmt.Println(logMessage)
+		return nil
+	}
+
 	// 在主线程上更新 UI
 	fyne.Do(func() {
 		// 获取当前日志文本框的内容
@@ -173,6 +190,40 @@ func SchedLogToFile(message string) error {
 	return logToUIAndFile(schedLogger, schedLogText, message, 17)
 }
 
+// profileLogger 返回配置方案 profile 的任务日志记录器，日志保存在任务日志目录下以方案名命名的子目录中
+func profileLogger(base *Logger, profile string) *Logger {
+	if base == nil {
+		return nil
+	}
+	logDir := filepath.Join(base.LogDir, profile)
+
+	profileLoggersMu.Lock()
+	defer profileLoggersMu.Unlock()
+	if logger, ok := profileLoggers[logDir]; ok {
+		return logger
+	}
+	logger := NewLogger(logDir)
+	profileLoggers[logDir] = logger
+	return logger
+}
+
+// AutoProfileLogToFile 向配置方案 profile 的自动任务日志和 UI 日志写入消息，UI 日志带有 [方案名] 前缀
+// 默认方案（profile 为空）与 AutoLogToFile 相同
+func AutoProfileLogToFile(profile, message string) error {
+	if profile == "" {
+		return AutoLogToFile(message)
+	}
+	return logToUIAndFile(profileLogger(autoLogger, profile), autoLogText, fmt.Sprintf("[%s] %s", profile, message), 20)
+}
+
+// SchedProfileLogToFile 向配置方案 profile 的计划任务日志和 UI 日志写入消息，默认方案与 SchedLogToFile 相同
+func SchedProfileLogToFile(profile, message string) error {
+	if profile == "" {
+		return SchedLogToFile(message)
+	}
+	return logToUIAndFile(profileLogger(schedLogger, profile), schedLogText, fmt.Sprintf("[%s] %s", profile, message), 17)
+}
+
 // ...
 // ...
 // ...
@@ -184,6 +235,12 @@ func SchedLogToFile(message string) error {
 //	tag       - 日志来源标签，例如 "[OSS配置]"
 //	message   - 实际日志内容（不需要时间戳，函数内部自动添加）
 func updateLog(logWidget *widget.Entry, tag, message string) {
+	// 命令行模式或控件尚未创建时，只写入系统日志
+	if headlessMode || logWidget == nil {
+		SysLogToFile(fmt.Sprintf("%s %s", tag, message))
+		return
+	}
+
 	fyne.Do(func() {
 		// 构建带时间戳和标签的日志消息
 		timestamp := time.Now().Format("2006-01-02 15:04:05")
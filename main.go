@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -12,7 +13,6 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
-	"golang.org/x/sys/windows/registry"
 
 	"go-uposs/utils"
 )
@@ -50,7 +50,7 @@ func initDatabase() error {
 // ShowPasswordDialogIfNeeded 根据配置决定是否显示密码对话框
 func ShowPasswordDialogIfNeeded(window fyne.Window, config *Config) {
 	// 检查是否启用了界面锁定
-	if config == nil || config.LockUI != "true" {
+	if config == nil || !config.LockUI {
 		// 如果未启用，直接显示窗口
 		window.Show()
 		return
@@ -71,8 +71,27 @@ func ShowPasswordDialogIfNeeded(window fyne.Window, config *Config) {
 }
 
 func main() {
+	// 解析命令之前的全局参数，确定程序文件夹
+	dataDir, args, code := parseGlobalFlags(os.Args[1:])
+	if code >= 0 {
+		os.Exit(code)
+	}
+	if err := utils.InitPaths(dataDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInitFailed)
+	}
+	initStdLog()
+	if s := overridesSummary(); s != "" {
+		MainLogToFile("以下配置字段被环境变量或命令行覆盖: " + s)
+	}
+
+	// 带命令参数时以无界面的命令行模式运行，不创建 Fyne 窗口
+	if len(args) > 0 {
+		os.Exit(runCLI(args))
+	}
 
-	// 绑定受监听端口
+	// 绑定受监听端口，设置了配置包口令时同时接收远程下发的配置包
+	registerConfigRemoteHandler()
 	utils.ListenPort()
 
 	// 初始化数据库
@@ -82,15 +101,24 @@ func main() {
 	}
 	defer utils.CloseDB()
 
+	// 后台补推 API2 推送待办
+	go runOutboxWorker(context.Background())
+
 	// 加载配置
-	config, err := LoadConfig("config.json") // 使用相对路径
+	config, err := appConfig.Load()
 	if err != nil {
 		MainLogToFile(fmt.Sprintf("初始化配置失败: %v", err))
 		log.Fatalf("初始化配置失败: %v", err)
 	}
 
+	// 校验配置，有问题时记录日志并在界面提示，不阻止启动以便在界面中修改
+	configErr := config.Validate()
+	for _, e := range validationErrorsOf(configErr) {
+		MainLogToFile(fmt.Sprintf("配置检查: %s", e.Error()))
+	}
+
 	// 同步开机自启动配置
-	if config.AutoStart == "true" {
+	if config.AutoStart {
 		if err := utils.EnableAutoStart(); err != nil {
 			MainLogToFile(fmt.Sprintf("启用开机自启动失败: %v", err))
 		}
@@ -108,6 +136,14 @@ func main() {
 	customTheme, _ := utils.NewCustomTheme()
 	myApp.Settings().SetTheme(customTheme)
 
+	// 各界面共用 config，配置在其他界面、配置文件或历史版本中被修改时先同步到 config，再通知各界面刷新
+	onConfigChange(func(_, c *Config) { *config = *c })
+	go func() {
+		if err := appConfig.Watch(context.Background()); err != nil {
+			MainLogToFile(fmt.Sprintf("监听配置文件失败: %v", err))
+		}
+	}()
+
 	// 创建自动执行 UI
 	autoTaskUI := createautoTaskUI(myWindow, config)
 
@@ -124,11 +160,14 @@ func main() {
 	picConfigUI := createPicConfigUI(config, myWindow)
 
 	// 创建关于界面 UI
-	aboutUI := createAboutUI(myWindow)
+	aboutUI := createAboutUI(myWindow, config)
 
 	// 创建api配置界面 UI
 	apiconfigUI := createAPIConfigUI(config, myWindow)
 
+	// 创建执行记录界面 UI
+	historyUI := createHistoryUI(myWindow)
+
 	// 创建 Tab 内容，并添加内边距
 	autotaskTab := container.NewTabItem("自动任务", container.NewVBox(container.NewPadded(autoTaskUI)))
 	schedTab := container.NewTabItem("计划任务", container.NewVBox(container.NewPadded(schedUI)))
@@ -136,6 +175,7 @@ func main() {
 	configUITab := container.NewTabItem("OSS 配置", container.NewVBox(container.NewPadded(configUI)))
 	picConfigTab := container.NewTabItem("图片配置", container.NewVBox(container.NewPadded(picConfigUI)))
 	apiConfigTab := container.NewTabItem("API配置", container.NewVBox(container.NewPadded(apiconfigUI)))
+	historyTab := container.NewTabItem("执行记录", container.NewPadded(historyUI))
 	aboutTab := container.NewTabItem("关于", container.NewVBox(container.NewPadded(aboutUI)))
 
 	// 创建 Tabs
@@ -146,9 +186,17 @@ func main() {
 		configUITab,
 		picConfigTab,
 		apiConfigTab,
+		historyTab,
 		aboutTab,
 	)
 
+	// 切换到执行记录时刷新列表
+	tabs.OnSelected = func(tab *container.TabItem) {
+		if tab == historyTab && refreshHistory != nil {
+			refreshHistory()
+		}
+	}
+
 	// 设置 Tab 显示顺序和默认选择的标签
 	tabs.SetTabLocation(container.TabLocationTop)
 	tabs.Select(autotaskTab)
@@ -169,15 +217,7 @@ func main() {
 	}
 
 	// 处理自动执行任务的逻辑
-	shouldAutoRunTask := config.AutoStartAutoTask == "true"
-
-	// 修改注册表启动命令
-	if utils.IsAutoStartEnabled() {
-		exePath, _ := os.Executable()
-		key, _ := registry.OpenKey(registry.CURRENT_USER, utils.RegistryAutoRunPath, registry.SET_VALUE)
-		key.SetStringValue(utils.AppRegKeyName, fmt.Sprintf(`"%s"`, exePath)) // 移除 --minimized 参数
-		key.Close()
-	}
+	shouldAutoRunTask := config.AutoStartAutoTask
 
 	// 程序启动行为设置 - 修改为默认最小化
 	myApp.Lifecycle().SetOnStarted(func() {
@@ -190,6 +230,13 @@ func main() {
 		myWindow.Hide()
 		MainLogToFile("程序启动时已最小化到系统托盘")
 
+		// 配置有问题时发送通知，打开界面后可看到详细错误
+		if configErr != nil {
+			myApp.SendNotification(fyne.NewNotification("GO-UPOSS 配置检查",
+				fmt.Sprintf("配置文件有 %d 处问题，请打开界面查看", len(validationErrorsOf(configErr)))))
+			dialog.ShowError(fmt.Errorf("配置检查发现以下问题:\n%v", configErr), myWindow)
+		}
+
 		// 检查是否自动执行任务
 		if shouldAutoRunTask {
 			MainLogToFile("触发自动任务执行...")
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"go-uposs/pipeline"
 	"strings"
 	"time"
 )
@@ -11,7 +12,7 @@ func parseFolderNameToTime(folderName string) (time.Time, error) {
 }
 
 // isFolderInTimeRange 检查文件夹名称是否在时间范围内
-func isFolderInTimeRange(folderName string, isScheduledTask bool, config *Config) bool {
+func isFolderInTimeRange(folderName string, rc *pipeline.RunContext, config *Config) bool {
 	if folderName == "." {
 		return false
 	}
@@ -21,7 +22,7 @@ func isFolderInTimeRange(folderName string, isScheduledTask bool, config *Config
 	}
 
 	now := time.Now()
-	if !isScheduledTask {
+	if rc.IsAuto() {
 		// 自动任务：上传今天和昨天文件夹中的文件
 		todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 		todayEnd := todayStart.AddDate(0, 0, 1)
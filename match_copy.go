@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"go-uposs/pipeline"
 	"go-uposs/utils"
 	"io"
 	"os"
@@ -16,15 +18,17 @@ func MatchDatePattern(folderName string) bool {
 	return re.MatchString(folderName)
 }
 
-// CopyDir 复制目录及其内容
-func CopyDir(src string, dst string, bufferSize int, isAutoTask bool, dateRange string, orderNumbers string) error {
+// CopyDir 复制目录及其内容，ctx 取消时立即停止
+func CopyDir(ctx context.Context, rc *pipeline.RunContext, src string, dst string, bufferSize int, dateRange string) error {
 	if bufferSize <= 0 {
 		return fmt.Errorf("无效的缓冲区大小")
 	}
 
-	err := os.MkdirAll(dst, os.ModePerm)
-	if err != nil {
-		return fmt.Errorf("创建目标目录失败: %v", err)
+	// 模拟运行不创建目标目录
+	if !rc.DryRun {
+		if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+			return fmt.Errorf("创建目标目录失败: %v", err)
+		}
 	}
 
 	entries, err := os.ReadDir(src)
@@ -34,25 +38,29 @@ func CopyDir(src string, dst string, bufferSize int, isAutoTask bool, dateRange
 
 	// 检查日期范围
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
 		// 检查是否是目录
 		if entry.IsDir() {
-			err = CopyDir(srcPath, dstPath, bufferSize, isAutoTask, dateRange, orderNumbers)
+			err = CopyDir(ctx, rc, srcPath, dstPath, bufferSize, dateRange)
 			if err != nil {
 				return err
 			}
 		} else {
 			// 非自动任务，检查编号
-			if !isAutoTask && orderNumbers != "" {
-				matchedFiles := matchFilesByNumbers(orderNumbers, []string{entry.Name()})
+			if rc.IsSched() && rc.OrderNumbers != "" {
+				matchedFiles := matchFilesByNumbers(rc.OrderNumbers, []string{entry.Name()})
 				if len(matchedFiles) == 0 {
 					continue
 				}
 			}
 			// 现在只检查数据库记录，不需要检查物理文件
-			err = CopyFile(srcPath, dstPath, bufferSize, isAutoTask, dateRange)
+			err = CopyFile(ctx, rc, srcPath, dstPath, bufferSize, dateRange)
 			if err != nil {
 				return err
 			}
@@ -60,50 +68,47 @@ func CopyDir(src string, dst string, bufferSize int, isAutoTask bool, dateRange
 	}
 
 	// 记录系统日志
-	logMessage := fmt.Sprintf("匹配到的源文件夹: %s, 目录复制成功: %s -> %s", src, src, dst)
-	if isAutoTask {
-		AutoLogToFile(logMessage)
-	} else {
-		SchedLogToFile(logMessage)
+	if !rc.DryRun {
+		rc.Log(fmt.Sprintf("匹配到的源文件夹: %s, 目录复制成功: %s -> %s", src, src, dst))
 	}
 
 	return nil
 }
 
-// CopyFile 复制文件
-func CopyFile(src, dst string, bufferSize int, isAutoTask bool, dateRange string) error {
+// CopyFile 复制文件，复制失败或 ctx 取消时删除不完整的目标文件
+func CopyFile(ctx context.Context, rc *pipeline.RunContext, src, dst string, bufferSize int, dateRange string) error {
 	fileName := filepath.Base(src)
+	stats := runStatsOf(rc)
+	stats.addScanned() // 已复制过而跳过的文件也计入扫描数
 
 	// 检查文件是否已经复制过
-	exists, err := utils.CheckFileExists(fileName, isAutoTask)
+	exists, err := utils.CheckFileExists(rc.Profile, fileName, rc.IsAuto())
 	if err != nil {
 		// 数据库错误，记录到文件日志
-		logMsg := fmt.Sprintf("检查文件是否存在时出错: %v", err)
-		if isAutoTask {
-			AutoLogToFile(logMsg)
-		} else {
-			SchedLogToFile(logMsg)
-		}
+		rc.Log(fmt.Sprintf("检查文件是否存在时出错: %v", err))
 	} else if exists {
 		// 普通操作信息，只记录到文件日志
 		// logMsg := fmt.Sprintf("文件 %s 已经复制过，跳过", fileName)
-		// if isAutoTask {
-		// 	AutoLogToFile(logMsg)
-		// } else {
-		// 	SchedLogToFile(logMsg)
-		// }
+		// rc.Log(logMsg)
+		return nil
+	}
+
+	// 模拟运行只记录将要复制的文件
+	if rc.DryRun {
+		info, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("无法读取源文件: %v", err)
+		}
+		dryRunPlanOf(rc).addCopy(src, dst, info)
+		stats.addCopied(info.Size())
+		dryRunLog(rc, "将复制文件: %s -> %s (%dKB)", src, dst, info.Size()/1024)
 		return nil
 	}
 
 	srcFile, err := os.Open(src)
 	if err != nil {
 		// 错误信息，记录到文件
-		logMsg := fmt.Sprintf("无法打开源文件 %s: %v", src, err)
-		if isAutoTask {
-			AutoLogToFile(logMsg)
-		} else {
-			SchedLogToFile(logMsg)
-		}
+		rc.Log(fmt.Sprintf("无法打开源文件 %s: %v", src, err))
 		// 删除对已移除函数的调用
 		return fmt.Errorf("无法打开源文件: %v", err)
 	}
@@ -112,27 +117,26 @@ func CopyFile(src, dst string, bufferSize int, isAutoTask bool, dateRange string
 	dstFile, err := os.Create(dst)
 	if err != nil {
 		// 记录错误到文件日志
-		logMsg := fmt.Sprintf("无法创建目标文件 %s: %v", dst, err)
-		if isAutoTask {
-			AutoLogToFile(logMsg)
-		} else {
-			SchedLogToFile(logMsg)
-		}
+		rc.Log(fmt.Sprintf("无法创建目标文件 %s: %v", dst, err))
 		return fmt.Errorf("无法创建目标文件: %v", err)
 	}
 	defer dstFile.Close()
 
 	buf := make([]byte, bufferSize)
+	var written int64
 	for {
+		// 任务被取消，删除只复制了一部分的文件
+		if err := ctx.Err(); err != nil {
+			removePartialFile(dstFile, dst)
+			rc.Log(fmt.Sprintf("复制已取消，删除不完整的文件: %s", dst))
+			return err
+		}
+
 		n, err := srcFile.Read(buf)
 		if err != nil && err != io.EOF {
 			// 记录错误到文件日志
-			logMsg := fmt.Sprintf("读取源文件 %s 失败: %v", src, err)
-			if isAutoTask {
-				AutoLogToFile(logMsg)
-			} else {
-				SchedLogToFile(logMsg)
-			}
+			rc.Log(fmt.Sprintf("读取源文件 %s 失败: %v", src, err))
+			removePartialFile(dstFile, dst)
 			return fmt.Errorf("读取源文件失败: %v", err)
 		}
 		if n == 0 {
@@ -141,43 +145,48 @@ func CopyFile(src, dst string, bufferSize int, isAutoTask bool, dateRange string
 
 		if _, err := dstFile.Write(buf[:n]); err != nil {
 			// 记录错误到文件日志
-			logMsg := fmt.Sprintf("写入目标文件 %s 失败: %v", dst, err)
-			if isAutoTask {
-				AutoLogToFile(logMsg)
-			} else {
-				SchedLogToFile(logMsg)
-			}
+			rc.Log(fmt.Sprintf("写入目标文件 %s 失败: %v", dst, err))
+			removePartialFile(dstFile, dst)
 			return fmt.Errorf("写入目标文件失败: %v", err)
 		}
+		written += int64(n)
+	}
+
+	// 关闭目标文件，确保数据写入完成后再记录数据库
+	if err := dstFile.Close(); err != nil {
+		rc.Log(fmt.Sprintf("写入目标文件 %s 失败: %v", dst, err))
+		os.Remove(dst)
+		return fmt.Errorf("写入目标文件失败: %v", err)
 	}
 
 	// 获取复制源完整路径的最后路径 (即包含日期的文件夹)
 	copyDir := filepath.Base(filepath.Dir(src))
 
 	// 将复制记录添加到数据库，移除 parsedNames 参数
-	if err = utils.RecordFileCopy(fileName, copyDir, isAutoTask); err != nil {
+	if err = utils.RecordFileCopy(rc.Profile, fileName, copyDir, rc.IsAuto()); err != nil {
 		// 数据库错误仅记录，不影响复制结果
-		logMsg := fmt.Sprintf("记录文件复制失败: %v", err)
-		if isAutoTask {
-			AutoLogToFile(logMsg)
-		} else {
-			SchedLogToFile(logMsg)
-		}
+		rc.Log(fmt.Sprintf("记录文件复制失败: %v", err))
+	}
+	if err = utils.RecordJobCopied(rc.Profile, fileName, copyDir, src, dst); err != nil {
+		rc.Log(fmt.Sprintf("记录文件处理状态失败: %v", err))
 	}
 
+	stats.addCopied(written)
+
 	// 成功复制的文件信息，记录到日志文件
-	logMsg := fmt.Sprintf("成功复制文件: %s %s -> %s %s", "源路径", fileName, "目的路径", fileName)
-	if isAutoTask {
-		AutoLogToFile(logMsg)
-	} else {
-		SchedLogToFile(logMsg)
-	}
+	rc.Log(fmt.Sprintf("成功复制文件: %s %s -> %s %s", "源路径", fileName, "目的路径", fileName))
 
 	return nil
 }
 
-// ScanAndCopyFolders 扫描并复制匹配选定日期范围内的文件夹
-func ScanAndCopyFolders(config *Config, orderNumbers string) error {
+// removePartialFile 关闭并删除未复制完整的目标文件
+func removePartialFile(f *os.File, path string) {
+	f.Close()
+	os.Remove(path)
+}
+
+// ScanAndCopyFolders 扫描并复制匹配选定日期范围内的文件夹，按 rc.OrderNumbers 过滤文件
+func ScanAndCopyFolders(ctx context.Context, rc *pipeline.RunContext, config *Config) error {
 	startDate, err := time.Parse("2006.01.02", config.StartTime)
 	if err != nil {
 		return fmt.Errorf("解析开始日期失败: %v", err)
@@ -195,6 +204,10 @@ func ScanAndCopyFolders(config *Config, orderNumbers string) error {
 		if err != nil {
 			return err
 		}
+		// 任务被取消时立即结束遍历
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 
 		if info.IsDir() && MatchDatePattern(info.Name()) {
 			folderDate, err := time.Parse("2006.01.02", info.Name())
@@ -204,9 +217,9 @@ func ScanAndCopyFolders(config *Config, orderNumbers string) error {
 
 			if !folderDate.Before(startDate) && !folderDate.After(endDate) {
 				dstPath := filepath.Join(config.LocalFolder, info.Name())
-				err = CopyDir(path, dstPath, config.IOBuffer, false, dateRange, orderNumbers)
+				err = CopyDir(ctx, rc, path, dstPath, config.IOBufferBytes(), dateRange)
 				if err != nil {
-					return fmt.Errorf("复制文件夹 %s 失败: %v", path, err)
+					return fmt.Errorf("复制文件夹 %s 失败: %w", path, err)
 				}
 			}
 		}
@@ -216,7 +229,7 @@ func ScanAndCopyFolders(config *Config, orderNumbers string) error {
 }
 
 // ScanAndCopyFoldersForToday 扫描并复制匹配当前日期的文件夹
-func ScanAndCopyFoldersForToday(config *Config) error {
+func ScanAndCopyFoldersForToday(ctx context.Context, rc *pipeline.RunContext, config *Config) error {
 	// 获取昨天和今天的日期字符串
 	yesterday := time.Now().AddDate(0, 0, -1).Format("2006.01.02")
 	today := time.Now().Format("2006.01.02")
@@ -234,12 +247,16 @@ func ScanAndCopyFoldersForToday(config *Config) error {
 		if err != nil {
 			return err
 		}
+		// 任务被取消时立即结束遍历
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 
 		if info.IsDir() && dateSet[info.Name()] {
 			dstPath := filepath.Join(config.LocalFolder, info.Name())
-			err = CopyDir(path, dstPath, config.IOBuffer, true, dateRange, "") // 自动任务不需要编号，参数置为空字符串
+			err = CopyDir(ctx, rc, path, dstPath, config.IOBufferBytes(), dateRange) // 自动任务不按编号过滤
 			if err != nil {
-				return fmt.Errorf("复制文件夹 %s 失败: %v", path, err)
+				return fmt.Errorf("复制文件夹 %s 失败: %w", path, err)
 			}
 		}
 
@@ -1,100 +1,178 @@
 package main
 
 import (
-	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
-// InitMinioClient 初始化 minio 客户端
-func InitMinioClient(config *Config, useSSL bool) (*minio.Client, error) {
-	client, err := minio.New(config.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("初始化客户端失败: %v", err)
-	}
-
-	logMessage := fmt.Sprintf("客户端初始化成功 | MachineCode: %s | Endpoint: %s | UseSSL: %v",
-		config.MachineCode, config.Endpoint, useSSL)
-	updateLog(ossLogText, "[MinioClient]", logMessage)
+// s3_bucket_lookup 的取值，为空时与 auto 相同
+const (
+	s3LookupAuto        = "auto"         // 按端点判断：AWS、阿里云等使用 virtual-host，其他使用 path
+	s3LookupPath        = "path"         // <端点>/<存储桶>/<对象>，MinIO 和大多数自建存储
+	s3LookupVirtualHost = "virtual-host" // <存储桶>.<端点>/<对象>
+)
 
-	return client, nil
-}
+// s3BucketLookups 界面中可选的存储桶地址风格
+var s3BucketLookups = []string{s3LookupAuto, s3LookupPath, s3LookupVirtualHost}
 
-// TestConnection 测试 minio 连接（带超时）
-func TestConnection(client *minio.Client) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// S3 连接参数未配置时的默认值
+const (
+	defaultS3ConnectTimeout = 10  // 秒
+	defaultS3ReadTimeout    = 60  // 秒
+	defaultS3MaxIdleConns   = 16  // 每个主机
+	maxS3Timeout            = 600 // 超时时间的上限，单位秒
+	maxS3Conns              = 1024
+)
 
-	_, err := client.ListBuckets(ctx)
-	if err != nil {
-		return fmt.Errorf("minio 连接测试失败: %v", err)
+// s3BucketLookup 返回配置的存储桶地址风格对应的 minio 参数
+func s3BucketLookup(style string) minio.BucketLookupType {
+	switch style {
+	case s3LookupPath:
+		return minio.BucketLookupPath
+	case s3LookupVirtualHost:
+		return minio.BucketLookupDNS
+	default:
+		return minio.BucketLookupAuto
 	}
-	return nil
 }
 
-// TestMinioConnection 加载配置、初始化客户端、测试连接
-func TestMinioConnection(useSSL bool) (string, error) {
-	// 加载配置
-	config, err := LoadConfig("config.json")
-
-	if err != nil {
-		// 记录加载配置失败
-		updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("加载配置失败: %v", err)) // 更新日志
-		return "", fmt.Errorf("加载配置失败: %v", err)
+// secondsOrDefault 返回 seconds 秒，为 0 时返回 def 秒
+func secondsOrDefault(seconds, def int) time.Duration {
+	if seconds <= 0 {
+		seconds = def
 	}
+	return time.Duration(seconds) * time.Second
+}
 
-	// 记录连接测试开始
-	updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("测试与 %s 的连接 (UseSSL: %t)", config.Endpoint, useSSL)) // 更新日志
+// s3MaxIdleConns 返回每个主机保留的空闲连接数，未配置时返回默认值
+func s3MaxIdleConns(config *Config) int {
+	if config.S3MaxIdleConns > 0 {
+		return config.S3MaxIdleConns
+	}
+	return defaultS3MaxIdleConns
+}
 
-	// 初始化 minio 客户端
-	client, err := InitMinioClient(config, useSSL)
+// loadCAFile 读取 PEM 格式的 CA 文件，返回加入了这些证书的系统证书池
+func loadCAFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		// 记录初始化失败
-		updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("初始化minio客户端失败: %v", err)) // 更新日志
-		return "", err
+		return nil, fmt.Errorf("读取 CA 文件失败: %v", err)
 	}
-
-	// 测试连接
-	if err := TestConnection(client); err != nil {
-		updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("连接测试失败: %v", err)) // 更新日志
-		return "", err
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA 文件 %s 中没有 PEM 格式的证书", path)
 	}
+	return pool, nil
+}
 
-	// 获取存储桶列表
-	buckets, err := client.ListBuckets(context.Background())
+// newS3Transport 按配置中的代理、证书、超时和连接池参数创建连接 S3 使用的 http.Transport
+func newS3Transport(config *Config, useSSL bool) (*http.Transport, error) {
+	tr, err := minio.DefaultTransport(useSSL)
 	if err != nil {
-		updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("获取存储桶列表失败: %v", err)) // 更新日志
-		return "", fmt.Errorf("获取存储桶列表失败: %v", err)
+		return nil, err
 	}
 
-	// 记录连接成功并找到的存储桶数量
-	updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("连接成功! 找到 %d 个存储桶", len(buckets))) // 更新日志
+	connectTimeout := secondsOrDefault(config.S3ConnectTimeout, defaultS3ConnectTimeout)
+	tr.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	tr.TLSHandshakeTimeout = connectTimeout
+	tr.ResponseHeaderTimeout = secondsOrDefault(config.S3ReadTimeout, defaultS3ReadTimeout)
 
-	// 记录每个存储桶的详细信息
-	for _, bucket := range buckets {
-		updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("存储桶: %s (创建于: %s)", bucket.Name, bucket.CreationDate.Format("2006-01-02 15:04:05"))) // 更新日志
+	tr.MaxIdleConnsPerHost = s3MaxIdleConns(config)
+	if tr.MaxIdleConns < tr.MaxIdleConnsPerHost {
+		tr.MaxIdleConns = tr.MaxIdleConnsPerHost
+	}
+	tr.MaxConnsPerHost = config.S3MaxConnsPerHost
+
+	if config.S3Proxy != "" {
+		proxy, err := url.Parse(config.S3Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址 %s 无效: %v", config.S3Proxy, err)
+		}
+		tr.Proxy = http.ProxyURL(proxy)
 	}
 
-	return "连接测试成功", nil
+	if useSSL && (config.S3CAFile != "" || config.S3InsecureSkipVerify) {
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		if config.S3CAFile != "" {
+			pool, err := loadCAFile(config.S3CAFile)
+			if err != nil {
+				return nil, err
+			}
+			tr.TLSClientConfig.RootCAs = pool
+		}
+		tr.TLSClientConfig.InsecureSkipVerify = config.S3InsecureSkipVerify
+	}
+	return tr, nil
 }
 
-// ListBuckets 获取存储桶列表
-func ListBuckets(client *minio.Client) ([]minio.BucketInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	buckets, err := client.ListBuckets(ctx)
+// InitMinioClient 初始化 minio 客户端
+func InitMinioClient(config *Config, useSSL bool) (*minio.Client, error) {
+	transport, err := newS3Transport(config, useSSL)
 	if err != nil {
-		updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("获取存储桶列表失败: %v", err)) // 更新日志
-		return nil, fmt.Errorf("获取存储桶列表失败: %v", err)
+		return nil, fmt.Errorf("初始化客户端失败: %v", err)
 	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, config.S3SessionToken),
+		Secure:       useSSL,
+		Region:       config.S3Region,
+		BucketLookup: s3BucketLookup(config.S3BucketLookup),
+		Transport:    transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("初始化客户端失败: %v", err)
+	}
+
+	logMessage := fmt.Sprintf("客户端初始化成功 | MachineCode: %s | Endpoint: %s | UseSSL: %v | %s",
+		config.MachineCode, config.Endpoint, useSSL, s3OptionsSummary(config, useSSL))
+	updateLog(ossLogText, "[MinioClient]", logMessage)
+
+	return client, nil
+}
 
-	updateLog(ossLogText, "[MinioClient]", fmt.Sprintf("成功获取 %d 个存储桶信息", len(buckets))) // 更新日志
-	return buckets, nil
+// s3OptionsSummary 返回日志中显示的 S3 连接参数，不含密钥
+func s3OptionsSummary(config *Config, useSSL bool) string {
+	region := config.S3Region
+	if region == "" {
+		region = "自动"
+	}
+	lookup := config.S3BucketLookup
+	if lookup == "" {
+		lookup = s3LookupAuto
+	}
+	proxy := "环境变量"
+	if u, err := url.Parse(config.S3Proxy); config.S3Proxy != "" && err == nil {
+		proxy = u.Redacted()
+	}
+	summary := fmt.Sprintf("Region: %s | Lookup: %s | Proxy: %s | Timeout: %s/%s | IdleConns: %d",
+		region, lookup, proxy,
+		secondsOrDefault(config.S3ConnectTimeout, defaultS3ConnectTimeout),
+		secondsOrDefault(config.S3ReadTimeout, defaultS3ReadTimeout),
+		s3MaxIdleConns(config))
+	if config.S3MaxConnsPerHost > 0 {
+		summary += fmt.Sprintf(" | MaxConns: %d", config.S3MaxConnsPerHost)
+	}
+	if config.S3SessionToken != "" {
+		summary += " | SessionToken: 已设置"
+	}
+	if useSSL && config.S3CAFile != "" {
+		summary += " | CA: " + config.S3CAFile
+	}
+	if useSSL && config.S3InsecureSkipVerify {
+		summary += " | 不校验证书"
+	}
+	return summary
 }
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-uposs/storage"
+)
+
+// 配置中的 storage_type，为空时使用 minio
+const (
+	storageMinio  = "minio"  // MinIO 或其他 S3 兼容存储
+	storageLocal  = "local"  // 本地文件夹，用于离线测试和不能访问对象存储的现场
+	storageWebDAV = "webdav" // WebDAV 共享（NAS）
+	storageSFTP   = "sftp"   // SFTP 服务器
+)
+
+// storageTypes 界面中可选的存储类型和显示名称
+var storageTypes = []struct{ name, label string }{
+	{storageMinio, "MinIO / S3"},
+	{storageLocal, "本地文件夹"},
+	{storageWebDAV, "WebDAV"},
+	{storageSFTP, "SFTP"},
+}
+
+const (
+	storageHealthTimeout = 10 * time.Second // 存储连接测试的超时时间
+	storageIOTimeout     = 60 * time.Second // WebDAV 请求、SFTP 连接的超时时间
+)
+
+// storageType 返回配置的存储类型，未配置时为 minio
+func (config *Config) storageType() string {
+	if config.StorageType == "" {
+		return storageMinio
+	}
+	return config.StorageType
+}
+
+// storageTypeLabel 返回存储类型在界面中的名称
+func storageTypeLabel(name string) string {
+	for _, t := range storageTypes {
+		if t.name == name {
+			return t.label
+		}
+	}
+	return name
+}
+
+// storageTypeName 返回界面名称对应的存储类型
+func storageTypeName(label string) string {
+	for _, t := range storageTypes {
+		if t.label == label {
+			return t.name
+		}
+	}
+	return label
+}
+
+// openObjectStore 按配置的 storage_type 创建上传使用的存储
+func openObjectStore(config *Config) (storage.ObjectStore, error) {
+	switch config.storageType() {
+	case storageMinio:
+		client, err := InitMinioClient(config, config.UseSSL)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewMinioStore(client, config.BucketName), nil
+	case storageLocal:
+		return storage.NewLocalStore(config.LocalStoreDir, config.BucketName), nil
+	case storageWebDAV:
+		return storage.NewWebDAVStore(storage.WebDAVOptions{
+			URL:      config.WebDAVURL,
+			User:     config.WebDAVUser,
+			Password: config.WebDAVPassword,
+			Timeout:  storageIOTimeout,
+		}, config.BucketName), nil
+	case storageSFTP:
+		return storage.NewSFTPStore(storage.SFTPOptions{
+			Host:     config.SFTPHost,
+			User:     config.SFTPUser,
+			Password: config.SFTPPassword,
+			KeyFile:  config.SFTPKeyFile,
+			HostKey:  config.SFTPHostKey,
+			Dir:      config.SFTPDir,
+			Timeout:  storageIOTimeout,
+		}, config.BucketName), nil
+	default:
+		return nil, fmt.Errorf("未知的存储类型 %s", config.StorageType)
+	}
+}
+
+// objectURL 返回推送到 API2 的文件访问地址：配置了 public_base_url 时为 <public_base_url>/<key>，
+// 否则为 <public_url>/<bucket_name>/<key>
+func objectURL(config *Config, bucket, key string) string {
+	if config.PublicBaseURL != "" {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(config.PublicBaseURL, "/"), key)
+	}
+	return fmt.Sprintf("%s/%s/%s", config.PublicUrl, bucket, key)
+}
+
+// storageTarget 返回日志中显示的存储位置
+func storageTarget(config *Config) string {
+	switch config.storageType() {
+	case storageLocal:
+		return config.LocalStoreDir
+	case storageWebDAV:
+		return config.WebDAVURL
+	case storageSFTP:
+		return config.SFTPHost
+	default:
+		return config.Endpoint
+	}
+}
+
+// storeIdentity 返回对象哈希索引中的存储标识：存储类型和地址，切换存储或修改地址后不会沿用原来的记录
+func storeIdentity(config *Config) string {
+	return config.storageType() + ":" + storageTarget(config)
+}
+
+// checkObjectStore 检查存储是否可用（带超时），返回用于日志的说明
+func checkObjectStore(ctx context.Context, store storage.ObjectStore) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, storageHealthTimeout)
+	defer cancel()
+	return store.Health(ctx)
+}
+
+// minioConnFields 测试 MinIO 连接前校验的字段，校验失败时不连接
+var minioConnFields = []string{
+	"endpoint", "accessKeyID", "secretAccessKey", "s3_region", "s3_bucket_lookup", "s3_session_token",
+	"s3_ca_file", "s3_proxy", "s3_connect_timeout", "s3_read_timeout", "s3_max_idle_conns", "s3_max_conns_per_host",
+}
+
+// TestStorageConnection 在当前配置上应用界面中尚未保存的修改 apply，创建存储并测试连接
+func TestStorageConnection(apply func(c *Config) error) (string, error) {
+	// 加载配置
+	config, err := appConfig.Current()
+	if err != nil {
+		updateLog(ossLogText, "[存储]", fmt.Sprintf("加载配置失败: %v", err))
+		return "", fmt.Errorf("加载配置失败: %v", err)
+	}
+	if apply != nil {
+		if err := apply(config); err != nil {
+			return "", err
+		}
+	}
+
+	updateLog(ossLogText, "[存储]", fmt.Sprintf("测试与 %s 的连接 (存储类型: %s)", storageTarget(config), storageTypeLabel(config.storageType())))
+
+	if config.storageType() == storageMinio {
+		if err := config.ValidateFields(minioConnFields...); err != nil {
+			updateLog(ossLogText, "[存储]", fmt.Sprintf("连接参数校验失败:\n%v", err))
+			return "", fmt.Errorf("连接参数校验失败")
+		}
+	}
+
+	store, err := openObjectStore(config)
+	if err != nil {
+		updateLog(ossLogText, "[存储]", fmt.Sprintf("初始化存储失败: %v", err))
+		return "", err
+	}
+	defer store.Close()
+
+	detail, err := checkObjectStore(context.Background(), store)
+	if err != nil {
+		updateLog(ossLogText, "[存储]", fmt.Sprintf("连接测试失败: %v", err))
+		if config.storageType() == storageMinio && strings.Contains(err.Error(), "x509:") {
+			updateLog(ossLogText, "[存储]", "服务器证书校验失败，自签名证书请在连接选项中设置 CA File，或在内网测试时勾选不校验证书")
+		}
+		return "", err
+	}
+	updateLog(ossLogText, "[存储]", fmt.Sprintf("连接成功! %s", detail))
+
+	ctx, cancel := context.WithTimeout(context.Background(), storageHealthTimeout)
+	defer cancel()
+	if exists, err := store.BucketExists(ctx); err != nil {
+		updateLog(ossLogText, "[存储]", fmt.Sprintf("检查存储桶 %s 失败: %v", store.Bucket(), err))
+	} else if exists {
+		updateLog(ossLogText, "[存储]", fmt.Sprintf("存储桶 %s 已存在", store.Bucket()))
+	} else {
+		updateLog(ossLogText, "[存储]", fmt.Sprintf("存储桶 %s 不存在，首次上传时创建", store.Bucket()))
+	}
+
+	return "连接测试成功", nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-uposs/utils"
+)
+
+const (
+	outboxPollInterval = 15 * time.Second // 检查推送待办的间隔
+	outboxBatchSize    = 50               // 每次最多补推的条数
+	outboxNotifyAfter  = 5                // 失败达到该次数时发送企业微信通知
+)
+
+// OutboxLogToFile 推送待办日志写入系统日志
+func OutboxLogToFile(message string) {
+	SysLogToFile(fmt.Sprintf("[OUTBOX] %s", message))
+}
+
+// runOutboxWorker 后台补推 API2 推送待办，直到 ctx 取消
+func runOutboxWorker(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		drainOutbox(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drainOutbox 推送所有到期的待办，失败的按指数退避安排下一次推送
+func drainOutbox(ctx context.Context) {
+	entries, err := utils.DueOutboxEntries(time.Now(), outboxBatchSize)
+	if err != nil {
+		OutboxLogToFile(fmt.Sprintf("查询推送待办失败: %v", err))
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	// 每次读取 appConfig 的最新配置，API2 地址修正后立即生效
+	base, err := appConfig.Current()
+	if err != nil {
+		OutboxLogToFile(fmt.Sprintf("加载配置失败: %v", err))
+		return
+	}
+
+	for _, e := range entries {
+		// 使用产生通知的配置方案的 API2 地址；方案已删除时保留待办，恢复方案后继续补推
+		config, err := base.ForProfile(e.Profile)
+		if err != nil {
+			OutboxLogToFile(fmt.Sprintf("跳过编号 %s 的推送待办: %v", e.OrderNumber, err))
+			continue
+		}
+
+		if err := api2Limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		_, pushErr := utils.PostAPI2(ctx, config.API2, []byte(e.Payload))
+		if ctx.Err() != nil {
+			return
+		}
+
+		if pushErr == nil {
+			if err := utils.DeleteOutboxEntry(e.ID); err != nil {
+				OutboxLogToFile(fmt.Sprintf("删除推送待办失败: %v", err))
+			}
+			if e.FileName != "" {
+				if err := utils.MarkJobPushed(e.Profile, e.FileName); err != nil {
+					OutboxLogToFile(fmt.Sprintf("记录文件推送状态失败: %v", err))
+				}
+			}
+			OutboxLogToFile(fmt.Sprintf("补推 API2 成功😎，编号: %s，文件访问地址: %s", e.OrderNumber, e.FileURL))
+			continue
+		}
+
+		attempts, err := utils.RetryOutboxEntry(e, pushErr)
+		if err != nil {
+			OutboxLogToFile(fmt.Sprintf("更新推送待办失败: %v", err))
+			continue
+		}
+		OutboxLogToFile(fmt.Sprintf("补推 API2 失败❌😅 (第%d次)，编号: %s，%v 后重试，错误: %v",
+			attempts, e.OrderNumber, utils.OutboxBackoff(attempts), pushErr))
+
+		if attempts == outboxNotifyAfter {
+			if err := config.NotifyPushPending(e.OrderNumber, attempts); err != nil {
+				OutboxLogToFile(fmt.Sprintf("发送企业微信通知失败: %v", err))
+			}
+		}
+	}
+}
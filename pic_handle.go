@@ -1,46 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"go-uposs/pipeline"
 	"go-uposs/utils"
 	"image"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/nfnt/resize"
 )
 
-// CompressImage 根据配置压缩图像
-func CompressImage(srcPath string, quality, width int) error {
-	// 打开源文件
-	srcFile, err := os.Open(srcPath)
-	if err != nil {
-		return fmt.Errorf("无法打开源文件: %v", err)
-	}
-
+// CompressImage 根据配置压缩图像，无法解码时删除文件和配置方案 profile 的复制记录
+func CompressImage(profile, srcPath string, quality, width int) error {
 	// 解码图像
-	var img image.Image
-	switch {
-	case strings.HasSuffix(strings.ToLower(srcPath), ".jpeg"), strings.HasSuffix(strings.ToLower(srcPath), ".jpg"):
-		img, err = jpeg.Decode(srcFile)
-	case strings.HasSuffix(strings.ToLower(srcPath), ".png"):
-		img, err = png.Decode(srcFile)
-	case strings.HasSuffix(strings.ToLower(srcPath), ".gif"):
-		img, err = gif.Decode(srcFile)
-	}
-
-	// 关闭文件，释放文件锁
-	srcFile.Close()
-
+	img, err := decodeImage(srcPath)
 	if err != nil {
 		// 尝试删除数据库记录
 		fileName := filepath.Base(srcPath)
-		if delDBErr := utils.DeleteFileCopyRecord(fileName, true); delDBErr != nil {
+		if delDBErr := utils.DeleteFileCopyRecord(profile, fileName, true); delDBErr != nil {
 			return fmt.Errorf("\n解码图像失败、删除数据库记录失败且删除文件失败: 解码错误 %v, 删除数据库记录错误 %v", err, delDBErr)
 		}
 		// 尝试删除无法解码的图片
@@ -61,77 +48,323 @@ func CompressImage(srcPath string, quality, width int) error {
 	defer destFile.Close()
 
 	// 压缩图像并保存为新文件
+	if err := encodeImage(destFile, srcPath, newImg, quality); err != nil {
+		return fmt.Errorf("压缩图像失败: %v", err)
+	}
+
+	return nil
+}
+
+// decodeImage 按扩展名解码图像文件
+func decodeImage(path string) (image.Image, error) {
+	// 打开源文件
+	srcFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开源文件: %v", err)
+	}
+	// 解码后立即关闭文件，释放文件锁
+	defer srcFile.Close()
+
+	var img image.Image
 	switch {
-	case strings.HasSuffix(strings.ToLower(srcPath), ".jpeg"), strings.HasSuffix(strings.ToLower(srcPath), ".jpg"):
+	case strings.HasSuffix(strings.ToLower(path), ".jpeg"), strings.HasSuffix(strings.ToLower(path), ".jpg"):
+		img, err = jpeg.Decode(srcFile)
+	case strings.HasSuffix(strings.ToLower(path), ".png"):
+		img, err = png.Decode(srcFile)
+	case strings.HasSuffix(strings.ToLower(path), ".gif"):
+		img, err = gif.Decode(srcFile)
+	}
+	return img, err
+}
+
+// encodeImage 按 path 的扩展名把图像编码写入 w
+func encodeImage(w io.Writer, path string, img image.Image, quality int) error {
+	switch {
+	case strings.HasSuffix(strings.ToLower(path), ".jpeg"), strings.HasSuffix(strings.ToLower(path), ".jpg"):
 		opts := jpeg.Options{Quality: quality}
-		err = jpeg.Encode(destFile, newImg, &opts)
-	case strings.HasSuffix(strings.ToLower(srcPath), ".png"):
-		err = png.Encode(destFile, newImg)
-	case strings.HasSuffix(strings.ToLower(srcPath), ".gif"):
-		err = gif.Encode(destFile, newImg, nil)
+		return jpeg.Encode(w, img, &opts)
+	case strings.HasSuffix(strings.ToLower(path), ".png"):
+		return png.Encode(w, img)
+	case strings.HasSuffix(strings.ToLower(path), ".gif"):
+		return gif.Encode(w, img, nil)
 	}
+	return nil
+}
+
+// countingWriter 只统计写入的字节数
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// estimateCompressedSize 按压缩配置在内存中压缩图像，返回压缩后的大小（字节），不修改文件
+func estimateCompressedSize(path string, quality, width int) (int64, error) {
+	img, err := decodeImage(path)
 	if err != nil {
-		return fmt.Errorf("压缩图像失败: %v", err)
+		return 0, fmt.Errorf("解码图像失败: %v", err)
 	}
 
-	return nil
+	var w countingWriter
+	if err := encodeImage(&w, path, resize.Resize(uint(width), 0, img, resize.Lanczos3), quality); err != nil {
+		return 0, fmt.Errorf("压缩图像失败: %v", err)
+	}
+	return w.n, nil
 }
 
-// HandleImages 处理 local_folder 下的所有图像文件
-func HandleImages(folder, compress, width string, picSize int, isScheduledTask bool) error {
-	quality, err := strconv.Atoi(compress)
+// defaultPicMemBudget 未配置 pic_mem_budget 时压缩使用的内存预算，单位MB
+const defaultPicMemBudget = 1024
+
+// picWorkersOrDefault 返回配置的压缩并发数，未配置时使用 CPU 核数
+func picWorkersOrDefault(config *Config) int {
+	if config.PicWorkers > 0 {
+		return config.PicWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// picMemBudgetOrDefault 返回配置的压缩内存预算（MB），未配置时返回默认值
+func picMemBudgetOrDefault(config *Config) int {
+	if config.PicMemBudget > 0 {
+		return config.PicMemBudget
+	}
+	return defaultPicMemBudget
+}
+
+// memoryBudget 限制同时解码的图片占用的内存总量，避免多张大 PNG 同时解码导致内存耗尽
+type memoryBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	total int64
+	used  int64
+}
+
+func newMemoryBudget(total int64) *memoryBudget {
+	b := &memoryBudget{total: total}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire 占用 n 字节预算，预算不足时等待；超过总预算的单张图片独占全部预算
+// 返回实际占用的字节数，ctx 取消时返回 ctx.Err()
+func (b *memoryBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	if n > b.total {
+		n = b.total
+	}
+
+	// ctx 取消时唤醒等待中的协程
+	stop := context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	})
+	defer stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.used+n > b.total {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		b.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	b.used += n
+	return n, nil
+}
+
+// release 归还 acquire 占用的预算
+func (b *memoryBudget) release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// estimateImageMemory 根据图片尺寸估算解码和缩放需要的内存（字节）
+// 解码后的原图按每像素 4 字节计算，缩放结果及中间缓冲按目标尺寸的两倍计算
+func estimateImageMemory(path string, width int) int64 {
+	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("压缩比率转换失败: %v", err)
+		return 0
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		// 无法读取尺寸的文件解码时会失败，不占用预算
+		return 0
 	}
 
+	src := int64(cfg.Width) * int64(cfg.Height) * 4
+	dstHeight := int64(cfg.Height) * int64(width) / int64(cfg.Width)
+	dst := int64(width) * dstHeight * 4 * 2
+	return src + dst
+}
+
+// HandleImages 处理 local_folder 下的所有图像文件
+// 文件由 workers 个协程并行压缩，同时解码的图片估算内存不超过 memBudgetMB
+func HandleImages(ctx context.Context, rc *pipeline.RunContext, folder string, quality, width, picSize, workers, memBudgetMB int) error {
 	if quality < 0 || quality > 100 {
 		return fmt.Errorf("压缩比率应在 0 到 100 之间")
 	}
 
-	widthInt, err := strconv.Atoi(width)
-	if err != nil {
-		return fmt.Errorf("宽度配置转换失败: %v", err)
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if memBudgetMB <= 0 {
+		memBudgetMB = defaultPicMemBudget
 	}
 
-	return filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+	// 先收集需要处理的文件，再分发给压缩协程
+	var files []string
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// 任务被取消时立即结束遍历
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 
 		// 只处理大于等于 picSize KB 的 JPEG、PNG 和 GIF 文件
 		if !info.IsDir() && info.Size() >= int64(picSize*1024) && (strings.HasSuffix(strings.ToLower(info.Name()), ".jpeg") ||
 			strings.HasSuffix(strings.ToLower(info.Name()), ".jpg") ||
 			strings.HasSuffix(strings.ToLower(info.Name()), ".png") ||
 			strings.HasSuffix(strings.ToLower(info.Name()), ".gif")) {
-
-			// 记录开始处理
-			logMsg := fmt.Sprintf("正在处理文件: %s", path)
-			if isScheduledTask {
-				SchedLogToFile(logMsg)
-			} else {
-				AutoLogToFile(logMsg)
+			// 已压缩过的文件（中断后恢复）不再重复压缩
+			if job, err := utils.GetJob(rc.Profile, info.Name()); err == nil && job != nil && job.Reached(utils.JobProcessed) {
+				return nil
 			}
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-			// 处理图片，如果失败则记录错误并继续
-			if err := CompressImage(path, quality, widthInt); err != nil {
-				errMsg := fmt.Sprintf("处理文件 %s 失败: %v", path, err)
-				if isScheduledTask {
-					SchedLogToFile(errMsg)
-				} else {
-					AutoLogToFile(errMsg)
-				}
-				return nil // 返回 nil 以继续处理下一个文件
+	// 模拟运行时复制阶段没有真正复制文件，将要复制的文件也按本地路径加入
+	if rc.DryRun {
+		for _, c := range dryRunPlanOf(rc).plannedCopies() {
+			if c.info.Size() >= int64(picSize*1024) && isImageFile(c.info.Name()) {
+				files = append(files, c.dst)
 			}
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
 
-			// 记录处理完成
-			successMsg := fmt.Sprintf("文件处理完成: %s", path)
-			if isScheduledTask {
-				SchedLogToFile(successMsg)
-			} else {
-				AutoLogToFile(successMsg)
+	if workers > len(files) {
+		workers = len(files)
+	}
+	rc.Log(fmt.Sprintf("共 %d 个文件待处理，使用 %d 个压缩协程，内存预算 %dMB", len(files), workers, memBudgetMB))
+
+	budget := newMemoryBudget(int64(memBudgetMB) * 1024 * 1024)
+	fileCh := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range fileCh {
+				compressWithBudget(ctx, rc, budget, path, quality, width)
 			}
+		}()
+	}
+
+	// 任务被取消时停止分发，正在压缩的文件会先完成
+feed:
+	for _, path := range files {
+		select {
+		case <-ctx.Done():
+			break feed
+		case fileCh <- path:
 		}
+	}
+	close(fileCh)
+	wg.Wait()
 
-		return nil
-	})
+	return ctx.Err()
+}
+
+// compressWithBudget 在内存预算内压缩单个文件，并记录耗时
+func compressWithBudget(ctx context.Context, rc *pipeline.RunContext, budget *memoryBudget, path string, quality, width int) {
+	// 模拟运行时将要复制的文件从远程源路径读取
+	readPath := path
+	if rc.DryRun {
+		readPath = dryRunPlanOf(rc).sourceOf(path)
+	}
+
+	reserved, err := budget.acquire(ctx, estimateImageMemory(readPath, width))
+	if err != nil {
+		return
+	}
+	defer budget.release(reserved)
+
+	if rc.DryRun {
+		dryRunCompress(rc, readPath, path, quality, width)
+		return
+	}
+
+	// 记录开始处理
+	rc.Log(fmt.Sprintf("正在处理文件: %s", path))
+
+	var before int64
+	if info, err := os.Stat(path); err == nil {
+		before = info.Size()
+	}
+
+	start := time.Now()
+	// 处理图片，如果失败则记录错误并继续
+	if err := CompressImage(rc.Profile, path, quality, width); err != nil {
+		rc.Log(fmt.Sprintf("处理文件 %s 失败: %v", path, err))
+		if utils.IsPathExists(path) {
+			recordJobFailure(rc, path, fmt.Errorf("压缩失败: %v", err))
+		} else {
+			// 无法解码的文件已被删除
+			runStatsOf(rc).addError()
+			runStatsOf(rc).addDeleted()
+		}
+		return
+	}
+	runStatsOf(rc).addCompressed()
+	if err := utils.SetJobState(rc.Profile, filepath.Base(path), filepath.Base(filepath.Dir(path)), path, utils.JobProcessed); err != nil {
+		rc.Log(fmt.Sprintf("记录文件处理状态失败: %v", err))
+	}
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var after int64
+	if info, err := os.Stat(path); err == nil {
+		after = info.Size()
+	}
+
+	// 记录处理完成
+	rc.Log(fmt.Sprintf("文件处理完成: %s，%dKB → %dKB，耗时 %v", path, before/1024, after/1024, elapsed))
+}
+
+// dryRunCompress 模拟运行时估算压缩后的大小，不覆盖文件也不更新处理记录
+func dryRunCompress(rc *pipeline.RunContext, readPath, path string, quality, width int) {
+	var before int64
+	if info, err := os.Stat(readPath); err == nil {
+		before = info.Size()
+	}
+
+	after, err := estimateCompressedSize(readPath, quality, width)
+	if err != nil {
+		dryRunLog(rc, "文件 %s 无法压缩，将删除此文件: %v", path, err)
+		runStatsOf(rc).addError()
+		runStatsOf(rc).addDeleted()
+		return
+	}
+	dryRunPlanOf(rc).setCompressed(path, after)
+	runStatsOf(rc).addCompressed()
+	dryRunLog(rc, "将压缩文件: %s，%dKB → %dKB", path, before/1024, after/1024)
 }
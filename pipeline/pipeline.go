@@ -0,0 +1,231 @@
+// Package pipeline 定义 复制 → 压缩 → 上传 → 推送 流水线的运行框架
+// 界面、命令行都通过 Run 驱动同一组阶段，日志和进度通过 Observer 事件回调输出
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TaskType 任务类型
+type TaskType int
+
+const (
+	TaskAuto  TaskType = iota // 自动任务：处理今天和昨天的文件夹
+	TaskSched                 // 计划任务：处理配置的日期范围
+)
+
+// String 返回任务类型名称
+func (t TaskType) String() string {
+	switch t {
+	case TaskAuto:
+		return "auto"
+	case TaskSched:
+		return "sched"
+	default:
+		return fmt.Sprintf("task(%d)", int(t))
+	}
+}
+
+// EventKind 事件类型
+type EventKind int
+
+const (
+	EventRunStart    EventKind = iota // 流水线开始
+	EventStageStart                   // 阶段开始
+	EventStageDone                    // 阶段成功完成
+	EventStageFailed                  // 阶段失败
+	EventLog                          // 阶段内部输出的日志
+	EventRunDone                      // 流水线结束
+)
+
+// Event 流水线运行过程中产生的事件
+type Event struct {
+	Kind    EventKind
+	Task    TaskType
+	Profile string // 配置方案名称，默认方案为空
+	Stage   string // 产生事件的阶段名称，流水线级事件为空
+	Message string // EventLog 的日志内容
+	Err     error  // EventStageFailed / EventRunDone 的错误
+	Time    time.Time
+}
+
+// Observer 事件回调，由界面、命令行或测试提供
+type Observer func(Event)
+
+// RunContext 一次流水线运行的上下文，在各阶段之间共享
+type RunContext struct {
+	Task         TaskType // 任务类型
+	Profile      string   // 配置方案名称，默认方案为空；复制记录和执行记录按方案区分
+	OrderNumbers string   // 计划任务需要匹配的编号（逗号分割），为空表示不过滤
+	DryRun       bool     // 模拟运行：只报告将要执行的操作，不修改磁盘、存储桶和 API2
+
+	observer Observer
+	stage    string
+
+	mu     sync.Mutex
+	values map[string]interface{} // 阶段之间共享的数据
+}
+
+// IsAuto 是否为自动任务
+func (rc *RunContext) IsAuto() bool {
+	return rc.Task == TaskAuto
+}
+
+// IsSched 是否为计划任务
+func (rc *RunContext) IsSched() bool {
+	return rc.Task == TaskSched
+}
+
+// LoadOrStore 返回 key 对应的共享数据，不存在时保存并返回 value，可在多个协程中调用
+func (rc *RunContext) LoadOrStore(key string, value interface{}) interface{} {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if v, ok := rc.values[key]; ok {
+		return v
+	}
+	if rc.values == nil {
+		rc.values = make(map[string]interface{})
+	}
+	rc.values[key] = value
+	return value
+}
+
+// Log 输出一条日志事件
+func (rc *RunContext) Log(message string) {
+	rc.emit(Event{Kind: EventLog, Stage: rc.stage, Message: message})
+}
+
+// Logf 格式化输出一条日志事件
+func (rc *RunContext) Logf(format string, args ...interface{}) {
+	rc.Log(fmt.Sprintf(format, args...))
+}
+
+// emit 补全事件公共字段并交给观察者
+func (rc *RunContext) emit(ev Event) {
+	if rc.observer == nil {
+		return
+	}
+	ev.Task = rc.Task
+	ev.Profile = rc.Profile
+	ev.Time = time.Now()
+	rc.observer(ev)
+}
+
+// Stage 流水线中的一个阶段
+type Stage interface {
+	// Name 阶段名称，用于日志，例如 "扫描和复制文件"
+	Name() string
+	// Run 执行阶段；返回 Abort 包装的错误时终止后续阶段
+	Run(ctx context.Context, rc *RunContext) error
+}
+
+// stageFunc 以函数实现的阶段
+type stageFunc struct {
+	name string
+	fn   func(ctx context.Context, rc *RunContext) error
+}
+
+func (s stageFunc) Name() string { return s.name }
+
+func (s stageFunc) Run(ctx context.Context, rc *RunContext) error { return s.fn(ctx, rc) }
+
+// NewStage 使用名称和函数创建阶段
+func NewStage(name string, fn func(ctx context.Context, rc *RunContext) error) Stage {
+	return stageFunc{name: name, fn: fn}
+}
+
+// abortError 标记需要终止流水线的阶段错误
+type abortError struct {
+	err error
+}
+
+func (e *abortError) Error() string { return e.err.Error() }
+
+func (e *abortError) Unwrap() error { return e.err }
+
+// Abort 包装阶段错误，流水线遇到该错误后不再执行后续阶段
+func Abort(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &abortError{err: err}
+}
+
+// IsAborted 判断错误是否导致了流水线提前终止
+func IsAborted(err error) bool {
+	var ae *abortError
+	return errors.As(err, &ae)
+}
+
+// Sleep 等待 d 时长，ctx 取消时提前返回 ctx.Err()
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Config 一次流水线运行的配置
+type Config struct {
+	Task         TaskType
+	Profile      string
+	OrderNumbers string
+	DryRun       bool
+	Stages       []Stage
+	Observer     Observer
+	Values       map[string]interface{} // 运行前放入 RunContext 的共享数据，调用方可在运行结束后读取
+}
+
+// Run 按顺序执行所有阶段，普通阶段错误只记录并继续，Abort 错误或 ctx 取消时提前结束
+// 返回本次运行中所有阶段错误的合并结果
+func Run(ctx context.Context, cfg Config) error {
+	rc := &RunContext{
+		Task:         cfg.Task,
+		Profile:      cfg.Profile,
+		OrderNumbers: cfg.OrderNumbers,
+		DryRun:       cfg.DryRun,
+		observer:     cfg.Observer,
+	}
+	for key, value := range cfg.Values {
+		rc.LoadOrStore(key, value)
+	}
+
+	rc.emit(Event{Kind: EventRunStart})
+
+	var errs []error
+	for _, stage := range cfg.Stages {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		rc.stage = stage.Name()
+		rc.emit(Event{Kind: EventStageStart, Stage: rc.stage})
+
+		err := stage.Run(ctx, rc)
+		if err != nil {
+			rc.emit(Event{Kind: EventStageFailed, Stage: rc.stage, Err: err})
+			errs = append(errs, fmt.Errorf("%s失败: %w", rc.stage, err))
+			if IsAborted(err) || ctx.Err() != nil {
+				break
+			}
+		} else {
+			rc.emit(Event{Kind: EventStageDone, Stage: rc.stage})
+		}
+	}
+	rc.stage = ""
+
+	runErr := errors.Join(errs...)
+	rc.emit(Event{Kind: EventRunDone, Err: runErr})
+	return runErr
+}
@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var kindNames = map[EventKind]string{
+	EventRunStart:    "run-start",
+	EventStageStart:  "start",
+	EventStageDone:   "done",
+	EventStageFailed: "failed",
+	EventLog:         "log",
+	EventRunDone:     "run-done",
+}
+
+// recorder 记录观察者收到的事件，格式为 "类型 阶段 内容"
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) observe(ev Event) { r.events = append(r.events, ev) }
+
+func (r *recorder) trace() []string {
+	var trace []string
+	for _, ev := range r.events {
+		s := kindNames[ev.Kind]
+		if ev.Stage != "" {
+			s += " " + ev.Stage
+		}
+		if ev.Message != "" {
+			s += " " + ev.Message
+		}
+		trace = append(trace, s)
+	}
+	return trace
+}
+
+// last 返回最后一个事件
+func (r *recorder) last() Event {
+	return r.events[len(r.events)-1]
+}
+
+func TestRunEventOrder(t *testing.T) {
+	errUpload := errors.New("上传失败")
+	var rec recorder
+	err := Run(context.Background(), Config{
+		Task:    TaskSched,
+		Profile: "line-2",
+		Stages: []Stage{
+			NewStage("复制", func(ctx context.Context, rc *RunContext) error {
+				rc.Logf("复制 %d 个文件", 3)
+				return nil
+			}),
+			NewStage("上传", func(ctx context.Context, rc *RunContext) error { return errUpload }),
+			NewStage("推送", func(ctx context.Context, rc *RunContext) error { return nil }),
+		},
+		Observer: rec.observe,
+	})
+
+	want := []string{
+		"run-start",
+		"start 复制", "log 复制 复制 3 个文件", "done 复制",
+		"start 上传", "failed 上传",
+		"start 推送", "done 推送",
+		"run-done",
+	}
+	if got := rec.trace(); !reflect.DeepEqual(got, want) {
+		t.Errorf("事件顺序 = %q，应为 %q", got, want)
+	}
+	for _, ev := range rec.events {
+		if ev.Task != TaskSched || ev.Profile != "line-2" || ev.Time.IsZero() {
+			t.Errorf("事件 %+v 缺少任务类型、方案或时间", ev)
+		}
+	}
+
+	// 普通阶段错误不终止流水线，但包含在返回的错误中
+	if !errors.Is(err, errUpload) || IsAborted(err) {
+		t.Errorf("Run = %v，应包含 %v 且不是 Abort", err, errUpload)
+	}
+	if last := rec.last(); !errors.Is(last.Err, errUpload) {
+		t.Errorf("run-done 的错误 = %v，应包含 %v", last.Err, errUpload)
+	}
+}
+
+func TestRunAbort(t *testing.T) {
+	errSource := errors.New("源文件夹不存在")
+	var rec recorder
+	ran := false
+	err := Run(context.Background(), Config{
+		Stages: []Stage{
+			NewStage("复制", func(ctx context.Context, rc *RunContext) error { return Abort(errSource) }),
+			NewStage("上传", func(ctx context.Context, rc *RunContext) error { ran = true; return nil }),
+		},
+		Observer: rec.observe,
+	})
+
+	if ran {
+		t.Error("Abort 之后不应执行后续阶段")
+	}
+	if !IsAborted(err) || !errors.Is(err, errSource) {
+		t.Errorf("Run = %v，应为 Abort 包装的 %v", err, errSource)
+	}
+	want := []string{"run-start", "start 复制", "failed 复制", "run-done"}
+	if got := rec.trace(); !reflect.DeepEqual(got, want) {
+		t.Errorf("事件顺序 = %q，应为 %q", got, want)
+	}
+	if Abort(nil) != nil {
+		t.Error("Abort(nil) 应返回 nil")
+	}
+}
+
+func TestRunCanceledMidStage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var rec recorder
+	ran := false
+	done := make(chan error, 1)
+	started := make(chan struct{})
+	go func() {
+		done <- Run(ctx, Config{
+			Stages: []Stage{
+				NewStage("上传", func(ctx context.Context, rc *RunContext) error {
+					close(started)
+					<-ctx.Done()
+					return ctx.Err()
+				}),
+				NewStage("推送", func(ctx context.Context, rc *RunContext) error { ran = true; return nil }),
+			},
+			Observer: rec.observe,
+		})
+	}()
+
+	<-started
+	cancel()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ctx 取消后流水线没有结束")
+	}
+
+	if ran {
+		t.Error("ctx 取消后不应执行后续阶段")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v，应包含 context.Canceled", err)
+	}
+	want := []string{"run-start", "start 上传", "failed 上传", "run-done"}
+	if got := rec.trace(); !reflect.DeepEqual(got, want) {
+		t.Errorf("事件顺序 = %q，应为 %q", got, want)
+	}
+	if failed := rec.events[2]; !errors.Is(failed.Err, context.Canceled) {
+		t.Errorf("阶段失败事件的错误 = %v，应为 context.Canceled", failed.Err)
+	}
+}
+
+func TestRunCanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var rec recorder
+	err := Run(ctx, Config{
+		Stages: []Stage{
+			NewStage("复制", func(ctx context.Context, rc *RunContext) error {
+				return fmt.Errorf("不应执行")
+			}),
+		},
+		Observer: rec.observe,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v，应包含 context.Canceled", err)
+	}
+	want := []string{"run-start", "run-done"}
+	if got := rec.trace(); !reflect.DeepEqual(got, want) {
+		t.Errorf("事件顺序 = %q，应为 %q", got, want)
+	}
+}
+
+func TestRunValues(t *testing.T) {
+	var got interface{}
+	err := Run(context.Background(), Config{
+		Values: map[string]interface{}{"stats": 1},
+		Stages: []Stage{
+			NewStage("复制", func(ctx context.Context, rc *RunContext) error {
+				got = rc.LoadOrStore("stats", 2)
+				return nil
+			}),
+		},
+	})
+	if err != nil || got != 1 {
+		t.Errorf("LoadOrStore = %v, %v，应返回调用方放入的值 1", got, err)
+	}
+}
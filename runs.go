@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go-uposs/pipeline"
+	"go-uposs/utils"
+)
+
+// 任务周期的触发方式，记录在执行记录中
+const (
+	runTriggerManual   = "manual"   // 在界面点击开始或通过命令行执行
+	runTriggerStartup  = "startup"  // 程序启动时自动开始任务
+	runTriggerSchedule = "schedule" // 按执行间隔或 cron 表达式执行
+	runTriggerWatch    = "watch"    // 监听模式检测到文件变化
+)
+
+// runStatsKey 周期统计在 RunContext 中的键
+const runStatsKey = "runStats"
+
+// runStats 一次任务周期的统计，由各阶段的多个协程并发累加
+// 模拟运行时统计的是将要处理的数量
+type runStats struct {
+	scanned       int64
+	copied        int64
+	compressed    int64
+	uploaded      int64
+	skipped       int64
+	pushed        int64
+	deleted       int64
+	bytesCopied   int64
+	bytesUploaded int64
+	errors        int64
+}
+
+// runStatsOf 返回本次运行的周期统计
+func runStatsOf(rc *pipeline.RunContext) *runStats {
+	return rc.LoadOrStore(runStatsKey, &runStats{}).(*runStats)
+}
+
+func (s *runStats) addScanned() { atomic.AddInt64(&s.scanned, 1) }
+
+func (s *runStats) addCopied(bytes int64) {
+	atomic.AddInt64(&s.copied, 1)
+	atomic.AddInt64(&s.bytesCopied, bytes)
+}
+
+func (s *runStats) addCompressed() { atomic.AddInt64(&s.compressed, 1) }
+
+func (s *runStats) addUploaded(bytes int64) {
+	atomic.AddInt64(&s.uploaded, 1)
+	atomic.AddInt64(&s.bytesUploaded, bytes)
+}
+
+func (s *runStats) addSkipped() { atomic.AddInt64(&s.skipped, 1) }
+
+func (s *runStats) addPushed() { atomic.AddInt64(&s.pushed, 1) }
+
+func (s *runStats) addDeleted() { atomic.AddInt64(&s.deleted, 1) }
+
+func (s *runStats) addError() { atomic.AddInt64(&s.errors, 1) }
+
+// summary 返回周期统计的文字描述
+func (s *runStats) summary() string {
+	return fmt.Sprintf("扫描 %d 个文件，复制 %d 个（%.2fMB），压缩 %d 个，上传 %d 个（%.2fMB），跳过相同内容 %d 个，推送 API2 %d 次，删除本地文件 %d 个，错误 %d 个",
+		atomic.LoadInt64(&s.scanned), atomic.LoadInt64(&s.copied), float64(atomic.LoadInt64(&s.bytesCopied))/(1024*1024),
+		atomic.LoadInt64(&s.compressed), atomic.LoadInt64(&s.uploaded), float64(atomic.LoadInt64(&s.bytesUploaded))/(1024*1024),
+		atomic.LoadInt64(&s.skipped), atomic.LoadInt64(&s.pushed), atomic.LoadInt64(&s.deleted), atomic.LoadInt64(&s.errors))
+}
+
+// runPipeline 执行流水线并把本次周期写入执行记录表，记录失败不影响任务执行
+func runPipeline(ctx context.Context, cfg pipeline.Config, trigger string) error {
+	logToFile := taskLogFunc(cfg.Task, cfg.Profile)
+	stats := &runStats{}
+	cfg.Values = map[string]interface{}{runStatsKey: stats}
+
+	// 阶段失败计入错误数
+	observer := cfg.Observer
+	cfg.Observer = func(ev pipeline.Event) {
+		if ev.Kind == pipeline.EventStageFailed && !errors.Is(ev.Err, context.Canceled) {
+			stats.addError()
+		}
+		if observer != nil {
+			observer(ev)
+		}
+	}
+
+	startedAt := time.Now()
+	id, recordErr := utils.StartRun(cfg.Task.String(), cfg.Profile, trigger, cfg.DryRun, startedAt)
+	if recordErr != nil {
+		logToFile(fmt.Sprintf("记录执行开始失败: %v", recordErr))
+	}
+
+	err := pipeline.Run(ctx, cfg)
+
+	status := utils.RunOK
+	switch {
+	case ctx.Err() != nil:
+		status = utils.RunCanceled
+	case err != nil:
+		status = utils.RunFailed
+	}
+	if cfg.DryRun {
+		logToFile(fmt.Sprintf("[模拟] 本次周期将%s", stats.summary()))
+	} else {
+		logToFile(fmt.Sprintf("本次周期: %s", stats.summary()))
+	}
+
+	if recordErr != nil {
+		return err
+	}
+	record := &utils.RunRecord{
+		ID:              id,
+		FinishedAt:      time.Now(),
+		Status:          status,
+		FilesScanned:    atomic.LoadInt64(&stats.scanned),
+		FilesCopied:     atomic.LoadInt64(&stats.copied),
+		FilesCompressed: atomic.LoadInt64(&stats.compressed),
+		FilesUploaded:   atomic.LoadInt64(&stats.uploaded),
+		FilesSkipped:    atomic.LoadInt64(&stats.skipped),
+		FilesPushed:     atomic.LoadInt64(&stats.pushed),
+		FilesDeleted:    atomic.LoadInt64(&stats.deleted),
+		BytesCopied:     atomic.LoadInt64(&stats.bytesCopied),
+		BytesUploaded:   atomic.LoadInt64(&stats.bytesUploaded),
+		Errors:          atomic.LoadInt64(&stats.errors),
+	}
+	if err != nil {
+		record.LastError = err.Error()
+	}
+	if finishErr := utils.FinishRun(record); finishErr != nil {
+		logToFile(fmt.Sprintf("记录执行结果失败: %v", finishErr))
+	}
+	return err
+}
+
+// exportRunsJSON 将执行记录以 JSON 数组写入 path
+func exportRunsJSON(path string, runs []utils.RunRecord) error {
+	data, err := marshalRuns(runs)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	return nil
+}
+
+// marshalRuns 将执行记录编码为缩进的 JSON 数组，没有记录时输出 []
+func marshalRuns(runs []utils.RunRecord) ([]byte, error) {
+	if runs == nil {
+		runs = []utils.RunRecord{}
+	}
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("编码执行记录失败: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// runTriggerName 返回触发方式的中文名称，用于界面显示
+func runTriggerName(trigger string) string {
+	switch trigger {
+	case runTriggerManual:
+		return "手动"
+	case runTriggerStartup:
+		return "启动"
+	case runTriggerSchedule:
+		return "定时"
+	case runTriggerWatch:
+		return "监听"
+	default:
+		return trigger
+	}
+}
+
+// runStatusName 返回执行状态的中文名称，用于界面显示
+func runStatusName(status string) string {
+	switch status {
+	case utils.RunOK:
+		return "成功"
+	case utils.RunFailed:
+		return "失败"
+	case utils.RunCanceled:
+		return "已停止"
+	case utils.RunRunning:
+		return "执行中"
+	default:
+		return status
+	}
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore 本地文件夹存储，用于离线测试和不能访问对象存储的现场
+// 存储桶对应 root 下的子文件夹，key 对应其中的相对路径
+type LocalStore struct {
+	root   string
+	bucket string
+}
+
+// NewLocalStore 创建以 root 为根目录的本地存储，bucket 为 root 下的子文件夹
+func NewLocalStore(root, bucket string) *LocalStore {
+	return &LocalStore{root: root, bucket: bucket}
+}
+
+func (s *LocalStore) Type() string   { return "local" }
+func (s *LocalStore) Bucket() string { return s.bucket }
+
+// dir 返回存储桶文件夹
+func (s *LocalStore) dir() string {
+	return filepath.Join(s.root, s.bucket)
+}
+
+// path 返回 key 对应的文件路径
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir(), filepath.FromSlash(CleanKey(key)))
+}
+
+func (s *LocalStore) BucketExists(ctx context.Context) (bool, error) {
+	info, err := os.Stat(s.dir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (s *LocalStore) MakeBucket(ctx context.Context) error {
+	return os.MkdirAll(s.dir(), 0755)
+}
+
+// Put 先复制到同目录的临时文件再重命名，写入中断时不会留下不完整的对象
+func (s *LocalStore) Put(ctx context.Context, key, filePath string) error {
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (s *LocalStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	p := s.path(key)
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	etag, err := FileMD5(p)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: CleanKey(key), Size: info.Size(), ETag: etag, LastModified: info.ModTime()}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	prefix = strings.TrimPrefix(strings.ReplaceAll(prefix, "\\", "/"), "/")
+	err := filepath.WalkDir(s.dir(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir(), p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+// Presign 本地文件没有临时访问地址，返回 file:// 地址，expiry 不生效
+func (s *LocalStore) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	p, err := filepath.Abs(s.path(key))
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String(), nil
+}
+
+// Health 检查根目录存在且可以写入
+func (s *LocalStore) Health(ctx context.Context) (string, error) {
+	if s.root == "" {
+		return "", fmt.Errorf("未配置本地存储文件夹")
+	}
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return "", fmt.Errorf("本地存储文件夹不可用: %v", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s 不是文件夹", s.root)
+	}
+	f, err := os.CreateTemp(s.root, ".health-*")
+	if err != nil {
+		return "", fmt.Errorf("本地存储文件夹不可写入: %v", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return fmt.Sprintf("本地存储文件夹 %s 可以写入", s.root), nil
+}
+
+func (s *LocalStore) Close() error { return nil }
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// MinioStore 基于 minio 客户端的 S3 兼容存储
+type MinioStore struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewMinioStore 使用已初始化的 minio 客户端创建存储，bucket 为上传的存储桶
+func NewMinioStore(client *minio.Client, bucket string) *MinioStore {
+	return &MinioStore{client: client, bucket: bucket}
+}
+
+// Client 返回底层的 minio 客户端
+func (s *MinioStore) Client() *minio.Client {
+	return s.client
+}
+
+func (s *MinioStore) Type() string   { return "minio" }
+func (s *MinioStore) Bucket() string { return s.bucket }
+
+func (s *MinioStore) BucketExists(ctx context.Context) (bool, error) {
+	return s.client.BucketExists(ctx, s.bucket)
+}
+
+func (s *MinioStore) MakeBucket(ctx context.Context) error {
+	return s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{})
+}
+
+func (s *MinioStore) Put(ctx context.Context, key, filePath string) error {
+	_, err := s.client.FPutObject(ctx, s.bucket, CleanKey(key), filePath, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *MinioStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, CleanKey(key), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, err
+	}
+	return objectInfo(info), nil
+}
+
+func (s *MinioStore) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, CleanKey(key), minio.RemoveObjectOptions{})
+}
+
+func (s *MinioStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: strings.TrimPrefix(prefix, "/"), Recursive: true}) {
+		if info.Err != nil {
+			return objects, info.Err
+		}
+		objects = append(objects, objectInfo(info))
+	}
+	return objects, nil
+}
+
+func (s *MinioStore) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, CleanKey(key), expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Health 列出存储桶，确认端点可以访问、密钥有效
+func (s *MinioStore) Health(ctx context.Context) (string, error) {
+	buckets, err := s.client.ListBuckets(ctx)
+	if err != nil {
+		return "", fmt.Errorf("minio 连接测试失败: %v", err)
+	}
+	names := make([]string, len(buckets))
+	for i, b := range buckets {
+		names[i] = fmt.Sprintf("%s (创建于: %s)", b.Name, b.CreationDate.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("找到 %d 个存储桶: %s", len(buckets), strings.Join(names, ", ")), nil
+}
+
+func (s *MinioStore) Close() error { return nil }
+
+// objectInfo 转换 minio 的对象信息
+func objectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{Key: info.Key, Size: info.Size, ETag: info.ETag, LastModified: info.LastModified}
+}
@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPOptions SFTP 服务器的连接参数
+type SFTPOptions struct {
+	Host     string // host 或 host:port，未写端口时使用 22
+	User     string
+	Password string
+	KeyFile  string // 私钥文件，与密码至少设置一个
+	HostKey  string // 服务器公钥指纹，例如 SHA256:...，与实际不一致时拒绝连接
+	Dir      string // 服务器上的根目录，存储桶为其中的子文件夹，为空时使用登录后的目录
+	Timeout  time.Duration
+}
+
+// SFTPStore SFTP 服务器存储，连接在第一次操作时建立，Close 后下次操作重新连接
+type SFTPStore struct {
+	opts   SFTPOptions
+	bucket string
+
+	mu   sync.Mutex
+	ssh  *ssh.Client
+	sftp *sftp.Client
+}
+
+// NewSFTPStore 创建 SFTP 存储
+func NewSFTPStore(opts SFTPOptions, bucket string) *SFTPStore {
+	if _, _, err := net.SplitHostPort(opts.Host); err != nil {
+		opts.Host = net.JoinHostPort(opts.Host, "22")
+	}
+	return &SFTPStore{opts: opts, bucket: bucket}
+}
+
+func (s *SFTPStore) Type() string   { return "sftp" }
+func (s *SFTPStore) Bucket() string { return s.bucket }
+
+// HostKeyMismatchError 服务器公钥指纹与配置不一致，或者没有配置指纹
+type HostKeyMismatchError struct {
+	Host        string
+	Fingerprint string // 服务器实际的公钥指纹
+	Expected    string // 配置的指纹
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("未配置 sftp_host_key，服务器 %s 的公钥指纹为 %s，确认是该服务器后填入此指纹", e.Host, e.Fingerprint)
+	}
+	return fmt.Sprintf("服务器 %s 的公钥指纹为 %s，与配置的 sftp_host_key %s 不一致，服务器可能被替换或冒充", e.Host, e.Fingerprint, e.Expected)
+}
+
+// client 返回已连接的 SFTP 客户端，未连接时先连接
+func (s *SFTPStore) client() (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sftp != nil {
+		return s.sftp, nil
+	}
+
+	var auth []ssh.AuthMethod
+	if s.opts.KeyFile != "" {
+		data, err := os.ReadFile(s.opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥文件失败: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥文件失败: %v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.opts.Password != "" {
+		auth = append(auth, ssh.Password(s.opts.Password))
+	}
+
+	conn, err := ssh.Dial("tcp", s.opts.Host, &ssh.ClientConfig{
+		User: s.opts.User,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if fp := ssh.FingerprintSHA256(key); fp != s.opts.HostKey {
+				return &HostKeyMismatchError{Host: s.opts.Host, Fingerprint: fp, Expected: s.opts.HostKey}
+			}
+			return nil
+		},
+		Timeout: s.opts.Timeout,
+	})
+	if err != nil {
+		var mismatch *HostKeyMismatchError
+		if errors.As(err, &mismatch) {
+			return nil, mismatch
+		}
+		return nil, fmt.Errorf("连接 SFTP 服务器失败: %v", err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("启动 SFTP 会话失败: %v", err)
+	}
+	s.ssh, s.sftp = conn, client
+	return client, nil
+}
+
+// path 返回 key 在服务器上的路径
+func (s *SFTPStore) path(key string) string {
+	return path.Join(s.opts.Dir, s.bucket, CleanKey(key))
+}
+
+func (s *SFTPStore) BucketExists(ctx context.Context) (bool, error) {
+	c, err := s.client()
+	if err != nil {
+		return false, err
+	}
+	info, err := c.Stat(path.Join(s.opts.Dir, s.bucket))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (s *SFTPStore) MakeBucket(ctx context.Context) error {
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	return c.MkdirAll(path.Join(s.opts.Dir, s.bucket))
+}
+
+// Put 先写入同目录的临时文件再重命名，写入中断时不会留下不完整的文件
+func (s *SFTPStore) Put(ctx context.Context, key, filePath string) error {
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	dst := s.path(key)
+	if err := c.MkdirAll(path.Dir(dst)); err != nil {
+		return err
+	}
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path.Join(path.Dir(dst), ".upload-"+path.Base(dst))
+	f, err := c.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		c.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		c.Remove(tmp)
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		c.Remove(tmp)
+		return err
+	}
+	// 不支持 posix-rename 扩展的服务器不能覆盖已存在的文件，先删除再重命名
+	if err := c.PosixRename(tmp, dst); err != nil {
+		c.Remove(dst)
+		if err := c.Rename(tmp, dst); err != nil {
+			c.Remove(tmp)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SFTPStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	c, err := s.client()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := c.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: CleanKey(key), Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+func (s *SFTPStore) Delete(ctx context.Context, key string) error {
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	err = c.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *SFTPStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	c, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	root := path.Join(s.opts.Dir, s.bucket)
+	prefix = strings.TrimPrefix(strings.ReplaceAll(prefix, "\\", "/"), "/")
+	var objects []ObjectInfo
+	w := c.Walk(root)
+	for w.Step() {
+		if err := ctx.Err(); err != nil {
+			return objects, err
+		}
+		if err := w.Err(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return objects, err
+		}
+		info := w.Stat()
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".upload-") {
+			continue
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(w.Path(), root), "/")
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+	}
+	return objects, nil
+}
+
+// Presign SFTP 没有临时访问地址，返回 sftp:// 地址，expiry 不生效
+func (s *SFTPStore) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return fmt.Sprintf("sftp://%s@%s%s", s.opts.User, s.opts.Host, path.Join("/", s.path(key))), nil
+}
+
+// Health 连接服务器并检查根目录，确认账号和服务器指纹有效
+func (s *SFTPStore) Health(ctx context.Context) (string, error) {
+	c, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	dir := s.opts.Dir
+	if dir == "" {
+		if dir, err = c.Getwd(); err != nil {
+			return "", fmt.Errorf("读取 SFTP 登录目录失败: %v", err)
+		}
+	}
+	info, err := c.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("SFTP 根目录 %s 不可用: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("SFTP 根目录 %s 不是文件夹", dir)
+	}
+	return fmt.Sprintf("SFTP 服务器 %s 已连接，根目录 %s", s.opts.Host, dir), nil
+}
+
+// Close 关闭 SFTP 会话和 SSH 连接
+func (s *SFTPStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sftp == nil {
+		return nil
+	}
+	s.sftp.Close()
+	err := s.ssh.Close()
+	s.ssh, s.sftp = nil, nil
+	return err
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// newSFTPServer 启动本进程中密码认证的 SFTP 服务器，返回地址、服务器公钥指纹和根目录
+func newSFTPServer(t *testing.T) (host, fingerprint, root string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == testUser && string(pass) == testPassword {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("密码不正确")
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+	return ln.Addr().String(), ssh.FingerprintSHA256(signer.PublicKey()), filepath.ToSlash(t.TempDir())
+}
+
+// serveSFTP 处理一个 SSH 连接中的 sftp 子系统请求
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "只支持 session")
+			continue
+		}
+		ch, requests, err := newCh.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				req.Reply(req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}()
+		server, err := sftp.NewServer(ch)
+		if err != nil {
+			ch.Close()
+			continue
+		}
+		go func() {
+			server.Serve()
+			server.Close()
+		}()
+	}
+}
+
+func TestSFTPStore(t *testing.T) {
+	host, fingerprint, root := newSFTPServer(t)
+	testObjectStore(t, NewSFTPStore(SFTPOptions{
+		Host: host, User: testUser, Password: testPassword, HostKey: fingerprint, Dir: root, Timeout: 10 * time.Second,
+	}, "test"))
+}
+
+func TestSFTPStoreHostKeyMismatch(t *testing.T) {
+	host, fingerprint, root := newSFTPServer(t)
+	for _, expected := range []string{"SHA256:wrong", ""} {
+		store := NewSFTPStore(SFTPOptions{Host: host, User: testUser, Password: testPassword, HostKey: expected, Dir: root}, "test")
+		_, err := store.Health(context.Background())
+		var mismatch *HostKeyMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("指纹为 %q 时 Health = %v，应为 HostKeyMismatchError", expected, err)
+		}
+		if mismatch.Fingerprint != fingerprint || mismatch.Expected != expected {
+			t.Errorf("HostKeyMismatchError = %+v，服务器指纹应为 %s", mismatch, fingerprint)
+		}
+		store.Close()
+	}
+}
+
+func TestSFTPStoreWrongPassword(t *testing.T) {
+	host, fingerprint, root := newSFTPServer(t)
+	store := NewSFTPStore(SFTPOptions{Host: host, User: testUser, Password: "wrong", HostKey: fingerprint, Dir: root}, "test")
+	defer store.Close()
+	if _, err := store.Health(context.Background()); err == nil {
+		t.Fatal("密码错误时 Health 应返回错误")
+	}
+}
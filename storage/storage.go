@@ -0,0 +1,88 @@
+// Package storage 定义上传目标的对象存储接口
+// MinIO（S3 兼容）、本地文件夹、WebDAV 和 SFTP 各自实现 ObjectStore，上传流程只依赖接口，由配置中的 storage_type 选择
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound Stat 查询的对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// ObjectInfo 对象的基本信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// ObjectStore 对象存储：一个存储桶（或等价的根目录）中按 key 存取文件
+// key 使用 / 分隔，例如 machine-01/2025.01.01/A1.jpg
+type ObjectStore interface {
+	// Type 返回存储类型，例如 minio、local
+	Type() string
+	// Bucket 返回存储桶名称
+	Bucket() string
+
+	// BucketExists 检查存储桶是否存在
+	BucketExists(ctx context.Context) (bool, error)
+	// MakeBucket 创建存储桶
+	MakeBucket(ctx context.Context) error
+
+	// Put 上传本地文件 filePath 到 key，已存在时覆盖
+	Put(ctx context.Context, key, filePath string) error
+	// Stat 返回 key 的信息，不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete 删除 key，不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// List 列出 key 以 prefix 开头的对象
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Presign 返回 key 在 expiry 时间内有效的临时访问地址
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+
+	// Health 检查存储是否可用，返回用于日志的说明
+	Health(ctx context.Context) (string, error)
+	// Close 关闭连接，上传批次结束后调用
+	Close() error
+}
+
+// CleanKey 规范化对象 key：反斜杠改为 /，去掉开头的 / 和 . 、.. 路径段
+func CleanKey(key string) string {
+	key = strings.ReplaceAll(key, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
+
+// FileMD5 返回文件内容的 MD5（十六进制），与 S3 单次上传的 ETag 一致
+func FileMD5(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ETagMD5 ETag 为内容的 MD5 时返回小写的 MD5；分片上传（带 -N 后缀）、WebDAV 等服务器自定义的 ETag 返回 false
+func ETagMD5(etag string) (string, bool) {
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	if len(etag) != 32 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return "", false
+	}
+	return etag, true
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testObjectStore 检查 ObjectStore 的通用行为：创建存储桶、上传覆盖、查询、列出、临时地址和删除
+func testObjectStore(t *testing.T, store ObjectStore) {
+	t.Helper()
+	ctx := context.Background()
+	defer store.Close()
+
+	if _, err := store.Health(ctx); err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	if exists, err := store.BucketExists(ctx); err != nil || exists {
+		t.Fatalf("BucketExists = %v, %v，应为 false", exists, err)
+	}
+	if err := store.MakeBucket(ctx); err != nil {
+		t.Fatalf("MakeBucket: %v", err)
+	}
+	if exists, err := store.BucketExists(ctx); err != nil || !exists {
+		t.Fatalf("BucketExists = %v, %v，应为 true", exists, err)
+	}
+
+	src := filepath.Join(t.TempDir(), "A1.jpg")
+	content := []byte("object store " + store.Type())
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	key := "machine-01/2025.01.01/A1.jpg"
+	if err := store.Put(ctx, key, src); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// 已存在的对象被覆盖
+	if err := store.Put(ctx, key, src); err != nil {
+		t.Fatalf("Put 覆盖: %v", err)
+	}
+
+	info, err := store.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Key != key || info.Size != int64(len(content)) {
+		t.Errorf("Stat = %+v，应为 %s、%d 字节", info, key, len(content))
+	}
+	if _, err := store.Stat(ctx, "machine-01/none.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat 不存在的对象 = %v，应为 ErrNotFound", err)
+	}
+	// 文件夹不是对象
+	if _, err := store.Stat(ctx, "machine-01"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat 文件夹 = %v，应为 ErrNotFound", err)
+	}
+
+	objects, err := store.List(ctx, "machine-01/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != key {
+		t.Errorf("List = %+v，应只有 %s", objects, key)
+	}
+	if objects, err := store.List(ctx, "machine-02/"); err != nil || len(objects) != 0 {
+		t.Errorf("List 其他前缀 = %+v, %v，应为空", objects, err)
+	}
+
+	if _, err := store.Presign(ctx, key, time.Hour); err != nil {
+		t.Errorf("Presign: %v", err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后 Stat = %v，应为 ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("删除不存在的对象: %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	testObjectStore(t, NewLocalStore(t.TempDir(), "test"))
+}
+
+func TestETagMD5(t *testing.T) {
+	for _, tc := range []struct {
+		etag string
+		want string
+		ok   bool
+	}{
+		{`"D41D8CD98F00B204E9800998ECF8427E"`, "d41d8cd98f00b204e9800998ecf8427e", true},
+		{"d41d8cd98f00b204e9800998ecf8427e-2", "", false},
+		{"5f3e-1a2b", "", false},
+		{"", "", false},
+	} {
+		got, ok := ETagMD5(tc.etag)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ETagMD5(%q) = %q, %v，应为 %q, %v", tc.etag, got, ok, tc.want, tc.ok)
+		}
+	}
+}
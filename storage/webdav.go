@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/studio-b12/gowebdav"
+)
+
+// WebDAVOptions WebDAV 共享的连接参数
+type WebDAVOptions struct {
+	URL      string // 共享的根地址，例如 https://nas.local/webdav/uposs
+	User     string
+	Password string
+	Timeout  time.Duration
+}
+
+// WebDAVStore WebDAV 共享（NAS）存储，存储桶对应根地址下的子文件夹
+type WebDAVStore struct {
+	client *gowebdav.Client
+	url    string
+	bucket string
+}
+
+// NewWebDAVStore 创建 WebDAV 存储，连接在第一次操作时建立
+func NewWebDAVStore(opts WebDAVOptions, bucket string) *WebDAVStore {
+	client := gowebdav.NewClient(opts.URL, opts.User, opts.Password)
+	if opts.Timeout > 0 {
+		client.SetTimeout(opts.Timeout)
+	}
+	return &WebDAVStore{client: client, url: strings.TrimRight(opts.URL, "/"), bucket: bucket}
+}
+
+func (s *WebDAVStore) Type() string   { return "webdav" }
+func (s *WebDAVStore) Bucket() string { return s.bucket }
+
+// with 返回请求使用 ctx 的客户端；gowebdav 创建请求时不带 context，在拦截器中替换，
+// 超时仍由 http.Client 的 Timeout 控制，登录状态与 s.client 共用
+func (s *WebDAVStore) with(ctx context.Context) *gowebdav.Client {
+	c := *s.client
+	c.SetInterceptor(func(_ string, rq *http.Request) {
+		*rq = *rq.WithContext(ctx)
+	})
+	return &c
+}
+
+// ctxErr gowebdav 的部分方法（Remove、MkdirAll）不返回请求本身的错误，
+// 请求失败时如果 ctx 已取消或超时，返回 ctx 的错误
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// path 返回 key 在共享中的路径
+func (s *WebDAVStore) path(key string) string {
+	return path.Join("/", s.bucket, CleanKey(key))
+}
+
+func (s *WebDAVStore) BucketExists(ctx context.Context) (bool, error) {
+	info, err := s.with(ctx).Stat("/" + s.bucket)
+	if gowebdav.IsErrNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (s *WebDAVStore) MakeBucket(ctx context.Context) error {
+	return ctxErr(ctx, s.with(ctx).MkdirAll("/"+s.bucket, 0755))
+}
+
+// Put 上传文件，上级文件夹不存在时自动创建
+func (s *WebDAVStore) Put(ctx context.Context, key, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ctxErr(ctx, s.with(ctx).WriteStream(s.path(key), f, 0644))
+}
+
+func (s *WebDAVStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := s.with(ctx).Stat(s.path(key))
+	if gowebdav.IsErrNotFound(err) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	obj := ObjectInfo{Key: CleanKey(key), Size: info.Size(), LastModified: info.ModTime()}
+	if f, ok := info.(*gowebdav.File); ok {
+		obj.ETag = strings.Trim(f.ETag(), `"`)
+	}
+	return obj, nil
+}
+
+func (s *WebDAVStore) Delete(ctx context.Context, key string) error {
+	return ctxErr(ctx, s.with(ctx).Remove(s.path(key)))
+}
+
+// List 逐层读取文件夹，WebDAV 没有按前缀列出的请求
+func (s *WebDAVStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	prefix = strings.TrimPrefix(strings.ReplaceAll(prefix, "\\", "/"), "/")
+	client := s.with(ctx)
+	var walk func(dir, rel string) error
+	walk = func(dir, rel string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			if gowebdav.IsErrNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		for _, e := range entries {
+			key := path.Join(rel, e.Name())
+			// 不在前缀范围内的文件夹不再读取
+			if e.IsDir() {
+				if strings.HasPrefix(key+"/", prefix) || strings.HasPrefix(prefix, key+"/") {
+					if err := walk(path.Join(dir, e.Name()), key); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(key, prefix) {
+				objects = append(objects, ObjectInfo{Key: key, Size: e.Size(), LastModified: e.ModTime()})
+			}
+		}
+		return nil
+	}
+	err := walk("/"+s.bucket, "")
+	return objects, err
+}
+
+// Presign WebDAV 没有临时访问地址，返回对象在共享中的地址（需要共享的账号才能访问），expiry 不生效
+func (s *WebDAVStore) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return s.url + s.path(key), nil
+}
+
+// Health 读取根文件夹，确认地址可以访问、账号有效
+func (s *WebDAVStore) Health(ctx context.Context) (string, error) {
+	entries, err := s.with(ctx).ReadDir("/")
+	if err != nil {
+		if gowebdav.IsErrCode(err, 401) || gowebdav.IsErrCode(err, 403) {
+			return "", fmt.Errorf("WebDAV 账号或密码不正确: %v", err)
+		}
+		return "", fmt.Errorf("WebDAV 连接测试失败: %v", err)
+	}
+	return fmt.Sprintf("WebDAV 共享 %s 可以访问，根文件夹中有 %d 项", s.url, len(entries)), nil
+}
+
+func (s *WebDAVStore) Close() error { return nil }
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+const (
+	testUser     = "uposs"
+	testPassword = "uposs-test"
+)
+
+// newWebDAVServer 启动本进程中带基本认证的 WebDAV 服务器，返回根地址
+func newWebDAVServer(t *testing.T) string {
+	t.Helper()
+	handler := &webdav.Handler{FileSystem: webdav.Dir(t.TempDir()), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != testUser || pass != testPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestWebDAVStore(t *testing.T) {
+	url := newWebDAVServer(t)
+	testObjectStore(t, NewWebDAVStore(WebDAVOptions{URL: url, User: testUser, Password: testPassword, Timeout: 10 * time.Second}, "test"))
+}
+
+func TestWebDAVStoreWrongPassword(t *testing.T) {
+	url := newWebDAVServer(t)
+	store := NewWebDAVStore(WebDAVOptions{URL: url, User: testUser, Password: "wrong"}, "test")
+	if _, err := store.Health(context.Background()); err == nil {
+		t.Fatal("密码错误时 Health 应返回错误")
+	}
+}
+
+func TestWebDAVStoreContext(t *testing.T) {
+	// 服务器不返回，直到请求被取消或测试结束
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	store := NewWebDAVStore(WebDAVOptions{URL: srv.URL, User: testUser, Password: testPassword}, "test")
+
+	src := filepath.Join(t.TempDir(), "A1.jpg")
+	if err := os.WriteFile(src, []byte("A1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ops := map[string]func(ctx context.Context) error{
+		"Put":    func(ctx context.Context) error { return store.Put(ctx, "A1.jpg", src) },
+		"Stat":   func(ctx context.Context) error { _, err := store.Stat(ctx, "A1.jpg"); return err },
+		"Delete": func(ctx context.Context) error { return store.Delete(ctx, "A1.jpg") },
+		"List":   func(ctx context.Context) error { _, err := store.List(ctx, ""); return err },
+	}
+	for name, op := range ops {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		done := make(chan error, 1)
+		go func() { done <- op(ctx) }()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%s = %v，应为 context.DeadlineExceeded", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s 在 context 超时后没有返回", name)
+		}
+		cancel()
+	}
+}
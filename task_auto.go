@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"sync"
@@ -19,7 +20,7 @@ var (
 	autoLogText     = widget.NewMultiLineEntry() // 用于显示日志信息
 	autoScanButton  *widget.Button               // 扫描按钮
 	autoProgressBar *widget.ProgressBarInfinite  // 无限进度条
-	autoStopChan    chan struct{}                // 用于停止任务的通道
+	autoCancel      context.CancelFunc           // 用于取消正在运行的任务
 	autoWg          sync.WaitGroup               // 用于等待任务完成的 WaitGroup
 )
 
@@ -38,8 +39,10 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 	}
 
 	// 启动自动任务的功能
-	startAutoTask := func() {
-		autoStopChan = make(chan struct{})
+	// trigger 为第一个周期的触发方式：点击开始按钮为 manual，程序启动时自动开始为 startup
+	startAutoTask := func(trigger string) {
+		var ctx context.Context
+		ctx, autoCancel = context.WithCancel(context.Background())
 
 		// 更新UI状态
 		autoScanButton.SetText("停止任务")
@@ -53,94 +56,33 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 		autoWg.Add(1)
 		go func() {
 			defer autoWg.Done()
-			for {
-				select {
-				case <-autoStopChan:
-					return
-				default:
-					// 加载最新配置 - 直接使用文件名
-					newConfig, err := LoadConfig("config.json")
-					if err != nil {
-						AutoLogToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
-						// 直接在这里更新UI状态
-						updateUIOnTaskEnd()
-						return
-					}
-
-					// 检查缓冲区大小
-					if newConfig.IOBuffer <= 0 {
-						AutoLogToFile("缓冲区大小必须大于零")
-						updateUIOnTaskEnd()
-						return
-					}
-
-					// 步骤1: 扫描和复制文件
-					AutoLogToFile("开始扫描和复制文件...")
-
-					err = ScanAndCopyFoldersForToday(newConfig)
-					if err != nil {
-						AutoLogToFile(fmt.Sprintf("扫描和复制文件失败❌😅: %s", err.Error()))
-						AutoLogToFile("避免冲突，跳过本次复制...")
-					} else {
-						AutoLogToFile("文件扫描和复制完成")
-					}
-
-					// 步骤2: 处理图像
-					AutoLogToFile("开始处理图像...")
-
-					err = HandleImages(newConfig.LocalFolder, newConfig.PicCompress, newConfig.PicWidth, newConfig.PicSize, false)
-					if err != nil {
-						AutoLogToFile(fmt.Sprintf("处理图像失败: %s", err.Error()))
-					} else {
-						AutoLogToFile("图像处理完成")
-					}
-
-					// 步骤3: 上传图片
-					AutoLogToFile("开始上传流程...")
-
-					err = UploadImagesWithTaskType(newConfig, false) // 指定为自动任务
-					if err != nil {
-						if err.Error() == "无文件可上传" {
-							AutoLogToFile("无文件可上传")
-						} else {
-							AutoLogToFile(fmt.Sprintf("上传图片失败: %v，\n20 秒后重试一次...", err))
-							time.Sleep(20 * time.Second) // 等待 20 秒再试一次
-
-							// 再次尝试
-							err = UploadImagesWithTaskType(newConfig, false)
-							if err != nil {
-								AutoLogToFile(fmt.Sprintf("重试仍然失败: %v", err))
-								// 发送企业微信通知
-								if notifyErr := newConfig.NotifyUploadFailed(); notifyErr != nil {
-									AutoLogToFile(fmt.Sprintf("发送企业微信通知: %v", notifyErr))
-								}
-							} else {
-								AutoLogToFile("重试成功，所有图片上传完成")
-							}
-						}
-					}
-
-					// 当前执行周期完成
-					AutoLogToFile("当前执行周期已完成 ✅")
-
-					// 获取间隔时间
-					interval, err := strconv.Atoi(newConfig.AutoInterval)
-					if err != nil {
-						AutoLogToFile(fmt.Sprintf("无效的间隔时间: %s", newConfig.AutoInterval))
-						updateUIOnTaskEnd()
-						return
-					}
-
-					AutoLogToFile(fmt.Sprintf("将在 %d 秒后开始下一次任务执行...", interval))
-
-					select {
-					case <-autoStopChan:
-						return
-					case <-time.After(time.Duration(interval) * time.Second):
-						// 继续下一个循环
-					}
+
+			// 每个配置方案单独循环，新增或删除方案后重新开始任务生效
+			config, err := appConfig.Current()
+			if err != nil {
+				AutoLogToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
+				updateUIOnTaskEnd()
+				return
+			}
+			profiles := config.ProfileNames()
+			if len(profiles) > 1 {
+				AutoLogToFile(fmt.Sprintf("共 %d 个配置方案同时执行", len(profiles)))
+			}
+
+			// 某个方案配置无效时只停止该方案，全部方案停止后更新界面
+			runProfiles(profiles, func(profile string) error {
+				err := runAutoProfile(ctx, profile, trigger, nil)
+				if ctx.Err() == nil && len(profiles) > 1 {
+					AutoProfileLogToFile(profile, "该方案已停止")
 				}
+				return err
+			})
+
+			// 手动停止时由停止按钮更新界面
+			if ctx.Err() != nil {
+				return
 			}
+			updateUIOnTaskEnd()
 		}()
 	}
 
@@ -157,21 +99,21 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 	systemTimeLabel := widget.NewLabel("系统时间:")
 
 	// 设置日志文本框
-	autoLogText.SetMinRowsVisible(20)
+	autoLogText.SetMinRowsVisible(18)
 
 	// 初始化进度条
 	autoProgressBar = widget.NewProgressBarInfinite()
 	autoProgressBar.Stop() // 确保进度条初始为停止状态
 
-	// 初始化停止通道
-	autoStopChan = make(chan struct{})
+	// 初始化取消函数，任务启动前停止操作不做任何事
+	autoCancel = func() {}
 
 	// 任务按钮的启停逻辑部分
 	autoScanButton = widget.NewButton("开始任务", func() {
 		if autoScanButton.Text == "开始任务" {
 			dialog.ShowConfirm("确认开始", "确定要开始任务吗？", func(confirm bool) {
 				if confirm {
-					startAutoTask() // 使用局部函数
+					startAutoTask(runTriggerManual) // 使用局部函数
 				}
 			}, myWindow)
 		} else {
@@ -180,8 +122,8 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 				if confirm {
 					AutoLogToFile("正在停止任务...")
 
-					// 关闭停止通道
-					close(autoStopChan)
+					// 取消任务，正在进行的复制、压缩、上传会在数秒内中断
+					autoCancel()
 
 					// 等待任务完成
 					autoWg.Wait()
@@ -195,7 +137,7 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 
 	// 为需要手动触发自动任务的地方提供访问点，例如，当程序启动时自动执行任务
 	// 使用闭包方式，避免全局函数
-	triggerAutoTask = startAutoTask
+	triggerAutoTask = func() { startAutoTask(runTriggerStartup) }
 
 	// 设置按钮和进度条的宽度
 	scanButtonContainer := container.NewGridWrap(fyne.NewSize(300, 35), autoScanButton)
@@ -209,7 +151,7 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 
 	// 创建 autoInterval 文本框和保存按钮
 	autoIntervalEntry := widget.NewEntry()
-	autoIntervalEntry.SetText(config.AutoInterval)
+	autoIntervalEntry.SetText(strconv.Itoa(config.AutoInterval))
 
 	// 创建标签
 	intervalLabel := widget.NewLabel("执行间隔:")
@@ -227,17 +169,15 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 			if confirm {
 				// 验证输入
 				interval, err := strconv.Atoi(autoIntervalEntry.Text)
-				if err != nil || interval <= 0 {
+				if err != nil {
 					dialog.ShowInformation("输入错误", "请输入有效的时间间隔（正整数）", myWindow)
 					return
 				}
 
-				// 更新配置
-				config.AutoInterval = autoIntervalEntry.Text
-
-				// 保存配置 - 直接使用文件名
-				if err := SaveConfig("config.json", config); err != nil {
-					dialog.ShowInformation("保存失败", fmt.Sprintf("保存配置失败: %v", err), myWindow)
+				// 校验后更新并保存配置
+				err = updateConfig(config, func(c *Config) { c.AutoInterval = interval }, autoConfigFields...)
+				if err != nil {
+					dialog.ShowInformation("保存失败", configSaveError(err), myWindow)
 				} else {
 					AutoLogToFile("配置已成功保存")
 				}
@@ -248,15 +188,102 @@ func createautoTaskUI(myWindow fyne.Window, config *Config) fyne.CanvasObject {
 	// 设置按钮的宽度
 	saveButtonContainer := container.NewGridWrap(fyne.NewSize(150, utils.LEBHeight), saveButton)
 
+	// 监听模式：文件变化时立即复制，执行间隔作为全量扫描间隔，下一个周期生效
+	watchCheck := widget.NewCheck("监听模式", nil)
+	watchCheck.SetChecked(config.AutoWatch)
+	watchCheck.OnChanged = func(checked bool) {
+		if checked == config.AutoWatch {
+			return // 配置变更通知同步复选框状态
+		}
+		if err := updateConfig(config, func(c *Config) { c.AutoWatch = checked }); err != nil {
+			AutoLogToFile(fmt.Sprintf("保存监听模式配置失败: %v", err))
+			return
+		}
+		if checked {
+			AutoLogToFile("已启用监听模式，下一个周期生效")
+		} else {
+			AutoLogToFile("已停用监听模式，下一个周期生效")
+		}
+	}
+
+	// 模拟运行：只输出将要复制、压缩、上传、推送和删除的文件，下一个周期生效
+	dryRunCheck := widget.NewCheck("模拟运行", nil)
+	dryRunCheck.SetChecked(config.AutoDryRun)
+	dryRunCheck.OnChanged = func(checked bool) {
+		if checked == config.AutoDryRun {
+			return // 配置变更通知同步复选框状态
+		}
+		if err := updateConfig(config, func(c *Config) { c.AutoDryRun = checked }); err != nil {
+			AutoLogToFile(fmt.Sprintf("保存模拟运行配置失败: %v", err))
+			return
+		}
+		if checked {
+			AutoLogToFile("已启用模拟运行，下一个周期生效，不会修改任何文件")
+		} else {
+			AutoLogToFile("已停用模拟运行，下一个周期生效")
+		}
+	}
+
 	// 创建按钮容器，按钮上下排列，并设置按钮的尺寸
 	intervalContainer := container.NewBorder(nil, nil, nil, nil, container.NewHBox(
+		dryRunCheck,
+		watchCheck,
 		autoIntervalContainer,
 		saveButtonContainer,
 	))
 
+	// 创建 cron 表达式输入框和下次执行时间预览，留空时按执行间隔运行
+	autoCronEntry := widget.NewEntry()
+	autoCronEntry.SetPlaceHolder("秒 分 时 日 月 周，例如 0 */10 8-19 * * MON-FRI")
+	autoCronEntry.SetText(config.AutoCron)
+
+	autoCronPreview := widget.NewLabel("")
+	updateAutoCronPreview := func(spec string) {
+		if spec == "" {
+			autoCronPreview.SetText("未设置，按执行间隔运行")
+			return
+		}
+		autoCronPreview.SetText(cronPreview(spec, config.CronTimezone, 3))
+	}
+	updateAutoCronPreview(config.AutoCron)
+	autoCronEntry.OnChanged = updateAutoCronPreview
+
+	// 配置被其他程序修改或恢复历史版本时同步变化的字段
+	onConfigChange(func(old, c *Config) {
+		syncEntry(autoIntervalEntry, strconv.Itoa(old.AutoInterval), strconv.Itoa(c.AutoInterval))
+		syncEntry(autoCronEntry, old.AutoCron, c.AutoCron)
+		if old.CronTimezone != c.CronTimezone {
+			updateAutoCronPreview(autoCronEntry.Text)
+		}
+		watchCheck.SetChecked(c.AutoWatch)
+		dryRunCheck.SetChecked(c.AutoDryRun)
+	})
+
+	saveCronButton := widget.NewButton("修改定时", func() {
+		spec := autoCronEntry.Text
+		dialog.ShowConfirm("确认保存", "确定要保存定时配置吗？重新开始任务后生效", func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if err := updateConfig(config, func(c *Config) { c.AutoCron = spec }, autoConfigFields...); err != nil {
+				dialog.ShowInformation("保存失败", configSaveError(err), myWindow)
+				return
+			}
+			AutoLogToFile(fmt.Sprintf("定时配置已保存: %s", spec))
+		}, myWindow)
+	})
+
+	cronContainer := container.NewHBox(
+		widget.NewLabel("Cron:"),
+		container.NewGridWrap(fyne.NewSize(260, utils.LEBHeight), autoCronEntry),
+		container.NewGridWrap(fyne.NewSize(150, utils.LEBHeight), saveCronButton),
+		autoCronPreview,
+	)
+
 	// 创建 "任务界面" Tab 内容，将日期 UI 放在文件夹扫描器 UI 之前
 	ui := container.NewVBox(
 		container.NewBorder(nil, nil, nil, intervalContainer, container.NewHBox(systemTimeLabel, timeLabel)), // 系统时间标签、实时时间标签和输入框、保存按钮
+		cronContainer,
 		folderScannerUI,
 		autoLogText,
 	)
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-uposs/pipeline"
+
+	"github.com/robfig/cron/v3"
+)
+
+// newTaskPipeline 构建 复制 → 压缩 → 上传（含 API2 推送）流水线，界面和命令行共用
+// config 为 ForProfile 生成的方案配置，流水线属于该方案；orderNumbers 为计划任务需要匹配的编号（逗号分割），自动任务传空字符串
+func newTaskPipeline(config *Config, task pipeline.TaskType, orderNumbers string) pipeline.Config {
+	copyStage := pipeline.NewStage("扫描和复制文件", func(ctx context.Context, rc *pipeline.RunContext) error {
+		if rc.IsAuto() {
+			// 自动任务复制失败时跳过本次复制，继续处理已复制的文件
+			if err := ScanAndCopyFoldersForToday(ctx, rc, config); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				rc.Log("避免冲突，跳过本次复制...")
+				return err
+			}
+			return nil
+		}
+
+		// 打印需要匹配的编号
+		if rc.OrderNumbers != "" {
+			rc.Log(fmt.Sprintf("需要匹配的编号: %s", rc.OrderNumbers))
+		} else {
+			rc.Log("未输入编号，不进行编号匹配")
+		}
+		// 计划任务复制失败时终止本次运行
+		return pipeline.Abort(ScanAndCopyFolders(ctx, rc, config))
+	})
+
+	return pipeline.Config{
+		Task:         task,
+		Profile:      config.Profile,
+		OrderNumbers: orderNumbers,
+		DryRun:       config.dryRunFor(task),
+		Observer:     taskLogObserver,
+		Stages:       append([]pipeline.Stage{copyStage}, processStages(config)...),
+	}
+}
+
+// newWatchPipeline 构建监听模式的自动任务流水线，只复制发生变化的日期文件夹
+func newWatchPipeline(config *Config, dirs []string) pipeline.Config {
+	copyStage := pipeline.NewStage("复制变化的文件夹", func(ctx context.Context, rc *pipeline.RunContext) error {
+		return copyChangedFolders(ctx, rc, config, dirs)
+	})
+
+	return pipeline.Config{
+		Task:     pipeline.TaskAuto,
+		Profile:  config.Profile,
+		DryRun:   config.dryRunFor(pipeline.TaskAuto),
+		Observer: taskLogObserver,
+		Stages:   append([]pipeline.Stage{copyStage}, processStages(config)...),
+	}
+}
+
+// processStages 复制之后的 压缩 → 上传（含 API2 推送）阶段
+func processStages(config *Config) []pipeline.Stage {
+	return []pipeline.Stage{
+		pipeline.NewStage("处理图像", func(ctx context.Context, rc *pipeline.RunContext) error {
+			return HandleImages(ctx, rc, config.LocalFolder, config.PicCompress, config.PicWidth, config.PicSize, config.PicWorkers, config.PicMemBudget)
+		}),
+		pipeline.NewStage("上传图片", func(ctx context.Context, rc *pipeline.RunContext) error {
+			return uploadWithRetry(ctx, rc, config)
+		}),
+	}
+}
+
+// taskLogFunc 返回任务类型和配置方案对应的日志函数
+func taskLogFunc(task pipeline.TaskType, profile string) func(string) error {
+	if task == pipeline.TaskSched {
+		return func(message string) error { return SchedProfileLogToFile(profile, message) }
+	}
+	return func(message string) error { return AutoProfileLogToFile(profile, message) }
+}
+
+// taskLogObserver 将流水线事件写入对应任务和方案的日志文件和界面
+func taskLogObserver(ev pipeline.Event) {
+	logToFile := taskLogFunc(ev.Task, ev.Profile)
+
+	switch ev.Kind {
+	case pipeline.EventStageStart:
+		logToFile(fmt.Sprintf("开始%s...", ev.Stage))
+	case pipeline.EventStageDone:
+		logToFile(fmt.Sprintf("%s完成", ev.Stage))
+	case pipeline.EventStageFailed:
+		if errors.Is(ev.Err, context.Canceled) {
+			logToFile(fmt.Sprintf("%s已取消", ev.Stage))
+			return
+		}
+		logToFile(fmt.Sprintf("%s失败❌😅: %v", ev.Stage, ev.Err))
+	case pipeline.EventLog:
+		logToFile(ev.Message)
+	}
+}
+
+// runAutoCycle 执行一次自动任务周期：扫描复制今天和昨天的文件夹 → 压缩 → 上传 → 推送
+// trigger 为触发方式，记录在执行记录中
+func runAutoCycle(ctx context.Context, newConfig *Config, trigger string) error {
+	err := runPipeline(ctx, newTaskPipeline(newConfig, pipeline.TaskAuto, ""), trigger)
+
+	// 当前执行周期完成
+	AutoProfileLogToFile(newConfig.Profile, "当前执行周期已完成 ✅")
+
+	return err
+}
+
+// runAutoWatchCycle 执行一次监听模式触发的自动任务周期，只复制 dirs 中的日期文件夹
+func runAutoWatchCycle(ctx context.Context, newConfig *Config, dirs []string) error {
+	err := runPipeline(ctx, newWatchPipeline(newConfig, dirs), runTriggerWatch)
+
+	// 当前执行周期完成
+	AutoProfileLogToFile(newConfig.Profile, "当前执行周期已完成 ✅")
+
+	return err
+}
+
+// errAutoConfig 自动任务配置无效（加载失败、校验失败或执行间隔无效）
+var errAutoConfig = errors.New("自动任务配置无效")
+
+// runAutoProfile 循环执行配置方案 profile 的自动任务，直到 ctx 取消或配置无效，界面和命令行共用
+// trigger 为第一个周期的触发方式；每个周期开始时读取最新配置，override 不为空时用于调整本轮配置（例如命令行的模拟运行）
+// ctx 取消时返回 ctx.Err()，配置无效时返回的错误满足 errors.Is(err, errAutoConfig)
+func runAutoProfile(ctx context.Context, profile, trigger string, override func(*Config)) error {
+	logToFile := taskLogFunc(pipeline.TaskAuto, profile)
+
+	// 监听模式下文件变化会提前触发周期
+	loop := &autoLoop{profile: profile, trigger: trigger}
+	defer loop.close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 每个周期开始时读取最新配置，界面修改或配置文件被外部修改后下一轮生效
+		newConfig, err := currentProfileConfig(profile)
+		if err != nil {
+			logToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
+			return fmt.Errorf("%w: %v", errAutoConfig, err)
+		}
+
+		// 校验执行任务所需的配置
+		if err := newConfig.ValidateFields(runConfigFields...); err != nil {
+			logToFile(fmt.Sprintf("配置校验失败:\n%v", err))
+			return fmt.Errorf("%w: %v", errAutoConfig, err)
+		}
+		if override != nil {
+			override(newConfig)
+		}
+
+		// 执行一个完整周期：复制 → 压缩 → 上传 → 推送
+		loop.runCycle(ctx, newConfig)
+
+		// 获取间隔时间
+		interval := newConfig.AutoInterval
+		if interval <= 0 {
+			logToFile(fmt.Sprintf("无效的间隔时间: %d", newConfig.AutoInterval))
+			return fmt.Errorf("%w: 无效的间隔时间 %d", errAutoConfig, newConfig.AutoInterval)
+		}
+
+		if err := loop.wait(ctx, newConfig, time.Duration(interval)*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// runSchedCycle 执行一次计划任务周期，orderNumbers 为逗号分割的编号（可为空）
+// 复制失败时返回的错误满足 pipeline.IsAborted，调用方据此终止剩余的执行次数
+func runSchedCycle(ctx context.Context, newConfig *Config, orderNumbers, trigger string) error {
+	return runPipeline(ctx, newTaskPipeline(newConfig, pipeline.TaskSched, orderNumbers), trigger)
+}
+
+// errSchedConfig 计划任务配置无效（加载失败、执行次数或缓冲区大小无效）
+var errSchedConfig = errors.New("计划任务配置无效")
+
+// runSchedBatch 连续执行 times 次配置方案 profile 的计划任务周期，times <= 0 时使用配置中的 sched_times
+// trigger 为触发方式，每轮重新加载配置，prepare 不为空时用于调整本轮的日期范围；复制失败（pipeline.IsAborted）或 ctx 取消时提前结束
+// 返回各周期错误的合并结果，配置无效时返回的错误满足 errors.Is(err, errSchedConfig)
+func runSchedBatch(ctx context.Context, profile, orderNumbers string, times int, trigger string, prepare func(*Config)) error {
+	logToFile := taskLogFunc(pipeline.TaskSched, profile)
+
+	// 加载配置，获取最大执行次数
+	newConfig, err := currentProfileConfig(profile)
+	if err != nil {
+		logToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
+		return fmt.Errorf("%w: %v", errSchedConfig, err)
+	}
+	maxExecutions := times
+	if maxExecutions <= 0 {
+		maxExecutions = newConfig.SchedTimes
+		if maxExecutions <= 0 {
+			logToFile(fmt.Sprintf("无效的执行次数: %d", newConfig.SchedTimes))
+			return fmt.Errorf("%w: 无效的执行次数 %d", errSchedConfig, newConfig.SchedTimes)
+		}
+	}
+
+	var errs []error
+	for executionCount := 0; executionCount < maxExecutions; executionCount++ {
+		// 每轮读取 appConfig 的最新配置，界面修改或配置文件被外部修改后下一轮生效
+		newConfig, err = currentProfileConfig(profile)
+		if err != nil {
+			logToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
+			return fmt.Errorf("%w: %v", errSchedConfig, err)
+		}
+		if err := newConfig.ValidateFields(runConfigFields...); err != nil {
+			logToFile(fmt.Sprintf("配置校验失败:\n%v", err))
+			return fmt.Errorf("%w: %v", errSchedConfig, err)
+		}
+		if prepare != nil {
+			prepare(newConfig)
+		}
+
+		logToFile(fmt.Sprintf("第 %d 次任务开始...", executionCount+1))
+
+		err = runSchedCycle(ctx, newConfig, orderNumbers, trigger)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		currentTime := time.Now().Format("2006.01.02 15:04:05")
+		logToFile(fmt.Sprintf("%s 当前执行周期已完成 (%d/%d)", currentTime, executionCount+1, maxExecutions))
+
+		if err != nil {
+			errs = append(errs, err)
+			if pipeline.IsAborted(err) {
+				return errors.Join(errs...)
+			}
+		}
+		// 不等待间隔，立即进入下一轮或结束
+	}
+
+	logToFile("所有计划任务已完成 ✅")
+	return errors.Join(errs...)
+}
+
+// runSchedCron 按 sched_cron 循环触发配置方案 profile 的计划任务，直到 ctx 取消或配置无效
+// 每次触发执行 times 次周期，日期范围由 sched_cron_days 决定；override 不为空时在此之后调整本轮配置（例如命令行指定的日期范围）
+func runSchedCron(ctx context.Context, profile string, schedule cron.Schedule, catchUp bool, orderNumbers string, times int, override func(*Config)) error {
+	logToFile := taskLogFunc(pipeline.TaskSched, profile)
+	cronTask := profileCronTask(cronTaskSched, profile)
+	if err := waitCronStart(ctx, cronTask, schedule, catchUp, logToFile); err != nil {
+		return err
+	}
+
+	for {
+		recordCronRun(cronTask, logToFile)
+		trigger := time.Now()
+
+		err := runSchedBatch(ctx, profile, orderNumbers, times, runTriggerSchedule, func(c *Config) {
+			if c.SchedCronDays > 0 {
+				c.StartTime, c.EndTime = schedCronDateRange(trigger, c.SchedCronDays)
+			}
+			if override != nil {
+				override(c)
+			}
+			logToFile(fmt.Sprintf("本次处理日期范围: %s - %s", c.StartTime, c.EndTime))
+		})
+		if ctx.Err() != nil || errors.Is(err, errSchedConfig) {
+			return err
+		}
+
+		if err := waitCron(ctx, schedule, catchUp, logToFile); err != nil {
+			return err
+		}
+	}
+}
+
+// schedCronSchedule 返回 sched_cron 对应的执行计划，未配置时返回 nil
+func schedCronSchedule(config *Config) (cron.Schedule, error) {
+	if strings.TrimSpace(config.SchedCron) == "" {
+		return nil, nil
+	}
+	return parseCron(config.SchedCron, config.CronTimezone)
+}
+
+// uploadWithRetry 上传图片，失败时 20 秒后重试一次，重试仍失败则发送企业微信通知
+func uploadWithRetry(ctx context.Context, rc *pipeline.RunContext, newConfig *Config) error {
+	err := UploadImagesWithTaskType(ctx, rc, newConfig)
+	if err == nil {
+		return nil
+	}
+	// 任务被取消时不再重试和通知
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, errNoFilesToUpload) {
+		rc.Log(err.Error())
+		return nil
+	}
+
+	rc.Log(fmt.Sprintf("上传图片失败: %v，\n20 秒后重试一次...", err))
+	if err := pipeline.Sleep(ctx, 20*time.Second); err != nil { // 等待 20 秒再试一次
+		return err
+	}
+
+	// 再次尝试
+	err = UploadImagesWithTaskType(ctx, rc, newConfig)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 第一次上传虽然出错，但本地文件已全部处理
+		if errors.Is(err, errNoFilesToUpload) {
+			rc.Log("重试时已无文件可上传")
+			return nil
+		}
+		rc.Log(fmt.Sprintf("重试仍然失败: %v", err))
+		// 发送企业微信通知，模拟运行不发送
+		if rc.DryRun {
+			return err
+		}
+		if notifyErr := newConfig.NotifyUploadFailed(); notifyErr != nil {
+			rc.Log(fmt.Sprintf("发送企业微信通知: %v", notifyErr))
+		}
+		return err
+	}
+
+	rc.Log("重试成功，所有图片上传完成")
+	return nil
+}
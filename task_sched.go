@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"sync"
@@ -18,7 +19,7 @@ var (
 	schedLogText     = widget.NewMultiLineEntry() // 用于显示日志信息
 	scanButton       *widget.Button               // 扫描按钮
 	progressBar      *widget.ProgressBarInfinite  // 无限进度条
-	stopChan         chan struct{}                // 用于停止任务的通道
+	schedCancel      context.CancelFunc           // 用于取消正在运行的任务
 	wg               sync.WaitGroup               // 用于等待任务完成的 WaitGroup
 	orderNumberEntry *widget.Entry                // 用于输入编号的输入框
 )
@@ -39,7 +40,8 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 
 	// 启动自动任务的功能
 	startSchedTask := func() {
-		stopChan = make(chan struct{})
+		var ctx context.Context
+		ctx, schedCancel = context.WithCancel(context.Background())
 
 		// 显示无限进度条
 		scanButton.SetText("停止任务")
@@ -51,98 +53,40 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		go func() {
 			defer wg.Done()
 
-			// 加载配置，获取最大执行次数
-			newConfig, err := LoadConfig("config.json")
+			// 获取输入框中的编号
+			orderNumbers := orderNumberEntry.Text
+
+			newConfig, err := appConfig.Current()
 			if err != nil {
 				SchedLogToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
 				updateUIOnTaskEnd()
 				return
 			}
-			maxExecutions, err := strconv.Atoi(newConfig.SchedTimes)
-			if err != nil || maxExecutions <= 0 {
-				SchedLogToFile(fmt.Sprintf("无效的执行次数: %s", newConfig.SchedTimes))
+			schedule, err := schedCronSchedule(newConfig)
+			if err != nil {
+				SchedLogToFile(err.Error())
 				updateUIOnTaskEnd()
 				return
 			}
 
-			for executionCount := 0; executionCount < maxExecutions; executionCount++ {
-				select {
-				case <-stopChan:
-					return
-				default:
-					// 每轮重新加载配置，允许任务期间动态更新配置
-					newConfig, err = LoadConfig("config.json")
-					if err != nil {
-						SchedLogToFile(fmt.Sprintf("加载配置失败: %s", err.Error()))
-						updateUIOnTaskEnd()
-						return
-					}
-
-					if newConfig.IOBuffer <= 0 {
-						SchedLogToFile("缓冲区大小必须大于零")
-						updateUIOnTaskEnd()
-						return
-					}
-
-					SchedLogToFile(fmt.Sprintf("第 %d 次任务开始...", executionCount+1))
-
-					// 获取输入框中的编号
-					orderNumbers := orderNumberEntry.Text
-
-					SchedLogToFile("开始扫描和复制文件...")
-					// 打印需要匹配的编号
-					if orderNumbers != "" {
-						SchedLogToFile(fmt.Sprintf("需要匹配的编号: %s", orderNumbers))
-					} else {
-						SchedLogToFile("未输入编号，不进行编号匹配")
-					}
-					// 传递编号给 ScanAndCopyFolders 函数
-					err = ScanAndCopyFolders(newConfig, orderNumbers)
-					if err != nil {
-						SchedLogToFile(fmt.Sprintf("扫描和复制文件失败: %s", err.Error()))
-						updateUIOnTaskEnd()
-						return
-					}
-					SchedLogToFile("文件扫描和复制完成")
-					time.Sleep(500 * time.Millisecond)
-
-					SchedLogToFile("开始处理图像...")
-					err = HandleImages(newConfig.LocalFolder, newConfig.PicCompress, newConfig.PicWidth, newConfig.PicSize, true)
-					if err != nil {
-						SchedLogToFile(fmt.Sprintf("处理图像失败: %s", err.Error()))
-					} else {
-						SchedLogToFile("图像处理完成")
-					}
-					time.Sleep(500 * time.Millisecond)
-
-					SchedLogToFile("开始上传图片到OSS...")
-					err = UploadImagesWithTaskType(newConfig, true)
-					if err != nil {
-						if err.Error() == "无文件可上传" {
-							SchedLogToFile("无文件可上传")
-						} else {
-							SchedLogToFile(fmt.Sprintf("上传图片失败: %v，\n20 秒后重试一次...", err))
-							time.Sleep(20 * time.Second)
-							err = UploadImagesWithTaskType(newConfig, true)
-							if err != nil {
-								SchedLogToFile(fmt.Sprintf("重试仍然失败: %v", err))
-								if notifyErr := newConfig.NotifyUploadFailed(); notifyErr != nil {
-									SchedLogToFile(fmt.Sprintf("发送企业微信通知: %v", notifyErr))
-								}
-							} else {
-								SchedLogToFile("重试成功，所有图片上传完成")
-							}
-						}
-					}
-
-					currentTime := time.Now().Format("2006.01.02 15:04:05")
-					SchedLogToFile(fmt.Sprintf("%s 当前执行周期已完成 (%d/%d)", currentTime, executionCount+1, maxExecutions))
+			if schedule != nil {
+				SchedLogToFile(fmt.Sprintf("定时计划任务已启动: %s", newConfig.SchedCron))
+			}
 
-					// 不等待间隔，立即进入下一轮或结束
+			// 每个配置方案同时执行，全部方案结束后更新界面
+			runProfiles(newConfig.ProfileNames(), func(profile string) error {
+				if schedule == nil {
+					// 连续执行 sched_times 次后结束
+					return runSchedBatch(ctx, profile, orderNumbers, 0, runTriggerManual, nil)
 				}
-			}
+				// 按 sched_cron 定时执行，直到手动停止
+				return runSchedCron(ctx, profile, schedule, newConfig.CronCatchUp, orderNumbers, 0, nil)
+			})
 
-			SchedLogToFile("所有计划任务已完成 ✅")
+			// 手动停止时由停止按钮更新界面
+			if ctx.Err() != nil {
+				return
+			}
 			updateUIOnTaskEnd()
 		}()
 
@@ -152,14 +96,14 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	dateUI := CreateDateUI(myWindow, config)
 
 	// 设置日志文本框
-	schedLogText.SetMinRowsVisible(15)
+	schedLogText.SetMinRowsVisible(13)
 
 	// 初始化进度条并
 	progressBar = widget.NewProgressBarInfinite()
 	progressBar.Stop() // 确保进度条初始为停止状态
 
-	// 初始化停止通道
-	stopChan = make(chan struct{})
+	// 初始化取消函数，任务启动前停止操作不做任何事
+	schedCancel = func() {}
 
 	// 任务按钮的启停逻辑部分
 	scanButton = widget.NewButton("开始任务", func() {
@@ -175,8 +119,8 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 				if confirm {
 					SchedLogToFile("正在停止任务...")
 
-					// 关闭停止通道
-					close(stopChan)
+					// 取消任务，正在进行的复制、压缩、上传会在数秒内中断
+					schedCancel()
 
 					// 等待任务完成
 					wg.Wait()
@@ -200,7 +144,7 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 
 	// 创建 schedTimes 文本框和保存按钮
 	schedTimesEntry := widget.NewEntry()
-	schedTimesEntry.SetText(config.SchedTimes)
+	schedTimesEntry.SetText(strconv.Itoa(config.SchedTimes))
 
 	// 创建标签
 	TimesLabel := widget.NewLabel("执行次数:")
@@ -217,18 +161,16 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 		dialog.ShowConfirm("确认保存", "确定要保存配置吗？", func(confirm bool) {
 			if confirm {
 				// 验证输入
-				interval, err := strconv.Atoi(schedTimesEntry.Text)
-				if err != nil || interval <= 0 {
+				times, err := strconv.Atoi(schedTimesEntry.Text)
+				if err != nil {
 					dialog.ShowInformation("输入错误", "请输入有效的循环执行次数（正整数）", myWindow)
 					return
 				}
 
-				// 直接更新当前配置
-				config.SchedTimes = schedTimesEntry.Text
-
-				// 保存配置 - 直接使用文件名
-				if err := SaveConfig("config.json", config); err != nil {
-					dialog.ShowInformation("保存失败", fmt.Sprintf("保存配置失败: %v", err), myWindow)
+				// 校验后直接更新当前配置并保存
+				err = updateConfig(config, func(c *Config) { c.SchedTimes = times }, schedConfigFields...)
+				if err != nil {
+					dialog.ShowInformation("保存失败", configSaveError(err), myWindow)
 				} else {
 					currentTime := time.Now().Format("2006.01.02 15:04:05")
 					SchedLogToFile(fmt.Sprintf("%s 配置已成功保存", currentTime))
@@ -247,6 +189,88 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	orderNumberEntry = widget.NewEntry()
 	orderNumberEntry.SetPlaceHolder("编号匹配：输入一个或多个编号(逗号分割)，可模糊匹配。处理日期范围内所有文件请留空，勿输入空格")
 
+	// 创建 cron 表达式输入框、处理天数和下次执行时间预览，留空时点击开始后立即执行
+	schedCronEntry := widget.NewEntry()
+	schedCronEntry.SetPlaceHolder("例如 0 0 2 * * *（每天 02:00）")
+	schedCronEntry.SetText(config.SchedCron)
+
+	schedCronDaysEntry := widget.NewEntry()
+	schedCronDaysEntry.SetText(strconv.Itoa(config.SchedCronDays))
+
+	schedCronPreview := widget.NewLabel("")
+	updateSchedCronPreview := func(spec string) {
+		if spec == "" {
+			schedCronPreview.SetText("未设置，点击开始后立即执行")
+			return
+		}
+		schedCronPreview.SetText(cronPreview(spec, config.CronTimezone, 2))
+	}
+	updateSchedCronPreview(config.SchedCron)
+	schedCronEntry.OnChanged = updateSchedCronPreview
+
+	saveCronButton := widget.NewButton("修改定时", func() {
+		spec := schedCronEntry.Text
+		days, err := strconv.Atoi(schedCronDaysEntry.Text)
+		if err != nil {
+			dialog.ShowInformation("输入错误", "请输入有效的处理天数（0 表示使用上方日期范围）", myWindow)
+			return
+		}
+		dialog.ShowConfirm("确认保存", "确定要保存定时配置吗？重新开始任务后生效", func(confirm bool) {
+			if !confirm {
+				return
+			}
+			err := updateConfig(config, func(c *Config) {
+				c.SchedCron = spec
+				c.SchedCronDays = days
+			}, schedConfigFields...)
+			if err != nil {
+				dialog.ShowInformation("保存失败", configSaveError(err), myWindow)
+				return
+			}
+			SchedLogToFile(fmt.Sprintf("定时配置已保存: %s，处理最近 %d 天", spec, days))
+		}, myWindow)
+	})
+
+	// 模拟运行：只输出将要复制、压缩、上传、推送和删除的文件，下一次执行生效
+	dryRunCheck := widget.NewCheck("模拟运行", nil)
+	dryRunCheck.SetChecked(config.SchedDryRun)
+	dryRunCheck.OnChanged = func(checked bool) {
+		if checked == config.SchedDryRun {
+			return // 配置变更通知同步复选框状态
+		}
+		if err := updateConfig(config, func(c *Config) { c.SchedDryRun = checked }); err != nil {
+			SchedLogToFile(fmt.Sprintf("保存模拟运行配置失败: %v", err))
+			return
+		}
+		if checked {
+			SchedLogToFile("已启用模拟运行，不会修改任何文件")
+		} else {
+			SchedLogToFile("已停用模拟运行")
+		}
+	}
+
+	// 配置被其他程序修改或恢复历史版本时同步变化的字段
+	onConfigChange(func(old, c *Config) {
+		syncEntry(schedTimesEntry, strconv.Itoa(old.SchedTimes), strconv.Itoa(c.SchedTimes))
+		syncEntry(schedCronEntry, old.SchedCron, c.SchedCron)
+		syncEntry(schedCronDaysEntry, strconv.Itoa(old.SchedCronDays), strconv.Itoa(c.SchedCronDays))
+		if old.CronTimezone != c.CronTimezone {
+			updateSchedCronPreview(schedCronEntry.Text)
+		}
+		dryRunCheck.SetChecked(c.SchedDryRun)
+	})
+
+	cronContainer := container.NewHBox(
+		dryRunCheck,
+		widget.NewLabel("Cron:"),
+		container.NewGridWrap(fyne.NewSize(220, utils.LEBHeight), schedCronEntry),
+		widget.NewLabel("最近"),
+		container.NewGridWrap(fyne.NewSize(50, utils.LEBHeight), schedCronDaysEntry),
+		widget.NewLabel("天"),
+		container.NewGridWrap(fyne.NewSize(120, utils.LEBHeight), saveCronButton),
+		schedCronPreview,
+	)
+
 	// 初始化无限进度条
 	progressBar.Start()
 	time.Sleep(10 * time.Millisecond) // 只需要很短的时间
@@ -256,6 +280,7 @@ func createSchedUI(config *Config, myWindow fyne.Window) fyne.CanvasObject {
 	return container.NewVBox(
 		container.NewBorder(nil, nil, nil, TimesContainer, dateUI), // 将保存配置的 UI 组件布局在日期 UI 的右边
 		orderNumberEntry, // 添加编号输入框容器
+		cronContainer,
 		folderScannerUI,
 		schedLogText,
 	)
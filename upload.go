@@ -2,42 +2,167 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go-uposs/pipeline"
+	"go-uposs/storage"
 	"go-uposs/utils"
+)
+
+// defaultUploadWorkers 未配置 upload_workers 时使用的上传协程数
+const defaultUploadWorkers = 4
 
-	"github.com/minio/minio-go/v7"
+var (
+	// api1Limiter、api2Limiter 由所有上传协程（包括自动任务和计划任务）共享，限制 API 请求频率
+	api1Limiter = utils.NewRateLimiter(0)
+	api2Limiter = utils.NewRateLimiter(0)
 )
 
-// UploadImagesToMinio 上传本地路径中的所有图片到 minio
-func UploadImagesToMinio(client *minio.Client, bucketName, localPath, minioPath string, api1URL, api2URL string, isScheduledTask bool, config *Config) (int, error) {
+// uploadItem 待上传的本地文件
+type uploadItem struct {
+	path string
+	info os.FileInfo
+}
+
+// uploadBatch 一次上传批次中所有上传协程共享的参数和计数
+type uploadBatch struct {
+	store      storage.ObjectStore
+	storeID    string // 对象哈希索引中的存储标识
+	bucketName string
+	localPath  string
+	minioPath  string
+	api1URL    string
+	api2URL    string
+	config     *Config
+
+	fileCount     int64 // 统计处理文件数量
+	uploadedCount int64 // 统计成功上传的文件数量
+}
+
+// UploadImagesToMinio 上传本地路径中的所有图片到存储 store 的存储桶
+// 文件按解析出的第一个编号分组，同一编号的文件由同一个协程按顺序处理，不同编号并发上传
+func UploadImagesToMinio(ctx context.Context, rc *pipeline.RunContext, store storage.ObjectStore, localPath, minioPath string, api1URL, api2URL string, config *Config) (int, error) {
+	bucketName := store.Bucket()
+
 	// 检查存储桶是否存在
-	exists, err := client.BucketExists(context.Background(), bucketName)
+	exists, err := store.BucketExists(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("检查存储桶失败❌😅: %v", err)
 	}
-	if !exists {
-		err = client.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{})
+	if !exists && rc.DryRun {
+		dryRunLog(rc, "存储桶 %s 不存在，将创建存储桶", bucketName)
+	} else if !exists {
+		err = store.MakeBucket(ctx)
 		if err != nil {
 			return 0, fmt.Errorf("创建存储桶失败❌😅: %v", err)
 		}
-		logUploadMessage(fmt.Sprintf("存储桶 %s 已创建", bucketName), isScheduledTask)
+		rc.Log(fmt.Sprintf("存储桶 %s 已创建", bucketName))
 	} else {
-		logUploadMessage(fmt.Sprintf("存储桶 %s 已存在", bucketName), isScheduledTask)
+		rc.Log(fmt.Sprintf("存储桶 %s 已存在", bucketName))
+	}
+
+	groups, err := collectUploadGroups(ctx, rc, localPath, config)
+	if err != nil {
+		return 0, err
+	}
+	if len(groups) == 0 {
+		return 0, nil
+	}
+
+	workers := config.UploadWorkers
+	if workers <= 0 {
+		workers = defaultUploadWorkers
+	}
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+
+	// 每个批次按最新配置调整共享限速
+	api1Limiter.SetRate(config.API1RateLimit)
+	api2Limiter.SetRate(config.API2RateLimit)
+
+	rc.Log(fmt.Sprintf("共 %d 组待上传文件，使用 %d 个上传协程", len(groups), workers))
+
+	b := &uploadBatch{
+		store:      store,
+		storeID:    storeIdentity(config),
+		bucketName: bucketName,
+		localPath:  localPath,
+		minioPath:  minioPath,
+		api1URL:    api1URL,
+		api2URL:    api2URL,
+		config:     config,
+	}
+
+	groupCh := make(chan []uploadItem)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range groupCh {
+				for _, item := range group {
+					// 任务被取消时跳过剩余文件
+					if ctx.Err() != nil {
+						break
+					}
+					b.uploadFile(ctx, rc, item)
+				}
+			}
+		}()
 	}
 
-	fileCount := 0     // 统计处理文件数量
-	uploadedCount := 0 // 统计成功上传的文件数量
+feed:
+	for _, group := range groups {
+		select {
+		case groupCh <- group:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(groupCh)
+	wg.Wait()
+
+	if b.fileCount > 0 {
+		rc.Log(fmt.Sprintf("共处理 %d 个文件", b.fileCount))
+	}
+	return int(b.uploadedCount), ctx.Err()
+}
 
-	// 遍历本地路径下的所有文件
-	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+// collectUploadGroups 遍历本地路径，收集时间范围内的图片并按第一个编号分组
+// 无法解析编号的文件单独成组，分组顺序与遍历顺序一致
+func collectUploadGroups(ctx context.Context, rc *pipeline.RunContext, localPath string, config *Config) ([][]uploadItem, error) {
+	var groups [][]uploadItem
+	groupIndex := make(map[string]int)
+	addItem := func(item uploadItem) {
+		key := item.path
+		if orderNumbers := utils.ParseImageName(item.info.Name()); len(orderNumbers) > 0 {
+			key = orderNumbers[0]
+		}
+
+		if i, ok := groupIndex[key]; ok {
+			groups[i] = append(groups[i], item)
+		} else {
+			groupIndex[key] = len(groups)
+			groups = append(groups, []uploadItem{item})
+		}
+	}
+
+	err := filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// 任务被取消时立即结束遍历
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if info.IsDir() {
 			return nil
 		}
@@ -51,177 +176,381 @@ func UploadImagesToMinio(client *minio.Client, bucketName, localPath, minioPath
 		// 获取文件夹名称
 		dir := filepath.Base(filepath.Dir(path))
 		// 检查文件夹是否在时间范围内
-		if !isFolderInTimeRange(dir, isScheduledTask, config) {
+		if !isFolderInTimeRange(dir, rc, config) {
 			return nil
 		}
 
-		fileCount++
-		orderNumbers := utils.ParseImageName(info.Name())
+		addItem(uploadItem{path: path, info: info})
+		return nil
+	})
+	if err != nil {
+		return groups, err
+	}
 
-		// 解析文件名中的订单编号
-		if len(orderNumbers) == 0 {
-			logUploadMessage(fmt.Sprintf("无法从文件名解析编号: %s，删除此文件", info.Name()), isScheduledTask)
-			err = os.Remove(path)
-			if err != nil {
-				logUploadMessage(fmt.Sprintf("删除无编号文件失败❌😅: %s, 错误: %v", path, err), isScheduledTask)
-				return nil
+	// 模拟运行时将要复制的文件按复制后的本地路径加入
+	if rc.DryRun {
+		for _, c := range dryRunPlanOf(rc).plannedCopies() {
+			if isImageFile(c.info.Name()) && isFolderInTimeRange(filepath.Base(filepath.Dir(c.dst)), rc, config) {
+				addItem(uploadItem{path: c.dst, info: c.info})
 			}
-			logUploadMessage(fmt.Sprintf("已删除无编号文件: %s", path), isScheduledTask)
-			return nil
 		}
+	}
 
-		logUploadMessage(fmt.Sprintf("从文件名 %s 解析到的编号: %s", info.Name(), strings.Join(orderNumbers, ", ")), isScheduledTask)
+	return groups, nil
+}
 
-		validOrderFound := false
-		var validOrderNumber string
-		var explicitInvalid bool
+// uploadFile 处理单个文件：查询 API1 → 上传 minio → 推送 API2 → 删除本地文件
+// 各步骤失败只记录日志，不影响其他文件
+func (b *uploadBatch) uploadFile(ctx context.Context, rc *pipeline.RunContext, item uploadItem) {
+	path, info := item.path, item.info
 
-		// 遍历每个订单编号，尝试查询API1
-		for _, orderNumber := range orderNumbers {
-			// 对每个订单号尝试2次
-			for retry := 0; retry < 2; retry++ {
-				logUploadMessage(fmt.Sprintf("正在向 API1 查询编号: %s (第%d次尝试)", orderNumber, retry+1), isScheduledTask)
-				apiResponse, err := utils.QueryAPI1(api1URL, orderNumber)
+	atomic.AddInt64(&b.fileCount, 1)
 
-				if err != nil {
-					logUploadMessage(fmt.Sprintf("API1 查询失败❌😅: 编号: %s 第%d次尝试 错误: %v", orderNumber, retry+1, err), isScheduledTask)
-					if retry < 1 {
-						logUploadMessage("等待20秒后重试...", isScheduledTask)
-						time.Sleep(20 * time.Second)
-					}
-					continue // 重试当前订单号
-				}
+	// 根据处理记录恢复中断的流程
+	job, err := utils.GetJob(rc.Profile, info.Name())
+	if err != nil {
+		rc.Log(fmt.Sprintf("查询文件处理记录失败: %s, 错误: %v", info.Name(), err))
+	} else if job != nil && job.State == utils.JobPushed {
+		// 已推送但删除本地文件前中断，直接删除
+		if rc.DryRun {
+			b.dryRunDelete(rc, path, "已推送到 API2")
+			return
+		}
+		rc.Log(fmt.Sprintf("文件 %s 已推送到 API2，删除本地文件", info.Name()))
+		if err := os.Remove(path); err == nil {
+			runStatsOf(rc).addDeleted()
+			rc.Log(fmt.Sprintf("本地文件已删除: %s", path))
+		}
+		return
+	} else if job != nil && job.Reached(utils.JobUploaded) && job.ObjectKey != "" && job.OrderNumber != "" {
+		// 已上传但未推送成功，跳过 API1 查询和上传，只推送 API2
+		rc.Log(fmt.Sprintf("文件 %s 已上传到 %s，继续推送 API2", info.Name(), job.ObjectKey))
+		b.pushFile(ctx, rc, item, job.OrderNumber, job.ObjectKey)
+		return
+	}
 
-				// 检查是否为有效订单
-				if strings.HasPrefix(apiResponse, config.API1Response1) {
-					logUploadMessage(fmt.Sprintf("API1 查询成功，编号: %s 有效, 响应: %s", orderNumber, apiResponse), isScheduledTask)
-					validOrderFound = true
-					validOrderNumber = orderNumber
-					break // 跳出当前订单号的重试循环
-				}
+	orderNumbers := utils.ParseImageName(info.Name())
+
+	// 解析文件名中的订单编号
+	if len(orderNumbers) == 0 {
+		if rc.DryRun {
+			b.dryRunDelete(rc, path, "无法从文件名解析编号")
+			return
+		}
+		rc.Log(fmt.Sprintf("无法从文件名解析编号: %s，删除此文件", info.Name()))
+		if err := os.Remove(path); err != nil {
+			rc.Log(fmt.Sprintf("删除无编号文件失败❌😅: %s, 错误: %v", path, err))
+			return
+		}
+		runStatsOf(rc).addDeleted()
+		rc.Log(fmt.Sprintf("已删除无编号文件: %s", path))
+		return
+	}
+
+	rc.Log(fmt.Sprintf("从文件名 %s 解析到的编号: %s", info.Name(), strings.Join(orderNumbers, ", ")))
+
+	validOrderFound := false
+	var validOrderNumber string
+	var explicitInvalid bool
+
+	// 遍历每个订单编号，尝试查询API1
+	for _, orderNumber := range orderNumbers {
+		// 对每个订单号尝试2次
+		for retry := 0; retry < 2; retry++ {
+			rc.Log(fmt.Sprintf("正在向 API1 查询编号: %s (第%d次尝试)", orderNumber, retry+1))
+			if err := api1Limiter.Wait(ctx); err != nil {
+				return
+			}
+			apiResponse, err := utils.QueryAPI1(ctx, b.api1URL, orderNumber)
 
-				// 检查是否为明确无效订单
-				if strings.HasPrefix(apiResponse, config.API1Response2) {
-					logUploadMessage(fmt.Sprintf("API1 查询返回无效状态: 编号: %s, 响应: %s", orderNumber, apiResponse), isScheduledTask)
-					// 这里不设置explicitInvalid，继续尝试其他订单号
-					break // 跳出当前订单号的重试循环
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				rc.Log(fmt.Sprintf("API1 查询失败❌😅: 编号: %s 第%d次尝试 错误: %v", orderNumber, retry+1, err))
+				if retry < 1 {
+					rc.Log("等待20秒后重试...")
+					if err := pipeline.Sleep(ctx, 20*time.Second); err != nil {
+						return
+					}
 				}
+				continue // 重试当前订单号
+			}
 
-				logUploadMessage(fmt.Sprintf("跳过此订单号，API1 返回未定义响应: 编号: %s, 响应: %s", orderNumber, apiResponse), isScheduledTask)
+			// 检查是否为有效订单
+			if strings.HasPrefix(apiResponse, b.config.API1Response1) {
+				rc.Log(fmt.Sprintf("API1 查询成功，编号: %s 有效, 响应: %s", orderNumber, apiResponse))
+				validOrderFound = true
+				validOrderNumber = orderNumber
 				break // 跳出当前订单号的重试循环
 			}
 
-			// 如果找到有效订单，立即退出整个订单号循环
-			if validOrderFound {
-				break
+			// 检查是否为明确无效订单
+			if strings.HasPrefix(apiResponse, b.config.API1Response2) {
+				rc.Log(fmt.Sprintf("API1 查询返回无效状态: 编号: %s, 响应: %s", orderNumber, apiResponse))
+				// 这里不设置explicitInvalid，继续尝试其他订单号
+				break // 跳出当前订单号的重试循环
 			}
+
+			rc.Log(fmt.Sprintf("跳过此订单号，API1 返回未定义响应: 编号: %s, 响应: %s", orderNumber, apiResponse))
+			break // 跳出当前订单号的重试循环
 		}
 
-		// 处理所有订单号后的结果判断
+		// 如果找到有效订单，立即退出整个订单号循环
 		if validOrderFound {
-			// 处理有效订单的逻辑
-			logUploadMessage(fmt.Sprintf("找到有效订单: %s", validOrderNumber), isScheduledTask)
-		} else {
-			// 所有订单号都无效或未定义的情况
-			logUploadMessage("所有订单号均无效或未定义", isScheduledTask)
-			explicitInvalid = true
+			break
 		}
+	}
 
-		// 如果没有找到有效的订单编号，且没有明确的无效状态，则跳过此文件
-		if !validOrderFound && explicitInvalid {
-			logUploadMessage(fmt.Sprintf("文件 %s 中没有有效编号（定义无效状态），删除此文件", info.Name()), isScheduledTask)
-			err := os.Remove(path)
-			if err != nil {
-				logUploadMessage(fmt.Sprintf("删除无效编号文件失败❌😅: %s, 错误: %v", path, err), isScheduledTask)
-			} else {
-				logUploadMessage(fmt.Sprintf("已删除无效编号文件: %s", path), isScheduledTask)
-			}
-			return nil
-		}
+	// 处理所有订单号后的结果判断
+	if validOrderFound {
+		// 处理有效订单的逻辑
+		rc.Log(fmt.Sprintf("找到有效订单: %s", validOrderNumber))
+	} else {
+		// 所有订单号都无效或未定义的情况
+		rc.Log("所有订单号均无效或未定义")
+		explicitInvalid = true
+	}
 
-		relPath, err := filepath.Rel(localPath, path)
-		if err != nil {
-			logUploadMessage(fmt.Sprintf("获取相对路径失败❌😅: %v", err), isScheduledTask)
-			return nil
+	// 如果没有找到有效的订单编号，且没有明确的无效状态，则跳过此文件
+	if !validOrderFound && explicitInvalid {
+		if rc.DryRun {
+			b.dryRunDelete(rc, path, "没有有效编号")
+			return
+		}
+		rc.Log(fmt.Sprintf("文件 %s 中没有有效编号（定义无效状态），删除此文件", info.Name()))
+		if _, err := utils.MarkJobFailed(rc.Profile, info.Name(), filepath.Base(filepath.Dir(path)), path, fmt.Errorf("API1 未查询到有效编号，文件已删除")); err != nil {
+			rc.Log(fmt.Sprintf("记录文件处理失败状态失败: %v", err))
 		}
-		var datePath string
-		if filepath.Dir(relPath) == "." {
-			datePath = time.Now().Format("2006.01.02")
+		err := os.Remove(path)
+		if err != nil {
+			rc.Log(fmt.Sprintf("删除无效编号文件失败❌😅: %s, 错误: %v", path, err))
 		} else {
-			datePath = filepath.Dir(relPath)
+			runStatsOf(rc).addDeleted()
+			rc.Log(fmt.Sprintf("已删除无效编号文件: %s", path))
 		}
+		return
+	}
 
-		//构造 minio 文件路径
-		minioFilePath := fmt.Sprintf("%s/%s/%s", minioPath, datePath, info.Name())
-		minioFilePath = strings.ReplaceAll(minioFilePath, "\\", "/")
+	relPath, err := filepath.Rel(b.localPath, path)
+	if err != nil {
+		rc.Log(fmt.Sprintf("获取相对路径失败❌😅: %v", err))
+		return
+	}
+	var datePath string
+	if filepath.Dir(relPath) == "." {
+		datePath = time.Now().Format("2006.01.02")
+	} else {
+		datePath = filepath.Dir(relPath)
+	}
 
-		// 从 图片配置 中获取图片大小限制作为上传大小限制，单位为 KB，转换为字节
-		maxFileSize := int64(config.PicSize) * 1024
-		if info.Size() > maxFileSize {
-			logUploadMessage(fmt.Sprintf("文件 %s 大小超过限制（%d 字节），跳过上传", info.Name(), maxFileSize), isScheduledTask)
-			return nil
+	//构造 minio 文件路径
+	minioFilePath := fmt.Sprintf("%s/%s/%s", b.minioPath, datePath, info.Name())
+	minioFilePath = strings.ReplaceAll(minioFilePath, "\\", "/")
+
+	// 从 图片配置 中获取图片大小限制作为上传大小限制，单位为 KB，转换为字节
+	maxFileSize := int64(b.config.PicSize) * 1024
+	if rc.DryRun {
+		b.dryRunUpload(ctx, rc, item, validOrderNumber, minioFilePath, maxFileSize)
+		return
+	}
+	if info.Size() > maxFileSize {
+		rc.Log(fmt.Sprintf("文件 %s 大小超过限制（%d 字节），跳过上传", info.Name(), maxFileSize))
+		recordJobFailure(rc, path, fmt.Errorf("文件大小超过限制（%d 字节）", maxFileSize))
+		return
+	}
+
+	// 存储中已有相同内容时（例如上次上传后推送失败、处理记录被重置）跳过上传
+	sum, err := storage.FileMD5(path)
+	if err != nil {
+		rc.Log(fmt.Sprintf("计算文件 MD5 失败: %s, 错误: %v，不检查存储中的内容", path, err))
+	}
+	if sum != "" && b.contentUnchanged(ctx, rc, minioFilePath, sum, info.Size()) {
+		rc.Log(fmt.Sprintf("存储中已有内容相同的 %s（MD5 %s），跳过上传", minioFilePath, sum))
+		runStatsOf(rc).addSkipped()
+	} else {
+		//上传文件到存储
+		if err := b.store.Put(ctx, minioFilePath, path); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			rc.Log(fmt.Sprintf("上传文件失败❌😅: %s -> %s, 错误: %v", path, minioFilePath, err))
+			recordJobFailure(rc, path, fmt.Errorf("上传失败: %v", err))
+			return
 		}
+		runStatsOf(rc).addUploaded(info.Size())
+	}
 
-		//上传文件到 minio
-		_, err = client.FPutObject(context.Background(), bucketName, minioFilePath, path, minio.PutObjectOptions{})
+	if sum != "" {
+		err := utils.RecordObjectHash(&utils.ObjectHash{
+			Store: b.storeID, Bucket: b.bucketName, ObjectKey: minioFilePath,
+			FileName: info.Name(), CopyDir: filepath.Base(filepath.Dir(path)), MD5: sum, Size: info.Size(),
+		})
 		if err != nil {
-			logUploadMessage(fmt.Sprintf("上传文件失败❌😅: %s -> %s, 错误: %v", path, minioFilePath, err), isScheduledTask)
-			return nil
+			rc.Log(fmt.Sprintf("记录对象哈希失败: %v", err))
 		}
+	}
+	if err := utils.MarkJobUploaded(rc.Profile, info.Name(), filepath.Base(filepath.Dir(path)), path, validOrderNumber, minioFilePath); err != nil {
+		rc.Log(fmt.Sprintf("记录文件上传状态失败: %v", err))
+	}
+	rc.Log("文件上传成功，向 API2 推送编号文件访问地址")
+	b.pushFile(ctx, rc, item, validOrderNumber, minioFilePath)
+}
 
-		fileUrl := fmt.Sprintf("%s/%s/%s", config.PublicUrl, bucketName, minioFilePath)
-		logUploadMessage("文件上传成功，向 API2 推送编号文件访问地址", isScheduledTask)
+// contentUnchanged 判断存储中的 key 是否与本地文件内容相同（MD5 为 sum，大小为 size），相同时不需要重新上传
+// 对象不存在或查询失败时返回 false；MinIO 单次上传的 ETag 即内容的 MD5，直接比较，
+// 其他情况（分片上传、本地文件夹、WebDAV、SFTP 的 ETag 不一定是 MD5）按本地哈希索引中上次上传的 MD5 和存储中的对象大小判断
+func (b *uploadBatch) contentUnchanged(ctx context.Context, rc *pipeline.RunContext, key, sum string, size int64) bool {
+	info, err := b.store.Stat(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		if rc.DryRun {
+			return false
+		}
+		if err := utils.DeleteObjectHash(b.storeID, b.bucketName, key); err != nil {
+			rc.Log(fmt.Sprintf("删除对象哈希记录失败: %v", err))
+		}
+		return false
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			rc.Log(fmt.Sprintf("查询存储中的 %s 失败，直接上传: %v", key, err))
+		}
+		return false
+	}
 
-		// 推送到API2
-		var api2Err error
-		for retry := 0; retry <= 1; retry++ {
-			_, api2Err = utils.PushToAPI2(api2URL, validOrderNumber, fileUrl)
-			if api2Err == nil {
-				logUploadMessage(fmt.Sprintf("推送到 API2 成功😎 (第%d次尝试)，编号: %s，文件访问地址: %s", retry+1, validOrderNumber, fileUrl), isScheduledTask)
-				err := os.Remove(path)
-				if err == nil {
-					logUploadMessage(fmt.Sprintf("本地文件已删除: %s", path), isScheduledTask)
-				}
-				uploadedCount++
-				break
-			}
-			if retry == 0 {
-				time.Sleep(20 * time.Second)
-			}
+	// ETagMD5 对分片上传（带 -N 后缀）的 ETag 返回 false
+	if etag, ok := storage.ETagMD5(info.ETag); ok && b.store.Type() == storageMinio {
+		if etag == sum {
+			return true
 		}
-		if api2Err != nil {
-			logUploadMessage("第 2 次推送 API2 失败❌😅，跳过此推送", isScheduledTask)
+		rc.Log(fmt.Sprintf("存储中的 %s 与本地文件内容不同（ETag %s，本地 MD5 %s），重新上传覆盖", key, etag, sum))
+		return false
+	}
+
+	indexed, err := utils.GetObjectHash(b.storeID, b.bucketName, key)
+	if err != nil {
+		rc.Log(fmt.Sprintf("查询对象哈希记录失败: %v", err))
+		return false
+	}
+	if indexed == nil {
+		return false
+	}
+	if indexed.MD5 != sum {
+		rc.Log(fmt.Sprintf("%s 的内容已变化（上次上传 MD5 %s，本地 MD5 %s），重新上传覆盖", key, indexed.MD5, sum))
+		return false
+	}
+	// 哈希相同但存储中的大小不同，说明对象已被其他程序修改
+	return indexed.Size == size && info.Size == size
+}
+
+// pushFile 推送已上传文件的访问地址到 API2，成功后删除本地文件
+// 推送失败时写入推送待办表由后台补推，本地文件同样删除，不需要重新上传
+func (b *uploadBatch) pushFile(ctx context.Context, rc *pipeline.RunContext, item uploadItem, validOrderNumber, minioFilePath string) {
+	path, info := item.path, item.info
+	fileUrl := objectURL(b.config, b.bucketName, minioFilePath)
+
+	if rc.DryRun {
+		dryRunLog(rc, "将推送到 API2，编号: %s，文件访问地址: %s", validOrderNumber, fileUrl)
+		runStatsOf(rc).addPushed()
+		b.dryRunDelete(rc, path, "推送完成")
+		atomic.AddInt64(&b.uploadedCount, 1)
+		return
+	}
+
+	// 推送到API2
+	if err := api2Limiter.Wait(ctx); err != nil {
+		return
+	}
+	_, api2Err := utils.PushToAPI2(ctx, b.api2URL, validOrderNumber, fileUrl)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if api2Err == nil {
+		rc.Log(fmt.Sprintf("推送到 API2 成功😎，编号: %s，文件访问地址: %s", validOrderNumber, fileUrl))
+		runStatsOf(rc).addPushed()
+		if err := utils.SetJobState(rc.Profile, info.Name(), filepath.Base(filepath.Dir(path)), path, utils.JobPushed); err != nil {
+			rc.Log(fmt.Sprintf("记录文件推送状态失败: %v", err))
 		}
-		return nil
-	})
+	} else {
+		rc.Log(fmt.Sprintf("推送 API2 失败❌😅: 编号: %s 错误: %v", validOrderNumber, api2Err))
+		payload, err := utils.BuildAPI2Payload(validOrderNumber, fileUrl)
+		if err == nil {
+			err = utils.EnqueueAPI2Push(rc.Profile, info.Name(), validOrderNumber, fileUrl, payload, api2Err.Error())
+		}
+		if err != nil {
+			// 写入待办失败时保留本地文件，下个周期按处理记录重新推送
+			rc.Log(fmt.Sprintf("写入推送待办失败❌😅: %v", err))
+			recordJobFailure(rc, path, fmt.Errorf("推送 API2 失败: %v", api2Err))
+			return
+		}
+		rc.Log("已加入推送待办，后台将自动重试推送")
+	}
 
-	if fileCount > 0 {
-		logUploadMessage(fmt.Sprintf("共处理 %d 个文件", fileCount), isScheduledTask)
+	if err := os.Remove(path); err == nil {
+		runStatsOf(rc).addDeleted()
+		rc.Log(fmt.Sprintf("本地文件已删除: %s", path))
 	}
-	return uploadedCount, err
+	atomic.AddInt64(&b.uploadedCount, 1)
 }
 
-// UploadImages 根据配置上传本地路径中的所有图片到 minio
-func UploadImages(config *Config) error {
-	return UploadImagesWithTaskType(config, true) // 默认为计划任务
+// dryRunDelete 模拟运行时报告将要删除的本地文件
+func (b *uploadBatch) dryRunDelete(rc *pipeline.RunContext, path, reason string) {
+	dryRunLog(rc, "%s，将删除本地文件: %s", reason, path)
+	runStatsOf(rc).addDeleted()
 }
 
-// UploadImagesWithTaskType 根据配置上传本地路径中的所有图片到 minio，指定任务类型
-func UploadImagesWithTaskType(config *Config, isScheduledTask bool) error {
+// dryRunUpload 模拟运行时报告将要上传的文件，大小按压缩阶段估算的结果判断
+func (b *uploadBatch) dryRunUpload(ctx context.Context, rc *pipeline.RunContext, item uploadItem, validOrderNumber, minioFilePath string, maxFileSize int64) {
+	plan := dryRunPlanOf(rc)
+	size := item.info.Size()
+	if compressed, ok := plan.compressedSize(item.path); ok {
+		size = compressed
+	}
+	if size > maxFileSize {
+		dryRunLog(rc, "文件 %s 大小超过限制（%d 字节），将跳过上传", item.info.Name(), maxFileSize)
+		return
+	}
+
+	// 将要压缩的文件内容还会变化，将要复制的文件还不在本地，只检查已在本地且不再压缩的文件
+	if _, planned := plan.compressedSize(item.path); !planned {
+		if sum, err := storage.FileMD5(item.path); err == nil && b.contentUnchanged(ctx, rc, minioFilePath, sum, item.info.Size()) {
+			dryRunLog(rc, "存储中已有内容相同的 %s，将跳过上传", minioFilePath)
+			runStatsOf(rc).addSkipped()
+			b.pushFile(ctx, rc, item, validOrderNumber, minioFilePath)
+			return
+		}
+	}
+
+	dryRunLog(rc, "将上传文件: %s -> %s/%s (%dKB)", item.path, b.bucketName, minioFilePath, size/1024)
+	runStatsOf(rc).addUploaded(size)
+	b.pushFile(ctx, rc, item, validOrderNumber, minioFilePath)
+}
+
+// errNoFilesToUpload 本地文件夹中没有需要上传的图片
+var errNoFilesToUpload = errors.New("无文件可上传")
+
+// UploadImagesWithTaskType 根据配置上传本地路径中的所有图片到 minio，任务类型由 rc 指定
+func UploadImagesWithTaskType(ctx context.Context, rc *pipeline.RunContext, config *Config) error {
 	hasImages, err := checkForImages(config.LocalFolder)
 	if err != nil {
 		return fmt.Errorf("检查图片文件失败❌😅: %v", err)
 	}
+	// 模拟运行时将要复制的文件还不在本地
+	if rc.DryRun && len(dryRunPlanOf(rc).plannedCopies()) > 0 {
+		hasImages = true
+	}
 	if !hasImages {
-		return fmt.Errorf("无文件可上传")
+		return errNoFilesToUpload
 	}
 
-	client, err := InitMinioClient(config, config.UseSSL)
+	store, err := openObjectStore(config)
 	if err != nil {
-		return fmt.Errorf("初始化 minio 客户端失败❌😅: %v", err)
+		return fmt.Errorf("初始化存储失败❌😅: %v", err)
 	}
-	if err := TestConnection(client); err != nil {
-		return fmt.Errorf("minio 连接测试失败❌😅: %v", err)
+	defer store.Close()
+	if _, err := checkObjectStore(ctx, store); err != nil {
+		return fmt.Errorf("存储连接测试失败❌😅: %v", err)
 	}
 
 	machineCode := config.MachineCode
@@ -229,17 +558,19 @@ func UploadImagesWithTaskType(config *Config, isScheduledTask bool) error {
 		return fmt.Errorf("配置中的 machine_code 不能为空")
 	}
 
-	logUploadMessage(fmt.Sprintf("开始上传图片，本地路径: %s, minio 路径: %s", config.LocalFolder, machineCode), isScheduledTask)
+	rc.Log(fmt.Sprintf("开始上传图片，本地路径: %s, 存储: %s, 对象路径: %s", config.LocalFolder, storageTypeLabel(store.Type()), machineCode))
 
-	uploadedCount, err := UploadImagesToMinio(client, config.BucketName, config.LocalFolder, machineCode, config.API1, config.API2, isScheduledTask, config)
+	uploadedCount, err := UploadImagesToMinio(ctx, rc, store, config.LocalFolder, machineCode, config.API1, config.API2, config)
 	if err != nil {
 		return fmt.Errorf("上传图片失败❌😅: %v", err)
 	}
 
-	if uploadedCount == 0 {
-		logUploadMessage("所有文件均被跳过或处理失败❌😅，未成功上传任何图片", isScheduledTask)
+	if rc.DryRun {
+		dryRunLog(rc, "图片上传检查完成，共 %d 张将上传并推送", uploadedCount)
+	} else if uploadedCount == 0 {
+		rc.Log("所有文件均被跳过或处理失败❌😅，未成功上传任何图片")
 	} else {
-		logUploadMessage(fmt.Sprintf("图片上传完成，共上传 %d 张", uploadedCount), isScheduledTask)
+		rc.Log(fmt.Sprintf("图片上传完成，共上传 %d 张", uploadedCount))
 	}
 
 	return nil
@@ -270,12 +601,3 @@ func checkForImages(path string) (bool, error) {
 
 	return hasImages, err
 }
-
-// logUploadMessage 记录上传相关日志，根据任务类型选择不同日志记录函数
-func logUploadMessage(message string, isScheduledTask bool) {
-	if isScheduledTask {
-		SchedLogToFile(message)
-	} else {
-		AutoLogToFile(message)
-	}
-}
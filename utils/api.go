@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,14 +11,14 @@ import (
 	"time"
 )
 
-// QueryAPI1 调用API1进行编号验证
-func QueryAPI1(apiURL, orderNumber string) (string, error) {
+// QueryAPI1 调用API1进行编号验证，ctx 取消时立即中断请求
+func QueryAPI1(ctx context.Context, apiURL, orderNumber string) (string, error) {
 	// 方法2：使用表单数据
 	formData := url.Values{}
 	formData.Set("orderCode", orderNumber)
 
 	// 创建POST请求
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(formData.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("创建请求失败: %v", err)
 	}
@@ -60,8 +61,17 @@ func QueryAPI1(apiURL, orderNumber string) (string, error) {
 	return fmt.Sprintf("%d:%s", response.Code, string(body)), nil
 }
 
-// PushToAPI2 推送编号和文件URL到API2，使用POST请求和JSON格式
-func PushToAPI2(apiURL string, orderNumber string, fileUrl string) (string, error) {
+// PushToAPI2 推送编号和文件URL到API2，使用POST请求和JSON格式，ctx 取消时立即中断请求
+func PushToAPI2(ctx context.Context, apiURL string, orderNumber string, fileUrl string) (string, error) {
+	jsonData, err := BuildAPI2Payload(orderNumber, fileUrl)
+	if err != nil {
+		return "", err
+	}
+	return PostAPI2(ctx, apiURL, jsonData)
+}
+
+// BuildAPI2Payload 构造推送到 API2 的 JSON 请求体
+func BuildAPI2Payload(orderNumber string, fileUrl string) ([]byte, error) {
 	// 创建请求体数据结构
 	requestData := struct {
 		OrderNumber string `json:"orderNumber"`
@@ -74,8 +84,13 @@ func PushToAPI2(apiURL string, orderNumber string, fileUrl string) (string, erro
 	// 将数据结构转换为JSON
 	jsonData, err := json.Marshal(requestData)
 	if err != nil {
-		return "", fmt.Errorf("JSON编码失败: %v", err)
+		return nil, fmt.Errorf("JSON编码失败: %v", err)
 	}
+	return jsonData, nil
+}
+
+// PostAPI2 将已构造好的 JSON 请求体推送到 API2，推送待办补推时直接使用保存的请求体
+func PostAPI2(ctx context.Context, apiURL string, jsonData []byte) (string, error) {
 
 	// 设置超时时间
 	client := &http.Client{
@@ -83,7 +98,7 @@ func PushToAPI2(apiURL string, orderNumber string, fileUrl string) (string, erro
 	}
 
 	// 创建POST请求
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(string(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(string(jsonData)))
 	if err != nil {
 		return "", fmt.Errorf("创建请求失败: %v", err)
 	}
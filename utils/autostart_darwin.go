@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LaunchAgentLabel macOS 登录项的标识，同时作为 plist 文件名
+const LaunchAgentLabel = "com.apotato.gouposs"
+
+// launchAgentPath 返回当前用户 LaunchAgent 的 plist 路径
+func launchAgentPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户目录失败: %v", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", LaunchAgentLabel+".plist"), nil
+}
+
+// IsAutoStartEnabled 检查程序是否已设置为开机自启动
+func IsAutoStartEnabled() bool {
+	path, err := launchAgentPath()
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	// 检查登录项中的路径是否与当前程序路径一致
+	exePath, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), "<string>"+html.EscapeString(filepath.Clean(exePath))+"</string>")
+}
+
+// EnableAutoStart 设置程序开机自启动，写入 ~/Library/LaunchAgents 登录项，下次登录时生效
+func EnableAutoStart() error {
+	path, err := launchAgentPath()
+	if err != nil {
+		return err
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取程序路径失败: %v", err)
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>%s</string>
+    <key>ProgramArguments</key>
+    <array>
+        <string>%s</string>
+    </array>
+    <key>RunAtLoad</key>
+    <true/>
+</dict>
+</plist>
+`, LaunchAgentLabel, html.EscapeString(filepath.Clean(exePath)))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建 LaunchAgents 目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(plist), 0644); err != nil {
+		return fmt.Errorf("写入登录项失败: %v", err)
+	}
+	return nil
+}
+
+// DisableAutoStart 取消程序开机自启动
+func DisableAutoStart() error {
+	path, err := launchAgentPath()
+	if err != nil {
+		return err
+	}
+	// 如果登录项不存在，不视为错误
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除登录项失败: %v", err)
+	}
+	return nil
+}
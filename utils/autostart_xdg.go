@@ -0,0 +1,136 @@
+//go:build !windows && !darwin
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	AutostartDesktopName = "gouposs.desktop" // XDG 自动启动项文件名
+	SystemdUnitName      = "gouposs.service" // systemd 用户服务名
+)
+
+// autostartDesktopPath 返回 XDG 自动启动项路径：$XDG_CONFIG_HOME/autostart/gouposs.desktop
+func autostartDesktopPath() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "autostart", AutostartDesktopName)
+}
+
+// systemdUnitPath 返回 systemd 用户服务路径：$XDG_CONFIG_HOME/systemd/user/gouposs.service
+func systemdUnitPath() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "systemd", "user", SystemdUnitName)
+}
+
+// hasGraphicalSession 判断当前是否运行在图形会话中
+func hasGraphicalSession() bool {
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// IsAutoStartEnabled 检查程序是否已设置为开机自启动（XDG 自动启动项或 systemd 用户服务）
+func IsAutoStartEnabled() bool {
+	exePath, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	exePath = filepath.Clean(exePath)
+
+	// 检查启动项中的路径是否与当前程序路径一致
+	for _, path := range []string{autostartDesktopPath(), systemdUnitPath()} {
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), quoteExec(exePath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableAutoStart 设置程序开机自启动
+// 图形会话中写入 XDG 自动启动项，登录桌面时启动界面；
+// 无图形会话（服务器）时写入 systemd 用户服务，登录后以命令行 auto 模式运行自动任务
+func EnableAutoStart() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取程序路径失败: %v", err)
+	}
+	exePath = filepath.Clean(exePath)
+
+	if hasGraphicalSession() {
+		entry := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=GO-UPOSS
+Comment=IMG Upload To Minio
+Exec=%s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, quoteExec(exePath))
+		return writeAutostartFile(autostartDesktopPath(), entry)
+	}
+
+	unit := fmt.Sprintf(`[Unit]
+Description=GO-UPOSS 自动任务
+After=network-online.target
+
+[Service]
+ExecStart=%s auto
+Restart=on-failure
+RestartSec=30
+
+[Install]
+WantedBy=default.target
+`, quoteExec(exePath))
+	if err := writeAutostartFile(systemdUnitPath(), unit); err != nil {
+		return err
+	}
+	if err := systemctlUser("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctlUser("enable", SystemdUnitName)
+}
+
+// DisableAutoStart 取消程序开机自启动，同时删除 XDG 自动启动项和 systemd 用户服务
+func DisableAutoStart() error {
+	// 如果启动项不存在，不视为错误
+	if err := os.Remove(autostartDesktopPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除自动启动项失败: %v", err)
+	}
+
+	unitPath := systemdUnitPath()
+	if _, err := os.Stat(unitPath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := systemctlUser("disable", SystemdUnitName); err != nil {
+		return err
+	}
+	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除 systemd 用户服务失败: %v", err)
+	}
+	return systemctlUser("daemon-reload")
+}
+
+// quoteExec 为 Exec / ExecStart 中的程序路径加引号（处理路径中的空格）
+func quoteExec(path string) string {
+	return `"` + strings.ReplaceAll(path, `"`, `\"`) + `"`
+}
+
+// writeAutostartFile 写入启动项文件，目录不存在时自动创建
+func writeAutostartFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建启动项目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("写入启动项失败: %v", err)
+	}
+	return nil
+}
+
+// systemctlUser 执行 systemctl --user 命令
+func systemctlUser(args ...string) error {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("执行 systemctl --user %s 失败: %v %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -43,6 +43,9 @@ func InitDB(config *DBConfig) error {
 			return
 		}
 
+		// 上传和压缩协程会并发写入，SQLite 只使用一个连接避免 database is locked
+		db.SetMaxOpenConns(1)
+
 		// 创建必要的表
 		err = createTables()
 	})
@@ -66,24 +69,121 @@ func CloseDB() error {
 // createTables 创建必要的表
 func createTables() error {
 	// 创建文件复制记录表
-	_, err := db.Exec(`
+	if err := createCopyRecordsTable(); err != nil {
+		return err
+	}
+
+	// 创建文件处理状态表
+	if err := createJobsTable(); err != nil {
+		return err
+	}
+
+	// 创建 API2 推送待办表
+	if err := createOutboxTable(); err != nil {
+		return err
+	}
+
+	// 创建定时任务记录表
+	if err := createScheduleTable(); err != nil {
+		return err
+	}
+
+	// 创建任务周期执行记录表
+	if err := createRunsTable(); err != nil {
+		return err
+	}
+
+	// 创建已上传对象的哈希索引表
+	if err := createObjectHashesTable(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// copyRecordsSchema 文件复制记录表，同一文件名在每个配置方案中各有一条记录
+const copyRecordsSchema = `
     CREATE TABLE IF NOT EXISTS copy_records (
         id INTEGER PRIMARY KEY AUTOINCREMENT,
-        file_name TEXT NOT NULL UNIQUE,
+        profile TEXT NOT NULL DEFAULT '',
+        file_name TEXT NOT NULL,
         copy_dir TEXT NOT NULL,
-        copy_time TIMESTAMP NOT NULL
-	)`)
-	if err != nil {
+        copy_time TIMESTAMP NOT NULL,
+        UNIQUE (profile, file_name)
+	)`
+
+// createCopyRecordsTable 创建文件复制记录表，旧版本以 file_name 为唯一键的表重建为按配置方案区分
+func createCopyRecordsTable() error {
+	if _, err := db.Exec(copyRecordsSchema); err != nil {
 		return fmt.Errorf("创建文件复制记录表失败: %v", err)
 	}
 
-	return nil
+	hasProfile, err := columnExists("copy_records", "profile")
+	if err != nil {
+		return fmt.Errorf("读取文件复制记录表结构失败: %v", err)
+	}
+	if hasProfile {
+		return nil
+	}
+
+	// SQLite 不能修改唯一约束，在事务中重建表，原有记录归入默认方案
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		`ALTER TABLE copy_records RENAME TO copy_records_old`,
+		copyRecordsSchema,
+		`INSERT INTO copy_records (id, profile, file_name, copy_dir, copy_time)
+        SELECT id, '', file_name, copy_dir, copy_time FROM copy_records_old`,
+		`DROP TABLE copy_records_old`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("升级文件复制记录表失败: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// columnExists 判断表 table 是否有 column 列
+func columnExists(table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumnIfMissing 旧版本数据库的表没有 column 列时按 definition 添加
+func addColumnIfMissing(table, column, definition string) error {
+	exists, err := columnExists(table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
 }
 
-// CheckFileExists 检查文件是否已经复制过
-func CheckFileExists(fileName string, isAutotask bool) (bool, error) {
+// CheckFileExists 检查文件是否已经由配置方案 profile 复制过，默认方案为空
+func CheckFileExists(profile, fileName string, isAutotask bool) (bool, error) {
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM copy_records WHERE file_name =?", fileName).Scan(&count)
+	err := db.QueryRow("SELECT COUNT(*) FROM copy_records WHERE profile = ? AND file_name = ?", profile, fileName).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -91,18 +191,18 @@ func CheckFileExists(fileName string, isAutotask bool) (bool, error) {
 	return count > 0, nil
 }
 
-// DeleteFileCopyRecord 删除文件复制记录
-func DeleteFileCopyRecord(fileName string, isAutotask bool) error {
-	_, err := db.Exec("DELETE FROM copy_records WHERE file_name =?", fileName)
+// DeleteFileCopyRecord 删除配置方案 profile 的文件复制记录
+func DeleteFileCopyRecord(profile, fileName string, isAutotask bool) error {
+	_, err := db.Exec("DELETE FROM copy_records WHERE profile = ? AND file_name = ?", profile, fileName)
 	return err
 }
 
-// RecordFileCopy 记录文件复制操作
-func RecordFileCopy(fileName string, copyDir string, isAutoTask bool) error {
+// RecordFileCopy 记录配置方案 profile 的文件复制操作
+func RecordFileCopy(profile, fileName string, copyDir string, isAutoTask bool) error {
 	_, err := db.Exec(
-		`INSERT OR REPLACE INTO copy_records (file_name, copy_dir, copy_time) 
-        VALUES (?, ?, ?)`,
-		fileName, copyDir, time.Now())
+		`INSERT OR REPLACE INTO copy_records (profile, file_name, copy_dir, copy_time) 
+        VALUES (?, ?, ?, ?)`,
+		profile, fileName, copyDir, time.Now())
 	return err
 }
 
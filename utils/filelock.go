@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// fileLockMu 同一进程内的文件锁先经过该互斥锁，避免多个协程同时等待系统文件锁
+var fileLockMu sync.Mutex
+
+// LockFile 以独占方式锁定 path（不存在时创建），阻塞直到获得锁，返回的函数用于释放锁
+// 锁对其他进程同样有效：Windows 使用 LockFileEx，其他平台使用 flock
+func LockFile(path string) (func(), error) {
+	fileLockMu.Lock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		fileLockMu.Unlock()
+		return nil, fmt.Errorf("打开锁文件失败: %v", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		fileLockMu.Unlock()
+		return nil, fmt.Errorf("锁定文件失败: %v", err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+		fileLockMu.Unlock()
+	}, nil
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ObjectHash 已上传对象的内容哈希，用于跳过内容相同的重复上传、发现同名文件内容的变化
+type ObjectHash struct {
+	Store      string // 存储标识：存储类型和地址，切换存储后不会误判为已上传
+	Bucket     string
+	ObjectKey  string
+	FileName   string // 文件名和日期文件夹，与 jobs 一致，用于按日期和编号清理
+	CopyDir    string
+	MD5        string // 内容的 MD5，十六进制
+	Size       int64
+	UploadedAt time.Time
+}
+
+// createObjectHashesTable 创建已上传对象的哈希索引表
+func createObjectHashesTable() error {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS object_hashes (
+        store TEXT NOT NULL,
+        bucket TEXT NOT NULL,
+        object_key TEXT NOT NULL,
+        file_name TEXT NOT NULL DEFAULT '',
+        copy_dir TEXT NOT NULL DEFAULT '',
+        md5 TEXT NOT NULL,
+        size INTEGER NOT NULL,
+        uploaded_at TIMESTAMP NOT NULL,
+        PRIMARY KEY (store, bucket, object_key)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建对象哈希索引表失败: %v", err)
+	}
+	return nil
+}
+
+// RecordObjectHash 记录对象上传（或确认存储中已有相同内容）时的内容哈希，已有记录时覆盖
+func RecordObjectHash(h *ObjectHash) error {
+	_, err := db.Exec(`
+    INSERT INTO object_hashes (store, bucket, object_key, file_name, copy_dir, md5, size, uploaded_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(store, bucket, object_key) DO UPDATE SET
+        file_name = excluded.file_name,
+        copy_dir = excluded.copy_dir,
+        md5 = excluded.md5,
+        size = excluded.size,
+        uploaded_at = excluded.uploaded_at`,
+		h.Store, h.Bucket, h.ObjectKey, h.FileName, h.CopyDir, h.MD5, h.Size, time.Now())
+	return err
+}
+
+// GetObjectHash 查询对象最近一次上传的内容哈希，没有记录时返回 nil
+func GetObjectHash(store, bucket, objectKey string) (*ObjectHash, error) {
+	var h ObjectHash
+	err := db.QueryRow(`
+    SELECT store, bucket, object_key, file_name, copy_dir, md5, size, uploaded_at
+    FROM object_hashes WHERE store = ? AND bucket = ? AND object_key = ?`, store, bucket, objectKey).
+		Scan(&h.Store, &h.Bucket, &h.ObjectKey, &h.FileName, &h.CopyDir, &h.MD5, &h.Size, &h.UploadedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// DeleteObjectHash 删除对象的哈希记录，存储中的对象已不存在时调用
+func DeleteObjectHash(store, bucket, objectKey string) error {
+	_, err := db.Exec("DELETE FROM object_hashes WHERE store = ? AND bucket = ? AND object_key = ?", store, bucket, objectKey)
+	return err
+}
@@ -0,0 +1,306 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// JobState 文件在流水线中的状态
+type JobState string
+
+const (
+	JobCopied      JobState = "copied"      // 已从远程复制到本地
+	JobProcessed   JobState = "processed"   // 已压缩
+	JobUploaded    JobState = "uploaded"    // 已上传到存储桶
+	JobPushed      JobState = "pushed"      // 已推送到 API2，流程结束
+	JobFailed      JobState = "failed"      // 最近一次处理失败，下个周期重试
+	JobQuarantined JobState = "quarantined" // 失败次数过多，已移入隔离目录，不再重试
+)
+
+// MaxJobAttempts 失败多少次后隔离文件
+const MaxJobAttempts = 5
+
+// jobStateOrder 正常流转状态的先后顺序
+var jobStateOrder = map[JobState]int{
+	JobCopied:    1,
+	JobProcessed: 2,
+	JobUploaded:  3,
+	JobPushed:    4,
+}
+
+// Job 单个文件的处理记录
+type Job struct {
+	Profile     string   // 配置方案，默认方案为空
+	FileName    string   // 文件名，与 copy_records 一致，与 Profile 一起作为唯一键
+	CopyDir     string   // 所在日期文件夹
+	SourcePath  string   // 远程源路径，非复制得到的文件为空
+	LocalPath   string   // 本地路径
+	State       JobState // 当前状态
+	PrevState   JobState // 失败前到达的状态，用于恢复
+	Attempts    int      // 失败次数
+	LastError   string   // 最近一次错误
+	ObjectKey   string   // 存储桶中的对象路径
+	OrderNumber string   // API1 查询到的有效编号
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Reached 判断文件是否已经完成 state 及之前的步骤，失败状态按失败前的状态判断
+func (j *Job) Reached(state JobState) bool {
+	current := j.State
+	if current == JobFailed || current == JobQuarantined {
+		current = j.PrevState
+	}
+	return jobStateOrder[current] >= jobStateOrder[state]
+}
+
+// jobsSchema 文件处理状态表，同一文件名在每个配置方案中各有一条记录
+const jobsSchema = `
+    CREATE TABLE IF NOT EXISTS jobs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        profile TEXT NOT NULL DEFAULT '',
+        file_name TEXT NOT NULL,
+        copy_dir TEXT NOT NULL DEFAULT '',
+        source_path TEXT NOT NULL DEFAULT '',
+        local_path TEXT NOT NULL DEFAULT '',
+        state TEXT NOT NULL,
+        prev_state TEXT NOT NULL DEFAULT '',
+        attempts INTEGER NOT NULL DEFAULT 0,
+        last_error TEXT NOT NULL DEFAULT '',
+        object_key TEXT NOT NULL DEFAULT '',
+        order_number TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP NOT NULL,
+        updated_at TIMESTAMP NOT NULL,
+        UNIQUE (profile, file_name)
+	)`
+
+// createJobsTable 创建文件处理状态表，旧版本以 file_name 为唯一键的表重建为按配置方案区分
+func createJobsTable() error {
+	if _, err := db.Exec(jobsSchema); err != nil {
+		return fmt.Errorf("创建文件处理状态表失败: %v", err)
+	}
+
+	hasProfile, err := columnExists("jobs", "profile")
+	if err != nil {
+		return fmt.Errorf("读取文件处理状态表结构失败: %v", err)
+	}
+	if !hasProfile {
+		// SQLite 不能修改唯一约束，在事务中重建表，原有记录归入默认方案
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		for _, stmt := range []string{
+			`DROP INDEX IF EXISTS idx_jobs_state`,
+			`ALTER TABLE jobs RENAME TO jobs_old`,
+			jobsSchema,
+			`INSERT INTO jobs (id, profile, ` + jobColumnsNoProfile + `)
+            SELECT id, '', ` + jobColumnsNoProfile + ` FROM jobs_old`,
+			`DROP TABLE jobs_old`,
+		} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("升级文件处理状态表失败: %v", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("升级文件处理状态表失败: %v", err)
+		}
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_jobs_state ON jobs (state)`); err != nil {
+		return fmt.Errorf("创建文件处理状态索引失败: %v", err)
+	}
+	return nil
+}
+
+// RecordJobCopied 记录配置方案 profile 的文件复制完成，重新复制的文件从头开始流转
+func RecordJobCopied(profile, fileName, copyDir, sourcePath, localPath string) error {
+	now := time.Now()
+	_, err := db.Exec(`
+    INSERT INTO jobs (profile, file_name, copy_dir, source_path, local_path, state, created_at, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(profile, file_name) DO UPDATE SET
+        copy_dir = excluded.copy_dir,
+        source_path = excluded.source_path,
+        local_path = excluded.local_path,
+        state = excluded.state,
+        prev_state = '',
+        attempts = 0,
+        last_error = '',
+        object_key = '',
+        order_number = '',
+        updated_at = excluded.updated_at`,
+		profile, fileName, copyDir, sourcePath, localPath, JobCopied, now, now)
+	return err
+}
+
+// SetJobState 更新配置方案 profile 的文件状态，文件没有记录时（例如手动放入本地目录）自动创建
+func SetJobState(profile, fileName, copyDir, localPath string, state JobState) error {
+	now := time.Now()
+	_, err := db.Exec(`
+    INSERT INTO jobs (profile, file_name, copy_dir, local_path, state, created_at, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(profile, file_name) DO UPDATE SET
+        local_path = excluded.local_path,
+        state = excluded.state,
+        prev_state = '',
+        last_error = '',
+        updated_at = excluded.updated_at`,
+		profile, fileName, copyDir, localPath, state, now, now)
+	return err
+}
+
+// MarkJobUploaded 记录配置方案 profile 的文件已上传，保存对象路径和有效编号供推送和恢复使用
+func MarkJobUploaded(profile, fileName, copyDir, localPath, orderNumber, objectKey string) error {
+	now := time.Now()
+	_, err := db.Exec(`
+    INSERT INTO jobs (profile, file_name, copy_dir, local_path, state, object_key, order_number, created_at, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(profile, file_name) DO UPDATE SET
+        local_path = excluded.local_path,
+        state = excluded.state,
+        prev_state = '',
+        last_error = '',
+        object_key = excluded.object_key,
+        order_number = excluded.order_number,
+        updated_at = excluded.updated_at`,
+		profile, fileName, copyDir, localPath, JobUploaded, objectKey, orderNumber, now, now)
+	return err
+}
+
+// MarkJobFailed 记录配置方案 profile 的文件一次失败并累加失败次数，达到 MaxJobAttempts 时状态变为 quarantined
+// 返回更新后的状态
+func MarkJobFailed(profile, fileName, copyDir, localPath string, cause error) (JobState, error) {
+	job, err := GetJob(profile, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	prev := JobState("")
+	attempts := 1
+	if job != nil {
+		prev = job.State
+		if prev == JobFailed || prev == JobQuarantined {
+			prev = job.PrevState
+		}
+		attempts = job.Attempts + 1
+	}
+
+	state := JobFailed
+	if attempts >= MaxJobAttempts {
+		state = JobQuarantined
+	}
+
+	now := time.Now()
+	_, err = db.Exec(`
+    INSERT INTO jobs (profile, file_name, copy_dir, local_path, state, prev_state, attempts, last_error, created_at, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(profile, file_name) DO UPDATE SET
+        local_path = excluded.local_path,
+        state = excluded.state,
+        prev_state = excluded.prev_state,
+        attempts = excluded.attempts,
+        last_error = excluded.last_error,
+        updated_at = excluded.updated_at`,
+		profile, fileName, copyDir, localPath, state, prev, attempts, cause.Error(), now, now)
+	if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// MarkJobPushed 记录配置方案 profile 的文件已推送到 API2（由推送待办补推成功时调用）
+func MarkJobPushed(profile, fileName string) error {
+	_, err := db.Exec("UPDATE jobs SET state = ?, prev_state = '', last_error = '', updated_at = ? WHERE profile = ? AND file_name = ?",
+		JobPushed, time.Now(), profile, fileName)
+	return err
+}
+
+// UpdateJobLocalPath 更新配置方案 profile 的文件的本地路径（例如移入隔离目录后）
+func UpdateJobLocalPath(profile, fileName, localPath string) error {
+	_, err := db.Exec("UPDATE jobs SET local_path = ?, updated_at = ? WHERE profile = ? AND file_name = ?", localPath, time.Now(), profile, fileName)
+	return err
+}
+
+// GetJob 查询配置方案 profile 中单个文件的处理记录，没有记录时返回 nil
+func GetJob(profile, fileName string) (*Job, error) {
+	row := db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE profile = ? AND file_name = ?`, profile, fileName)
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ListJobs 按更新时间倒序列出处理记录，state 为空时不按状态过滤，limit <= 0 时不限制条数
+func ListJobs(state JobState, limit int) ([]Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	var args []interface{}
+	if state != "" {
+		query += " WHERE state = ?"
+		args = append(args, state)
+	}
+	query += " ORDER BY updated_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// CountJobsByState 统计各状态的文件数量
+func CountJobsByState() (map[JobState]int, error) {
+	rows, err := db.Query("SELECT state, COUNT(*) FROM jobs GROUP BY state")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[JobState]int)
+	for rows.Next() {
+		var state JobState
+		var count int
+		if err := rows.Scan(&state, &count); err != nil {
+			return nil, err
+		}
+		counts[state] = count
+	}
+	return counts, rows.Err()
+}
+
+// rowScanner *sql.Row 和 *sql.Rows 共有的读取方法
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const jobColumnsNoProfile = `file_name, copy_dir, source_path, local_path, state, prev_state, attempts, last_error, object_key, order_number, created_at, updated_at`
+
+const jobColumns = `profile, ` + jobColumnsNoProfile
+
+// scanJob 从查询结果中读取一条处理记录
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	err := row.Scan(&job.Profile, &job.FileName, &job.CopyDir, &job.SourcePath, &job.LocalPath, &job.State, &job.PrevState,
+		&job.Attempts, &job.LastError, &job.ObjectKey, &job.OrderNumber, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
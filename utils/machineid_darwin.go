@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+)
+
+// ioPlatformUUIDPattern 匹配 ioreg 输出中的 "IOPlatformUUID" = "..."
+var ioPlatformUUIDPattern = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+// MachineID 返回本机唯一标识：ioreg 输出的 IOPlatformUUID
+func MachineID() (string, error) {
+	out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return "", fmt.Errorf("执行 ioreg 失败: %v", err)
+	}
+	m := ioPlatformUUIDPattern.FindSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("ioreg 输出中没有 IOPlatformUUID")
+	}
+	return string(m[1]), nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// MachineID 返回本机唯一标识：注册表中 Windows 安装时生成的 MachineGuid
+func MachineID() (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return "", fmt.Errorf("打开注册表失败: %v", err)
+	}
+	defer key.Close()
+
+	id, _, err := key.GetStringValue("MachineGuid")
+	if err != nil {
+		return "", fmt.Errorf("读取 MachineGuid 失败: %v", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows && !darwin
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MachineID 返回本机唯一标识：systemd 的 /etc/machine-id，不存在时使用 D-Bus 的 machine-id
+func MachineID() (string, error) {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("读取 /etc/machine-id 失败")
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// OutboxEntry 待推送到 API2 的通知
+type OutboxEntry struct {
+	ID          int64
+	Profile     string    // 产生通知的配置方案，补推时使用该方案的 API2 地址
+	FileName    string    // 对应 jobs 表中的文件名
+	OrderNumber string    // 有效编号
+	FileURL     string    // 文件访问地址
+	Payload     string    // 推送给 API2 的请求体（JSON）
+	Attempts    int       // 已失败次数
+	LastError   string    // 最近一次错误
+	NextRetryAt time.Time // 下一次允许推送的时间
+	CreatedAt   time.Time
+}
+
+// createOutboxTable 创建 API2 推送待办表
+func createOutboxTable() error {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS api2_outbox (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        file_name TEXT NOT NULL DEFAULT '',
+        order_number TEXT NOT NULL,
+        file_url TEXT NOT NULL,
+        payload TEXT NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        last_error TEXT NOT NULL DEFAULT '',
+        next_retry_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        UNIQUE (order_number, file_url)
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建 API2 推送待办表失败: %v", err)
+	}
+	if err := addColumnIfMissing("api2_outbox", "profile", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("升级 API2 推送待办表失败: %v", err)
+	}
+	return nil
+}
+
+// EnqueueAPI2Push 记录配置方案 profile 的一条待推送通知，同一编号和地址只保留一条
+func EnqueueAPI2Push(profile, fileName, orderNumber, fileURL string, payload []byte, lastError string) error {
+	now := time.Now()
+	_, err := db.Exec(`
+    INSERT INTO api2_outbox (profile, file_name, order_number, file_url, payload, attempts, last_error, next_retry_at, created_at)
+    VALUES (?, ?, ?, ?, ?, 1, ?, ?, ?)
+    ON CONFLICT(order_number, file_url) DO NOTHING`,
+		profile, fileName, orderNumber, fileURL, string(payload), lastError, now.Add(OutboxBackoff(1)), now)
+	return err
+}
+
+// DueOutboxEntries 查询到期需要推送的通知，按到期时间先后排列
+func DueOutboxEntries(now time.Time, limit int) ([]OutboxEntry, error) {
+	rows, err := db.Query(`
+    SELECT id, profile, file_name, order_number, file_url, payload, attempts, last_error, next_retry_at, created_at
+    FROM api2_outbox WHERE next_retry_at <= ? ORDER BY next_retry_at LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		if err := rows.Scan(&e.ID, &e.Profile, &e.FileName, &e.OrderNumber, &e.FileURL, &e.Payload,
+			&e.Attempts, &e.LastError, &e.NextRetryAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteOutboxEntry 推送成功后删除通知
+func DeleteOutboxEntry(id int64) error {
+	_, err := db.Exec("DELETE FROM api2_outbox WHERE id = ?", id)
+	return err
+}
+
+// RetryOutboxEntry 记录一次推送失败，并按指数退避安排下一次推送，返回累计失败次数
+func RetryOutboxEntry(e OutboxEntry, cause error) (int, error) {
+	attempts := e.Attempts + 1
+	_, err := db.Exec("UPDATE api2_outbox SET attempts = ?, last_error = ?, next_retry_at = ? WHERE id = ?",
+		attempts, cause.Error(), time.Now().Add(OutboxBackoff(attempts)), e.ID)
+	return attempts, err
+}
+
+// CountOutbox 统计待推送的通知数量
+func CountOutbox() (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM api2_outbox").Scan(&count)
+	return count, err
+}
+
+// OutboxBackoff 第 attempts 次失败后的等待时间：30 秒起每次翻倍，最长 1 小时
+func OutboxBackoff(attempts int) time.Duration {
+	const (
+		base    = 30 * time.Second
+		maxWait = time.Hour
+	)
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := base
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxWait {
+			return maxWait
+		}
+	}
+	return wait
+}
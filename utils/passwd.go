@@ -12,11 +12,6 @@ const (
 	hardcodedPassword = "1234"
 )
 
-// IsLockUIEnabled 检查是否启用了界面锁定
-func IsLockUIEnabled(lockUIValue string) bool {
-	return lockUIValue == "true"
-}
-
 // ShowPasswordDialogSync 显示密码输入对话框
 func ShowPasswordDialogSync(window fyne.Window) chan struct{} {
 	done := make(chan struct{})
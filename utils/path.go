@@ -4,18 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"syscall"
 	"time"
-	"unsafe"
 )
 
 var (
-	// DocumentsPath 存储 Windows 用户的文档文件夹路径
-	DocumentsPath string
-
-	// GoupossPath 存储 gouposs 应用程序在用户文档目录中的路径
+	// GoupossPath 存储 gouposs 应用程序文件夹路径（数据库、配置、隔离文件等）
+	// Windows 为 文档/gouposs，Linux 为 $XDG_DATA_HOME/gouposs，macOS 为 ~/Library/Application Support/gouposs
 	GoupossPath string
 
+	// LogRootPath 存储日志文件夹的上级目录，Windows 与 GoupossPath 相同
+	// Linux 为 $XDG_STATE_HOME/gouposs，macOS 为 ~/Library/Logs/gouposs
+	LogRootPath string
+
 	// DataPath 存储 gouposs 应用程序数据文件夹路径
 	DataPath string
 
@@ -27,6 +27,9 @@ var (
 
 	// SysLogPath 是系统日志文件路径
 	SysLogPath string
+
+	// QuarantinePath 存储多次处理失败后被隔离的文件
+	QuarantinePath string
 )
 
 // WriteSysLog 写入系统日志文件
@@ -48,81 +51,52 @@ func WriteSysLog(msg string) {
 	f.WriteString(logLine)
 }
 
-// 初始化模块变量
-func init() {
-	DocumentsPath = GetWindowsDocumentsPath()
-	GoupossPath = filepath.Join(DocumentsPath, "gouposs")  // 硬编码程序文件夹路径
-	DataPath = filepath.Join(GoupossPath, "data")          // 构建 data 文件夹路径
-	AutoLogPath = filepath.Join(GoupossPath, "log_auto")   // 构建 auto log 文件夹路径
-	SchedLogPath = filepath.Join(GoupossPath, "log_sched") // 构建 sched log 文件夹路径
-	SysLogPath = filepath.Join(GoupossPath, "sys.log")     // 构建系统日志路径
-
-	// 确保所有目录存在
-	EnsureDirExists(DataPath)
-	EnsureDirExists(AutoLogPath)
-	EnsureDirExists(SchedLogPath)
+// HomeEnv 指定程序文件夹的环境变量，优先级低于命令行参数 --data-dir
+const HomeEnv = "GOUPOSS_HOME"
 
-	WriteSysLog(fmt.Sprintf("DocumentsPath: %s", DocumentsPath))
-	WriteSysLog(fmt.Sprintf("GoupossPath: %s", GoupossPath))
-	WriteSysLog(fmt.Sprintf("DataPath: %s", DataPath))
-	WriteSysLog(fmt.Sprintf("AutoLogPath: %s", AutoLogPath))
-	WriteSysLog(fmt.Sprintf("SchedLogPath: %s", SchedLogPath))
+// 初始化模块变量，只计算路径不创建目录，由 InitPaths 确定最终路径
+func init() {
+	setPaths(platformPaths())
+}
 
+// setPaths 根据程序文件夹和日志文件夹计算其余路径
+func setPaths(goupossPath, logRootPath string) {
+	GoupossPath, LogRootPath = goupossPath, logRootPath
+	DataPath = filepath.Join(GoupossPath, "data")             // 构建 data 文件夹路径
+	AutoLogPath = filepath.Join(LogRootPath, "log_auto")      // 构建 auto log 文件夹路径
+	SchedLogPath = filepath.Join(LogRootPath, "log_sched")    // 构建 sched log 文件夹路径
+	SysLogPath = filepath.Join(LogRootPath, "sys.log")        // 构建系统日志路径
+	QuarantinePath = filepath.Join(GoupossPath, "quarantine") // 构建隔离文件夹路径
 }
 
-// GetWindowsDocumentsPath 获取当前 Windows 用户的 Documents 文件夹路径
-func GetWindowsDocumentsPath() string {
-	// 首先尝试使用环境变量方式获取
-	docPath := os.Getenv("USERPROFILE")
-	if docPath != "" {
-		docPath = filepath.Join(docPath, "Documents")
-		if _, err := os.Stat(docPath); err == nil {
-			return docPath
+// InitPaths 确定程序文件夹并创建所需目录，程序启动时在读取配置和日志之前调用
+// home 不为空时使用 home（命令行参数 --data-dir），否则使用环境变量 GOUPOSS_HOME，都未设置时使用平台默认路径
+// 指定 home 时数据和日志都保存在 home 下：home/data（config.json、uposs.db）、home/log_auto、home/log_sched、home/sys.log
+func InitPaths(home string) error {
+	if home == "" {
+		home = os.Getenv(HomeEnv)
+	}
+	if home != "" {
+		abs, err := filepath.Abs(home)
+		if err != nil {
+			return fmt.Errorf("无效的程序文件夹 %s: %v", home, err)
 		}
+		setPaths(abs, abs)
 	}
 
-	// 如果环境变量方式失败，使用 Windows API 获取
-	return getDocumentsPathUsingAPI()
-}
-
-// getDocumentsPathUsingAPI 使用 Windows API 获取 Documents 路径
-func getDocumentsPathUsingAPI() string {
-	// 加载 shell32.dll
-	shell32 := syscall.NewLazyDLL("shell32.dll")
-
-	// 获取 SHGetFolderPath 函数
-	shGetFolderPath := shell32.NewProc("SHGetFolderPathW")
-
-	// CSIDL for My Documents
-	const CSIDL_PERSONAL = 0x0005
-
-	// 为路径分配缓冲区
-	buf := make([]uint16, syscall.MAX_PATH)
-
-	// 调用 Windows API 获取 Documents 文件夹路径
-	ret, _, _ := shGetFolderPath.Call(
-		0,                                // hwndOwner [in, optional]
-		uintptr(CSIDL_PERSONAL),          // nFolder [in]
-		0,                                // hToken [in, optional]
-		0,                                // dwFlags [in]
-		uintptr(unsafe.Pointer(&buf[0])), // pszPath [out]
-	)
-
-	// 检查返回值
-	if ret != 0 {
-		// 如果 API 调用失败，返回默认路径
-		home := os.Getenv("USERPROFILE")
-		if home == "" {
-			home = os.Getenv("HOMEDRIVE") + os.Getenv("HOMEPATH")
-		}
-		if home == "" {
-			return ""
+	// 确保所有目录存在
+	for _, dir := range []string{DataPath, AutoLogPath, SchedLogPath} {
+		if err := EnsureDirExists(dir); err != nil {
+			return fmt.Errorf("创建目录 %s 失败: %v", dir, err)
 		}
-		return filepath.Join(home, "Documents")
 	}
 
-	// 将 UTF-16 编码的路径转换为字符串
-	return syscall.UTF16ToString(buf)
+	WriteSysLog(fmt.Sprintf("GoupossPath: %s", GoupossPath))
+	WriteSysLog(fmt.Sprintf("LogRootPath: %s", LogRootPath))
+	WriteSysLog(fmt.Sprintf("DataPath: %s", DataPath))
+	WriteSysLog(fmt.Sprintf("AutoLogPath: %s", AutoLogPath))
+	WriteSysLog(fmt.Sprintf("SchedLogPath: %s", SchedLogPath))
+	return nil
 }
 
 // IsPathExists 检查路径是否存在
@@ -138,3 +112,47 @@ func EnsureDirExists(path string) error {
 	}
 	return nil
 }
+
+// WriteFileAtomic 先写入同目录的临时文件并同步到磁盘，再重命名为 path
+// 写入过程中程序崩溃或断电时 path 保持原内容，读取方不会读到写了一半的文件
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath, err := WriteTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// WriteTempFile 将 data 写入 path 同目录的临时文件并同步到磁盘，返回临时文件路径
+// 调用方之后将其重命名为 path 或删除，用于多个文件都写入成功后再一起替换
+func WriteTempFile(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// platformPaths 返回 macOS 下的程序文件夹和日志文件夹
+// 数据保存在 ~/Library/Application Support/gouposs，日志保存在 ~/Library/Logs/gouposs
+func platformPaths() (string, string) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	library := filepath.Join(home, "Library")
+	return filepath.Join(library, "Application Support", "gouposs"), filepath.Join(library, "Logs", "gouposs")
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// platformPaths 返回 Windows 下的程序文件夹和日志文件夹：文档/gouposs
+func platformPaths() (string, string) {
+	goupossPath := filepath.Join(GetWindowsDocumentsPath(), "gouposs") // 硬编码程序文件夹路径
+	return goupossPath, goupossPath
+}
+
+// GetWindowsDocumentsPath 获取当前 Windows 用户的 Documents 文件夹路径
+func GetWindowsDocumentsPath() string {
+	// 首先尝试使用环境变量方式获取
+	docPath := os.Getenv("USERPROFILE")
+	if docPath != "" {
+		docPath = filepath.Join(docPath, "Documents")
+		if _, err := os.Stat(docPath); err == nil {
+			return docPath
+		}
+	}
+
+	// 如果环境变量方式失败，使用 Windows API 获取
+	return getDocumentsPathUsingAPI()
+}
+
+// getDocumentsPathUsingAPI 使用 Windows API 获取 Documents 路径
+func getDocumentsPathUsingAPI() string {
+	// 加载 shell32.dll
+	shell32 := syscall.NewLazyDLL("shell32.dll")
+
+	// 获取 SHGetFolderPath 函数
+	shGetFolderPath := shell32.NewProc("SHGetFolderPathW")
+
+	// CSIDL for My Documents
+	const CSIDL_PERSONAL = 0x0005
+
+	// 为路径分配缓冲区
+	buf := make([]uint16, syscall.MAX_PATH)
+
+	// 调用 Windows API 获取 Documents 文件夹路径
+	ret, _, _ := shGetFolderPath.Call(
+		0,                                // hwndOwner [in, optional]
+		uintptr(CSIDL_PERSONAL),          // nFolder [in]
+		0,                                // hToken [in, optional]
+		0,                                // dwFlags [in]
+		uintptr(unsafe.Pointer(&buf[0])), // pszPath [out]
+	)
+
+	// 检查返回值
+	if ret != 0 {
+		// 如果 API 调用失败，返回默认路径
+		home := os.Getenv("USERPROFILE")
+		if home == "" {
+			home = os.Getenv("HOMEDRIVE") + os.Getenv("HOMEPATH")
+		}
+		if home == "" {
+			return ""
+		}
+		return filepath.Join(home, "Documents")
+	}
+
+	// 将 UTF-16 编码的路径转换为字符串
+	return syscall.UTF16ToString(buf)
+}
@@ -0,0 +1,28 @@
+//go:build !windows && !darwin
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// platformPaths 返回 Linux 等系统下符合 XDG 规范的程序文件夹和日志文件夹
+// 数据保存在 $XDG_DATA_HOME/gouposs（默认 ~/.local/share/gouposs），
+// 日志保存在 $XDG_STATE_HOME/gouposs（默认 ~/.local/state/gouposs）
+func platformPaths() (string, string) {
+	return filepath.Join(xdgDir("XDG_DATA_HOME", ".local", "share"), "gouposs"),
+		filepath.Join(xdgDir("XDG_STATE_HOME", ".local", "state"), "gouposs")
+}
+
+// xdgDir 返回 XDG 环境变量指定的目录，未设置或不是绝对路径时使用 ~/defaults...
+func xdgDir(env string, defaults ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(append([]string{home}, defaults...)...)
+}
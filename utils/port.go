@@ -41,3 +41,13 @@ func ListenPort() bool {
 
 	return false
 }
+
+// AppRunning 检查是否已有界面模式的程序在运行（端口已被占用），只检查不占用端口
+func AppRunning() bool {
+	listener, err := net.Listen("tcp", "0.0.0.0:"+strconv.Itoa(AppPort))
+	if err != nil {
+		return true
+	}
+	listener.Close()
+	return false
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter 多个协程共享的请求限速器，保证相邻两次请求的间隔不小于 1/rate 秒
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // 两次请求之间的最小间隔，0 表示不限速
+	next     time.Time     // 下一次允许请求的时间
+}
+
+// NewRateLimiter 创建限速器，perSecond 为每秒允许的请求数，<= 0 表示不限速
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetRate(perSecond)
+	return l
+}
+
+// SetRate 修改限速，perSecond <= 0 表示不限速
+func (l *RateLimiter) SetRate(perSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if perSecond <= 0 {
+		l.interval = 0
+		return
+	}
+	l.interval = time.Duration(float64(time.Second) / perSecond)
+}
+
+// Wait 阻塞直到允许发起下一次请求，ctx 取消时返回 ctx.Err()
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.interval == 0 {
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+
+	// 预约下一个可用时间点，后来的协程依次顺延
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
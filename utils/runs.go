@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 任务周期的结束状态
+const (
+	RunRunning  = "running"  // 正在执行（程序在执行期间退出时保留该状态）
+	RunOK       = "ok"       // 全部阶段成功
+	RunFailed   = "failed"   // 有阶段失败
+	RunCanceled = "canceled" // 任务被停止
+)
+
+// RunRecord 一次任务周期的执行记录
+type RunRecord struct {
+	ID              int64     `json:"id"`
+	Task            string    `json:"task"`    // 任务类型：auto、sched
+	Profile         string    `json:"profile"` // 配置方案，默认方案为空
+	Trigger         string    `json:"trigger"` // 触发方式：manual、startup、schedule、watch
+	DryRun          bool      `json:"dry_run"` // 是否为模拟运行，模拟运行的数量为将要处理的数量
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"` // 未结束时为零值
+	Status          string    `json:"status"`
+	FilesScanned    int64     `json:"files_scanned"`    // 扫描到的源文件数，包括已复制过而跳过的文件
+	FilesCopied     int64     `json:"files_copied"`     // 复制的文件数
+	FilesCompressed int64     `json:"files_compressed"` // 压缩的文件数
+	FilesUploaded   int64     `json:"files_uploaded"`   // 上传的文件数
+	FilesSkipped    int64     `json:"files_skipped"`    // 存储中已有相同内容、跳过上传的文件数
+	FilesPushed     int64     `json:"files_pushed"`     // 推送 API2 成功的文件数
+	FilesDeleted    int64     `json:"files_deleted"`    // 删除的本地文件数
+	BytesCopied     int64     `json:"bytes_copied"`     // 复制的字节数
+	BytesUploaded   int64     `json:"bytes_uploaded"`   // 上传的字节数
+	Errors          int64     `json:"errors"`           // 失败的阶段和文件数
+	LastError       string    `json:"last_error"`       // 周期返回的错误
+}
+
+// Duration 返回执行时长，未结束时返回 0
+func (r *RunRecord) Duration() time.Duration {
+	if r.FinishedAt.IsZero() {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// createRunsTable 创建任务周期执行记录表
+func createRunsTable() error {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task TEXT NOT NULL,
+        trigger_type TEXT NOT NULL,
+        dry_run INTEGER NOT NULL DEFAULT 0,
+        started_at TIMESTAMP NOT NULL,
+        finished_at TIMESTAMP,
+        status TEXT NOT NULL,
+        files_scanned INTEGER NOT NULL DEFAULT 0,
+        files_copied INTEGER NOT NULL DEFAULT 0,
+        files_compressed INTEGER NOT NULL DEFAULT 0,
+        files_uploaded INTEGER NOT NULL DEFAULT 0,
+        files_pushed INTEGER NOT NULL DEFAULT 0,
+        files_deleted INTEGER NOT NULL DEFAULT 0,
+        bytes_copied INTEGER NOT NULL DEFAULT 0,
+        bytes_uploaded INTEGER NOT NULL DEFAULT 0,
+        errors INTEGER NOT NULL DEFAULT 0,
+        last_error TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建执行记录表失败: %v", err)
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_runs_started_at ON runs (started_at)`); err != nil {
+		return fmt.Errorf("创建执行记录索引失败: %v", err)
+	}
+	if err := addColumnIfMissing("runs", "profile", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("升级执行记录表失败: %v", err)
+	}
+	if err := addColumnIfMissing("runs", "files_skipped", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("升级执行记录表失败: %v", err)
+	}
+	return nil
+}
+
+// StartRun 记录配置方案 profile 的任务周期开始，返回记录 ID
+func StartRun(task, profile, trigger string, dryRun bool, startedAt time.Time) (int64, error) {
+	res, err := db.Exec("INSERT INTO runs (task, profile, trigger_type, dry_run, started_at, status) VALUES (?, ?, ?, ?, ?, ?)",
+		task, profile, trigger, dryRun, startedAt, RunRunning)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FinishRun 记录任务周期结束时的状态和统计
+func FinishRun(r *RunRecord) error {
+	_, err := db.Exec(`
+    UPDATE runs SET
+        finished_at = ?, status = ?,
+        files_scanned = ?, files_copied = ?, files_compressed = ?, files_uploaded = ?, files_skipped = ?, files_pushed = ?, files_deleted = ?,
+        bytes_copied = ?, bytes_uploaded = ?, errors = ?, last_error = ?
+    WHERE id = ?`,
+		r.FinishedAt, r.Status,
+		r.FilesScanned, r.FilesCopied, r.FilesCompressed, r.FilesUploaded, r.FilesSkipped, r.FilesPushed, r.FilesDeleted,
+		r.BytesCopied, r.BytesUploaded, r.Errors, r.LastError, r.ID)
+	return err
+}
+
+// ListRuns 按开始时间倒序列出执行记录
+// task 为空时不按任务类型过滤，since 为零值时不按时间过滤，limit <= 0 时不限制条数
+func ListRuns(task string, since time.Time, limit int) ([]RunRecord, error) {
+	query := `SELECT ` + runColumns + ` FROM runs WHERE 1 = 1`
+	var args []interface{}
+	if task != "" {
+		query += " AND task = ?"
+		args = append(args, task)
+	}
+	if !since.IsZero() {
+		query += " AND started_at >= ?"
+		args = append(args, since)
+	}
+	query += " ORDER BY started_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []RunRecord
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *r)
+	}
+	return runs, rows.Err()
+}
+
+const runColumns = `id, task, profile, trigger_type, dry_run, started_at, finished_at, status, files_scanned, files_copied, files_compressed,
+    files_uploaded, files_skipped, files_pushed, files_deleted, bytes_copied, bytes_uploaded, errors, last_error`
+
+// scanRun 从查询结果中读取一条执行记录
+func scanRun(row rowScanner) (*RunRecord, error) {
+	var r RunRecord
+	var finishedAt sql.NullTime
+	err := row.Scan(&r.ID, &r.Task, &r.Profile, &r.Trigger, &r.DryRun, &r.StartedAt, &finishedAt, &r.Status,
+		&r.FilesScanned, &r.FilesCopied, &r.FilesCompressed, &r.FilesUploaded, &r.FilesSkipped, &r.FilesPushed, &r.FilesDeleted,
+		&r.BytesCopied, &r.BytesUploaded, &r.Errors, &r.LastError)
+	if err != nil {
+		return nil, err
+	}
+	r.FinishedAt = finishedAt.Time
+	return &r, nil
+}
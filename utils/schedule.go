@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// createScheduleTable 创建定时任务上次执行时间表，用于判断休眠或程序未运行期间错过的执行
+func createScheduleTable() error {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS schedule_runs (
+        task TEXT PRIMARY KEY,
+        last_run TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建定时任务记录表失败: %v", err)
+	}
+	return nil
+}
+
+// GetLastScheduledRun 查询任务上次执行的时间，没有记录时返回零值
+func GetLastScheduledRun(task string) (time.Time, error) {
+	var lastRun time.Time
+	err := db.QueryRow("SELECT last_run FROM schedule_runs WHERE task = ?", task).Scan(&lastRun)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return lastRun, err
+}
+
+// SetLastScheduledRun 记录任务本次执行的时间
+func SetLastScheduledRun(task string, t time.Time) error {
+	_, err := db.Exec(`
+    INSERT INTO schedule_runs (task, last_run) VALUES (?, ?)
+    ON CONFLICT(task) DO UPDATE SET last_run = excluded.last_run`, task, t)
+	return err
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// SecretPrefix 加密值的前缀，格式为 enc:v1:<base64(nonce + 密文)>
+const SecretPrefix = "enc:v1:"
+
+// ErrSecretKey 密钥不正确或密文被修改，无法解密
+var ErrSecretKey = errors.New("密钥不正确或密文已损坏")
+
+// secretKeyCache 缓存口令和盐派生出的密钥，scrypt 派生较慢，同一配置的多个字段共用
+var (
+	secretKeyMu    sync.Mutex
+	secretKeyCache = map[[32]byte][]byte{}
+)
+
+// NewSecretSalt 生成 16 字节的随机盐，返回 base64 编码
+func NewSecretSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("生成随机盐失败: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(salt), nil
+}
+
+// deriveSecretKey 使用 scrypt 从口令和盐派生 AES-256 密钥
+func deriveSecretKey(passphrase, salt string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("口令不能为空")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil || len(saltBytes) == 0 {
+		return nil, fmt.Errorf("无效的盐: %q", salt)
+	}
+
+	id := sha256.Sum256([]byte(passphrase + "\x00" + salt))
+	secretKeyMu.Lock()
+	defer secretKeyMu.Unlock()
+	if key, ok := secretKeyCache[id]; ok {
+		return key, nil
+	}
+	key, err := scrypt.Key([]byte(passphrase), saltBytes, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %v", err)
+	}
+	secretKeyCache[id] = key
+	return key, nil
+}
+
+// secretAEAD 返回口令和盐对应的 AES-GCM
+func secretAEAD(passphrase, salt string) (cipher.AEAD, error) {
+	key, err := deriveSecretKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncryptedSecret 判断 value 是否为 EncryptSecret 生成的加密值
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, SecretPrefix)
+}
+
+// EncryptSecret 使用口令和盐派生的密钥以 AES-GCM 加密 plaintext，每次加密使用随机 nonce
+// field 作为附加数据参与认证，加密值不能被挪到其他字段使用
+func EncryptSecret(passphrase, salt, field, plaintext string) (string, error) {
+	aead, err := secretAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %v", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	return SecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 生成的加密值，密钥或字段不匹配时返回 ErrSecretKey
+func DecryptSecret(passphrase, salt, field, value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return "", fmt.Errorf("不是加密值")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, SecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("加密值格式错误: %v", err)
+	}
+	aead, err := secretAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("加密值长度错误")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(field))
+	if err != nil {
+		return "", ErrSecretKey
+	}
+	return string(plaintext), nil
+}
+
+// signKey 返回口令和盐对应的签名密钥，由加密密钥派生，与加密使用的密钥不同
+func signKey(passphrase, salt string) ([]byte, error) {
+	key, err := deriveSecretKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("gouposs-sign"))
+	return mac.Sum(nil), nil
+}
+
+// SignPayload 返回 payload 的 HMAC-SHA256 签名（base64），密钥由口令和盐派生
+func SignPayload(passphrase, salt string, payload []byte) (string, error) {
+	key, err := signKey(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyPayload 校验 SignPayload 生成的签名，口令不正确或内容被修改时返回 ErrSecretKey
+func VerifyPayload(passphrase, salt string, payload []byte, signature string) error {
+	expected, err := SignPayload(passphrase, salt, payload)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSecretKey
+	}
+	return nil
+}
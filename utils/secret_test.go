@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testPassphrase = "test-passphrase"
+
+func testSalt(t *testing.T) string {
+	t.Helper()
+	salt, err := NewSecretSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return salt
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	salt := testSalt(t)
+	for _, plaintext := range []string{"minioadmin", "", "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=密钥"} {
+		enc, err := EncryptSecret(testPassphrase, salt, "secretAccessKey", plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncryptedSecret(enc) || (plaintext != "" && strings.Contains(enc, plaintext)) {
+			t.Errorf("加密值 %q 应以 %s 开头且不含明文", enc, SecretPrefix)
+		}
+		dec, err := DecryptSecret(testPassphrase, salt, "secretAccessKey", enc)
+		if err != nil || dec != plaintext {
+			t.Errorf("DecryptSecret = %q, %v，应为 %q", dec, err, plaintext)
+		}
+	}
+
+	// 每次加密使用随机 nonce
+	a, _ := EncryptSecret(testPassphrase, salt, "accessKeyID", "same")
+	b, _ := EncryptSecret(testPassphrase, salt, "accessKeyID", "same")
+	if a == b {
+		t.Error("相同明文的两次加密结果不应相同")
+	}
+}
+
+func TestDecryptSecretRejects(t *testing.T) {
+	salt := testSalt(t)
+	enc, err := EncryptSecret(testPassphrase, salt, "secretAccessKey", "minioadmin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(enc, SecretPrefix))
+	sealed[len(sealed)-1] ^= 0x01
+	tampered := SecretPrefix + base64.StdEncoding.EncodeToString(sealed)
+
+	for _, tc := range []struct {
+		name       string
+		passphrase string
+		salt       string
+		field      string
+		value      string
+		keyErr     bool // 应返回 ErrSecretKey
+	}{
+		{"口令不正确", "wrong-passphrase", salt, "secretAccessKey", enc, true},
+		{"盐不正确", testPassphrase, testSalt(t), "secretAccessKey", enc, true},
+		{"挪到其他字段", testPassphrase, salt, "accessKeyID", enc, true},
+		{"密文被修改", testPassphrase, salt, "secretAccessKey", tampered, true},
+		{"不是加密值", testPassphrase, salt, "secretAccessKey", "minioadmin", false},
+		{"base64 格式错误", testPassphrase, salt, "secretAccessKey", SecretPrefix + "!!!", false},
+		{"长度不足", testPassphrase, salt, "secretAccessKey", SecretPrefix + base64.StdEncoding.EncodeToString([]byte("short")), false},
+		{"口令为空", "", salt, "secretAccessKey", enc, false},
+		{"盐无效", testPassphrase, "not base64!", "secretAccessKey", enc, false},
+	} {
+		dec, err := DecryptSecret(tc.passphrase, tc.salt, tc.field, tc.value)
+		if err == nil {
+			t.Errorf("%s: DecryptSecret = %q，应返回错误", tc.name, dec)
+			continue
+		}
+		if errors.Is(err, ErrSecretKey) != tc.keyErr {
+			t.Errorf("%s: 错误 %v，是否为 ErrSecretKey 应为 %v", tc.name, err, tc.keyErr)
+		}
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	salt := testSalt(t)
+	payload := []byte(`{"config":{"pic_compress":80}}`)
+	sig, err := SignPayload(testPassphrase, salt, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPayload(testPassphrase, salt, payload, sig); err != nil {
+		t.Errorf("VerifyPayload: %v", err)
+	}
+	if err := VerifyPayload(testPassphrase, salt, []byte(`{"config":{"pic_compress":81}}`), sig); !errors.Is(err, ErrSecretKey) {
+		t.Errorf("内容被修改时 VerifyPayload = %v，应为 ErrSecretKey", err)
+	}
+	if err := VerifyPayload("wrong-passphrase", salt, payload, sig); !errors.Is(err, ErrSecretKey) {
+		t.Errorf("口令不正确时 VerifyPayload = %v，应为 ErrSecretKey", err)
+	}
+	if err := VerifyPayload(testPassphrase, salt, payload, ""); !errors.Is(err, ErrSecretKey) {
+		t.Errorf("没有签名时 VerifyPayload = %v，应为 ErrSecretKey", err)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-uposs/pipeline"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/robfig/cron/v3"
+)
+
+// defaultWatchDebounce 未配置 watch_debounce 时的防抖时间，单位秒
+const defaultWatchDebounce = 3
+
+// watchDebounceOrDefault 返回监听模式的防抖时间
+func watchDebounceOrDefault(config *Config) time.Duration {
+	if config.WatchDebounce > 0 {
+		return time.Duration(config.WatchDebounce) * time.Second
+	}
+	return defaultWatchDebounce * time.Second
+}
+
+// todayDateSet 返回今天和昨天的日期文件夹名称
+func todayDateSet() map[string]bool {
+	return map[string]bool{
+		time.Now().AddDate(0, 0, -1).Format("2006.01.02"): true,
+		time.Now().Format("2006.01.02"):                   true,
+	}
+}
+
+// autoWatcher 监听 remote_folder 下今天和昨天的日期文件夹，文件变化停止 debounce 时长后发出触发信号
+type autoWatcher struct {
+	root     string
+	debounce time.Duration
+	watcher  *fsnotify.Watcher
+	trigger  chan struct{} // 防抖结束后发出信号，缓冲为 1，任务执行期间的变化不会丢失
+	log      func(string) error
+
+	mu      sync.Mutex
+	today   string          // dateSet 对应的日期，跨天后重新计算
+	dateSet map[string]bool // 需要监听的日期文件夹名称
+	watched map[string]bool // 已添加监听的目录
+	dirty   map[string]bool // 发生变化的日期文件夹路径
+	timer   *time.Timer
+}
+
+// newAutoWatcher 创建监听器并添加初始监听目录，监听出错时写入 log
+func newAutoWatcher(root string, debounce time.Duration, log func(string) error) (*autoWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建文件监听失败: %w", err)
+	}
+
+	aw := &autoWatcher{
+		root:     filepath.Clean(root),
+		debounce: debounce,
+		watcher:  w,
+		trigger:  make(chan struct{}, 1),
+		log:      log,
+		watched:  make(map[string]bool),
+		dirty:    make(map[string]bool),
+	}
+	go aw.loop()
+
+	if err := aw.Refresh(); err != nil {
+		aw.Close()
+		return nil, err
+	}
+	return aw, nil
+}
+
+// Close 停止监听
+func (aw *autoWatcher) Close() {
+	aw.mu.Lock()
+	if aw.timer != nil {
+		aw.timer.Stop()
+	}
+	aw.mu.Unlock()
+	aw.watcher.Close()
+}
+
+// Refresh 重新扫描 root，监听今天和昨天的日期文件夹（含子目录）和它们的上级目录
+// 其他日期文件夹不会进入，扫描开销远小于全量复制时的遍历
+func (aw *autoWatcher) Refresh() error {
+	aw.mu.Lock()
+	aw.resetDateSetLocked()
+	dateSet := aw.dateSet
+	aw.mu.Unlock()
+
+	want := map[string]bool{aw.root: true}
+	err := filepath.Walk(aw.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 跳过无法访问的目录，不影响其他目录的监听
+			if info != nil && info.IsDir() && path != aw.root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		if MatchDatePattern(info.Name()) {
+			if !dateSet[info.Name()] {
+				return filepath.SkipDir
+			}
+			want[filepath.Dir(path)] = true
+		}
+		if aw.dateFolderOf(path, dateSet) != "" {
+			want[path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("扫描监听目录失败: %w", err)
+	}
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	for path := range aw.watched {
+		if !want[path] {
+			aw.watcher.Remove(path)
+			delete(aw.watched, path)
+		}
+	}
+	for path := range want {
+		aw.addLocked(path)
+	}
+	return nil
+}
+
+// Wait 等待文件变化或 timeout 到期
+// 有变化时返回发生变化的日期文件夹；timeout 到期返回 nil，调用方执行全量扫描
+func (aw *autoWatcher) Wait(ctx context.Context, timeout time.Duration) ([]string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, nil
+		case <-aw.trigger:
+			if dirs := aw.takeChanges(); len(dirs) > 0 {
+				return dirs, nil
+			}
+		}
+	}
+}
+
+// loop 处理监听事件
+func (aw *autoWatcher) loop() {
+	for {
+		select {
+		case ev, ok := <-aw.watcher.Events:
+			if !ok {
+				return
+			}
+			aw.handle(ev)
+		case err, ok := <-aw.watcher.Errors:
+			if !ok {
+				return
+			}
+			aw.log(fmt.Sprintf("文件监听出错: %v", err))
+		}
+	}
+}
+
+// handle 记录发生变化的日期文件夹并重置防抖计时
+func (aw *autoWatcher) handle(ev fsnotify.Event) {
+	if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+		return
+	}
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	// 跨天后新建的今天文件夹也需要识别
+	if time.Now().Format("2006.01.02") != aw.today {
+		aw.resetDateSetLocked()
+	}
+
+	dir := aw.dateFolderOf(ev.Name, aw.dateSet)
+	if dir == "" {
+		return
+	}
+
+	// 新建的子目录加入监听
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			filepath.Walk(ev.Name, func(path string, info os.FileInfo, err error) error {
+				if err == nil && info.IsDir() {
+					aw.addLocked(path)
+				}
+				return nil
+			})
+		}
+	}
+
+	aw.dirty[dir] = true
+	if aw.timer == nil {
+		aw.timer = time.AfterFunc(aw.debounce, aw.fire)
+	} else {
+		aw.timer.Reset(aw.debounce)
+	}
+}
+
+// fire 防抖结束，发出触发信号
+func (aw *autoWatcher) fire() {
+	select {
+	case aw.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// takeChanges 取出并清空发生变化的日期文件夹
+func (aw *autoWatcher) takeChanges() []string {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	dirs := make([]string, 0, len(aw.dirty))
+	for dir := range aw.dirty {
+		dirs = append(dirs, dir)
+	}
+	aw.dirty = make(map[string]bool)
+	sort.Strings(dirs)
+	return dirs
+}
+
+// resetDateSetLocked 重新计算今天和昨天的日期，调用方需持有 mu
+func (aw *autoWatcher) resetDateSetLocked() {
+	aw.today = time.Now().Format("2006.01.02")
+	aw.dateSet = todayDateSet()
+}
+
+// addLocked 添加目录监听，调用方需持有 mu
+func (aw *autoWatcher) addLocked(path string) {
+	if aw.watched[path] {
+		return
+	}
+	if err := aw.watcher.Add(path); err != nil {
+		aw.log(fmt.Sprintf("添加文件监听失败: %s, 错误: %v", path, err))
+		return
+	}
+	aw.watched[path] = true
+}
+
+// dateFolderOf 返回 path 所在的（或 path 本身就是的）监听日期文件夹，不在日期文件夹中时返回空字符串
+func (aw *autoWatcher) dateFolderOf(path string, dateSet map[string]bool) string {
+	for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+		if dateSet[filepath.Base(p)] {
+			return p
+		}
+		if p == aw.root || filepath.Dir(p) == p {
+			return ""
+		}
+	}
+}
+
+// copyChangedFolders 复制监听到变化的日期文件夹到 local_folder
+func copyChangedFolders(ctx context.Context, rc *pipeline.RunContext, config *Config, dirs []string) error {
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rc.Log(fmt.Sprintf("检测到文件变化: %s", dir))
+
+		dstPath := filepath.Join(config.LocalFolder, filepath.Base(dir))
+		if err := CopyDir(ctx, rc, dir, dstPath, config.IOBufferBytes(), ""); err != nil {
+			return fmt.Errorf("复制文件夹 %s 失败: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// autoLoop 一个配置方案的自动任务循环的调度状态，界面和命令行共用
+// 普通模式每隔 auto_interval 秒（或按 auto_cron）全量扫描；监听模式文件变化时只复制变化的文件夹，
+// 同时按同样的间隔全量扫描，兼容不发送事件的网络共享目录
+type autoLoop struct {
+	profile  string // 配置方案名称，默认方案为空
+	watcher  *autoWatcher
+	root     string    // 当前监听的 remote_folder
+	changed  []string  // 下一次周期需要复制的日期文件夹，nil 表示全量扫描
+	lastFull time.Time // 上一次全量扫描的时间
+	started  bool      // 是否已执行过第一个周期
+	trigger  string    // 第一个周期的触发方式（manual、startup），之后的周期为 schedule
+}
+
+// runCycle 执行一次自动任务周期
+// 配置了 auto_cron 时，第一个周期先等待执行时间（错过执行时间且开启补执行时立即执行）
+func (l *autoLoop) runCycle(ctx context.Context, config *Config) error {
+	trigger := runTriggerSchedule
+	if !l.started {
+		l.started = true
+		if schedule := autoCronSchedule(config); schedule != nil {
+			if err := waitCronStart(ctx, profileCronTask(cronTaskAuto, l.profile), schedule, config.CronCatchUp, l.log); err != nil {
+				return err
+			}
+		} else if l.trigger != "" {
+			trigger = l.trigger
+		}
+	}
+
+	l.syncWatcher(config)
+
+	if l.changed != nil {
+		dirs := l.changed
+		l.changed = nil
+		return runAutoWatchCycle(ctx, config, dirs)
+	}
+
+	recordCronRun(profileCronTask(cronTaskAuto, l.profile), l.log)
+	err := runAutoCycle(ctx, config, trigger)
+	l.lastFull = time.Now()
+	if l.watcher != nil && ctx.Err() == nil {
+		if refreshErr := l.watcher.Refresh(); refreshErr != nil {
+			l.log(fmt.Sprintf("更新文件监听失败: %v", refreshErr))
+		}
+	}
+	return err
+}
+
+// wait 等待下一次周期，ctx 取消时返回 ctx.Err()
+func (l *autoLoop) wait(ctx context.Context, config *Config, interval time.Duration) error {
+	schedule := autoCronSchedule(config)
+	if l.watcher == nil {
+		if schedule != nil {
+			return waitCron(ctx, schedule, config.CronCatchUp, l.log)
+		}
+		l.log(fmt.Sprintf("将在 %d 秒后开始下一次任务执行...", int(interval.Seconds())))
+		return pipeline.Sleep(ctx, interval)
+	}
+
+	next := l.lastFull.Add(interval)
+	if schedule != nil {
+		next = schedule.Next(time.Now())
+	}
+	remaining := time.Until(next)
+	if remaining < 0 {
+		remaining = 0
+	}
+	l.log(fmt.Sprintf("监听文件变化中，%d 秒后执行全量扫描...", int(remaining.Seconds())))
+
+	dirs, err := l.watcher.Wait(ctx, remaining)
+	l.changed = dirs
+	return err
+}
+
+// syncWatcher 根据配置开启、关闭或重建文件监听
+func (l *autoLoop) syncWatcher(config *Config) {
+	if l.watcher != nil && (!config.AutoWatch || config.RemoteFolder != l.root) {
+		l.close()
+		l.log("已关闭监听模式")
+	}
+	if !config.AutoWatch || l.watcher != nil {
+		return
+	}
+
+	watcher, err := newAutoWatcher(config.RemoteFolder, watchDebounceOrDefault(config), l.log)
+	if err != nil {
+		l.log(fmt.Sprintf("开启监听模式失败，改为定时扫描: %v", err))
+		return
+	}
+	l.watcher = watcher
+	l.root = config.RemoteFolder
+	l.changed = nil // 新开启监听时先执行一次全量扫描
+	l.log(fmt.Sprintf("已开启监听模式，监听目录: %s", config.RemoteFolder))
+}
+
+// log 写入本方案的自动任务日志
+func (l *autoLoop) log(message string) error {
+	return AutoProfileLogToFile(l.profile, message)
+}
+
+// close 停止文件监听
+func (l *autoLoop) close() {
+	if l.watcher != nil {
+		l.watcher.Close()
+		l.watcher = nil
+	}
+	l.changed = nil
+}
+
+// autoCronSchedule 返回 auto_cron 对应的执行计划，未配置或表达式无效时返回 nil（按 auto_interval 执行）
+func autoCronSchedule(config *Config) cron.Schedule {
+	if strings.TrimSpace(config.AutoCron) == "" {
+		return nil
+	}
+	schedule, err := parseCron(config.AutoCron, config.CronTimezone)
+	if err != nil {
+		AutoProfileLogToFile(config.Profile, fmt.Sprintf("%v，改为按执行间隔运行", err))
+		return nil
+	}
+	return schedule
+}
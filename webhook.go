@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
+// webhookTimeout 发送企业微信通知的超时时间，webhook 不可访问时不阻塞上传和补推
+const webhookTimeout = 10 * time.Second
+
 // NotifyUploadFailed 发送上传失败通知到企业微信
 func (config *Config) NotifyUploadFailed() error {
 	content := fmt.Sprintf(
@@ -16,10 +20,27 @@ func (config *Config) NotifyUploadFailed() error {
 		config.BucketName, config.MachineCode,
 	)
 
+	return config.postWebhook(content)
+}
+
+// NotifyPushPending 推送待办多次补推失败时发送企业微信通知
+func (config *Config) NotifyPushPending(orderNumber string, attempts int) error {
+	content := fmt.Sprintf(
+		"API2 推送已失败 %d 次❌😅，图片已在存储桶中，将继续自动重试\n"+
+			">编号:<font color=\"warning\"> %s</font>\n"+
+			">机器代号:<font color=\"warning\"> %s</font>",
+		attempts, orderNumber, config.MachineCode,
+	)
+
+	return config.postWebhook(content)
+}
+
+// postWebhook 以 markdown 消息发送 text 到配置的企业微信 webhook
+func (config *Config) postWebhook(text string) error {
 	payload := map[string]interface{}{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
-			"content": content,
+			"content": text,
 		},
 	}
 
@@ -28,17 +49,15 @@ func (config *Config) NotifyUploadFailed() error {
 		return fmt.Errorf("编码 JSON 失败: %w", err)
 	}
 
-	// 打印 webhook 地址（可选，调试时启用）
-	fmt.Println("上传失败，发送企业微信通知...")
-
-	resp, err := http.Post(config.WebhookURL, "application/json", bytes.NewBuffer(data))
+	client := http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(config.WebhookURL, "application/json", bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("请求 webhook 失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("编码 JSON 失败: %w", err)
+		return fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
 	}
 
 	return nil